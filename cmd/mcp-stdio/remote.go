@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/techbuzzz/agent-shaker/internal/mcp"
+)
+
+// remote forwards stdio messages to an Agent Shaker server over the MCP
+// Streamable HTTP transport
+type remote struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+
+	mu      sync.Mutex
+	session string
+	// listening is set once the standalone notification stream is started
+	listening bool
+
+	out   io.Writer
+	outMu sync.Mutex
+}
+
+// runRemote forwards every message read from stdin to the server at
+// serverURL and writes what it answers to stdout
+func runRemote(ctx context.Context, serverURL, apiKey, projectID, agentID string) error {
+	endpoint, err := url.Parse(strings.TrimRight(serverURL, "/") + "/mcp")
+	if err != nil {
+		return fmt.Errorf("invalid server URL: %w", err)
+	}
+	query := endpoint.Query()
+	if projectID != "" {
+		query.Set("project_id", projectID)
+	}
+	if agentID != "" {
+		query.Set("agent_id", agentID)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	r := &remote{endpoint: endpoint.String(), apiKey: apiKey, client: &http.Client{}, out: os.Stdout}
+	defer r.close()
+
+	log.Printf("forwarding project=%s agent=%s to %s", projectID, agentID, serverURL)
+
+	var requests sync.WaitGroup
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		message := append([]byte(nil), line...)
+
+		// A batch is forwarded as is; the server answers it with an array
+		var req mcp.JSONRPCRequest
+		if message[0] == '[' {
+			req.Method = "batch"
+		} else if err := json.Unmarshal(message, &req); err != nil {
+			r.write(mustJSON(mcp.JSONRPCResponse{JSONRPC: "2.0", Error: &mcp.JSONRPCError{Code: -32700, Message: "Parse error", Data: err.Error()}}))
+			continue
+		}
+
+		// initialize must finish first so later requests carry the session
+		if req.Method == "initialize" {
+			r.forward(ctx, req, message)
+			continue
+		}
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			r.forward(ctx, req, message)
+		}()
+	}
+
+	requests.Wait()
+	return scanner.Err()
+}
+
+// forward POSTs one message and relays the JSON or SSE response
+func (r *remote) forward(ctx context.Context, req mcp.JSONRPCRequest, message []byte) {
+	httpReq, err := r.request(ctx, http.MethodPost, bytes.NewReader(message))
+	if err != nil {
+		r.fail(req, err)
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := r.client.Do(httpReq)
+	if err != nil {
+		r.fail(req, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if id := resp.Header.Get(mcp.SessionHeader); id != "" {
+		r.mu.Lock()
+		r.session = id
+		r.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		// Notifications, and batches holding only notifications, get no response
+		return
+	case resp.StatusCode == http.StatusNotFound && r.sessionID() != "":
+		r.fail(req, fmt.Errorf("session expired, restart the MCP client"))
+		return
+	case resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		r.fail(req, fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body))))
+		return
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		if _, err := r.relayEvents(resp.Body); err != nil {
+			log.Printf("stream for %s ended: %v", req.Method, err)
+		}
+	} else {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			r.fail(req, err)
+			return
+		}
+		if body = bytes.TrimSpace(body); len(body) > 0 {
+			r.write(body)
+		}
+	}
+
+	if req.Method == "initialize" {
+		r.listen(ctx)
+	}
+}
+
+// listen follows the session's standalone stream for server notifications,
+// reconnecting with Last-Event-ID when it drops
+func (r *remote) listen(ctx context.Context) {
+	r.mu.Lock()
+	if r.session == "" || r.listening {
+		r.mu.Unlock()
+		return
+	}
+	r.listening = true
+	r.mu.Unlock()
+
+	go func() {
+		lastEventID := ""
+		for {
+			req, err := r.request(ctx, http.MethodGet, nil)
+			if err != nil {
+				return
+			}
+			req.Header.Set("Accept", "text/event-stream")
+			if lastEventID != "" {
+				req.Header.Set("Last-Event-ID", lastEventID)
+			}
+
+			resp, err := r.client.Do(req)
+			if err == nil {
+				if resp.StatusCode != http.StatusOK {
+					// The server does not offer a stream or the session is gone
+					resp.Body.Close()
+					return
+				}
+				if id, _ := r.relayEvents(resp.Body); id != "" {
+					lastEventID = id
+				}
+				resp.Body.Close()
+			}
+			time.Sleep(time.Second)
+		}
+	}()
+}
+
+// relayEvents writes the data of each SSE event to stdout and returns the ID
+// of the last event seen
+func (r *remote) relayEvents(body io.Reader) (string, error) {
+	var lastID string
+	var data []string
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				r.write([]byte(strings.Join(data, "\n")))
+				data = nil
+			}
+		case strings.HasPrefix(line, "id:"):
+			lastID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return lastID, scanner.Err()
+}
+
+// close ends the server-side session
+func (r *remote) close() {
+	if r.sessionID() == "" {
+		return
+	}
+	req, err := r.request(context.Background(), http.MethodDelete, nil)
+	if err != nil {
+		return
+	}
+	if resp, err := r.client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+func (r *remote) request(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, r.endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+	if id := r.sessionID(); id != "" {
+		req.Header.Set(mcp.SessionHeader, id)
+	}
+	return req, nil
+}
+
+func (r *remote) sessionID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.session
+}
+
+// fail answers a request with an error; notifications and batches are only logged
+func (r *remote) fail(req mcp.JSONRPCRequest, err error) {
+	if req.ID == nil {
+		log.Printf("failed to forward %s: %v", req.Method, err)
+		return
+	}
+	r.write(mustJSON(mcp.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Error:   &mcp.JSONRPCError{Code: -32603, Message: "Internal error", Data: err.Error()},
+	}))
+}
+
+// write sends one message to stdout
+func (r *remote) write(message []byte) {
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
+	r.out.Write(append(message, '\n'))
+}
+
+func mustJSON(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
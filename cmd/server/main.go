@@ -9,15 +9,27 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
+	"github.com/techbuzzz/agent-shaker/internal/a2a/executor"
 	a2aserver "github.com/techbuzzz/agent-shaker/internal/a2a/server"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/comments"
 	"github.com/techbuzzz/agent-shaker/internal/database"
 	"github.com/techbuzzz/agent-shaker/internal/handlers"
+	"github.com/techbuzzz/agent-shaker/internal/lease"
 	"github.com/techbuzzz/agent-shaker/internal/mcp"
+	"github.com/techbuzzz/agent-shaker/internal/mcp/upstream"
 	"github.com/techbuzzz/agent-shaker/internal/middleware"
+	"github.com/techbuzzz/agent-shaker/internal/overdue"
+	"github.com/techbuzzz/agent-shaker/internal/presence"
+	"github.com/techbuzzz/agent-shaker/internal/routing"
+	"github.com/techbuzzz/agent-shaker/internal/subtasks"
 	"github.com/techbuzzz/agent-shaker/internal/task"
+	"github.com/techbuzzz/agent-shaker/internal/taskgraph"
 	"github.com/techbuzzz/agent-shaker/internal/websocket"
 )
 
@@ -54,15 +66,79 @@ func main() {
 	hub := websocket.NewHub()
 	go hub.Run()
 
+	// Create authentication service
+	authService := auth.NewService(db, auth.Config{
+		AdminKey: os.Getenv("ADMIN_API_KEY"),
+		Required: os.Getenv("AUTH_REQUIRED") == "true",
+	})
+	if authService.Required() {
+		log.Println("API key authentication required for /api, /mcp and /a2a/v1")
+	}
+
+	// Create task dependency graph service
+	taskGraph := taskgraph.NewService(db, hub)
+
+	// Create task lease manager and start the expired lease reaper
+	leaseManager := lease.NewManager(db, hub, lease.Config{
+		Duration:     getDuration("TASK_LEASE_DURATION", lease.DefaultDuration),
+		ReapInterval: getDuration("TASK_LEASE_REAP_INTERVAL", lease.DefaultReapInterval),
+	})
+	if db != nil {
+		go leaseManager.Run(context.Background())
+	}
+
+	// Create the scheduler that escalates overdue tasks
+	overdueScheduler := overdue.NewScheduler(db, hub, overdue.Config{
+		Interval:   getDuration("TASK_OVERDUE_CHECK_INTERVAL", overdue.DefaultInterval),
+		DefaultSLA: getDuration("TASK_DEFAULT_SLA", 0),
+	})
+	if db != nil {
+		go overdueScheduler.Run(context.Background())
+	}
+
+	// Create the tracker that derives agent presence from heartbeats
+	presenceTracker := presence.NewTracker(db, hub, taskGraph, presence.Config{
+		IdleAfter:    getDuration("AGENT_IDLE_AFTER", presence.DefaultIdleAfter),
+		OfflineAfter: getDuration("AGENT_OFFLINE_AFTER", presence.DefaultOfflineAfter),
+		Interval:     getDuration("AGENT_PRESENCE_CHECK_INTERVAL", presence.DefaultInterval),
+		ReleaseTasks: os.Getenv("AGENT_OFFLINE_RELEASE_TASKS") == "true",
+	})
+	if db != nil {
+		go presenceTracker.Run(context.Background())
+	}
+
+	// Create the router that auto-assigns tasks using each project's strategy
+	taskRouter := routing.NewRouter(routing.Config{
+		StaleAfter: getDuration("AUTO_ASSIGN_STALE_AFTER", routing.DefaultStaleAfter),
+	})
+
+	// Create task comment and subtask services
+	commentService := comments.NewService(db, hub)
+	subtaskService := subtasks.NewService(db, hub, taskRouter)
+
 	// Create handlers
-	projectHandler := handlers.NewProjectHandler(db, hub)
-	agentHandler := handlers.NewAgentHandler(db, hub)
-	taskHandler := handlers.NewTaskHandler(db, hub)
-	contextHandler := handlers.NewContextHandler(db, hub)
-	standupHandler := handlers.NewStandupHandler(db, hub)
-	wsHandler := handlers.NewWebSocketHandler(hub)
-	dashboardHandler := handlers.NewDashboardHandler(db)
-	mcpHandler := mcp.NewMCPHandler(db, hub)
+	projectHandler := handlers.NewProjectHandler(db, hub, authService)
+	agentHandler := handlers.NewAgentHandler(db, hub, authService, presenceTracker)
+	taskHandler := handlers.NewTaskHandler(db, hub, authService, taskGraph, leaseManager, subtaskService, taskRouter)
+	contextHandler := handlers.NewContextHandler(db, hub, authService)
+	standupHandler := handlers.NewStandupHandler(db, hub, authService, leaseManager, presenceTracker)
+	wsHandler := handlers.NewWebSocketHandler(hub, authService)
+	dashboardHandler := handlers.NewDashboardHandler(db, authService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db, authService)
+	userHandler := handlers.NewUserHandler(db)
+	commentHandler := handlers.NewCommentHandler(db, authService, commentService)
+	mcpHandler := mcp.NewMCPHandler(db, hub, authService, taskGraph, leaseManager, commentService, subtaskService, taskRouter, presenceTracker)
+
+	// Re-export the tools of downstream MCP servers
+	if path := os.Getenv("MCP_UPSTREAMS_CONFIG"); path != "" {
+		servers, err := upstream.LoadConfig(path)
+		if err != nil {
+			log.Printf("Failed to load MCP upstreams: %v", err)
+		} else {
+			log.Printf("Connecting to %d MCP upstream servers", len(servers))
+			mcpHandler.ConnectUpstreams(context.Background(), servers)
+		}
+	}
 
 	// A2A Protocol Setup
 	baseURL := os.Getenv("BASE_URL")
@@ -76,13 +152,25 @@ func main() {
 		tasksDir = "./data/tasks"
 	}
 	taskStore := task.NewMemoryStore(tasksDir)
-	taskManager := task.NewManager(taskStore, nil, baseURL)
+
+	// Carry out inbound A2A messages as tasks for local agents; without a
+	// database they are only acknowledged
+	var taskExecutor task.TaskExecutor
+	if db != nil {
+		taskExecutor = executor.New(db, hub, authService, executor.Config{
+			DefaultProject: getUUID("A2A_DEFAULT_PROJECT_ID"),
+			PollInterval:   getDuration("A2A_TASK_POLL_INTERVAL", executor.DefaultPollInterval),
+			Timeout:        getDuration("A2A_TASK_TIMEOUT", executor.DefaultTimeout),
+			BaseURL:        baseURL,
+		})
+	}
+	taskManager := task.NewManager(taskStore, taskExecutor, baseURL)
 
 	// Create A2A context storage (bridges existing contexts to A2A artifacts)
 	contextStorage := a2aserver.NewDatabaseContextStorage(db)
 
 	// Create A2A handlers
-	agentCardHandler := a2aserver.NewAgentCardHandler("1.0.0", baseURL)
+	agentCardHandler := a2aserver.NewAgentCardHandler("1.0.0", baseURL, a2aserver.WithAuthRequired(authService.Required()))
 	a2aHandler := a2aserver.NewA2AHandler(taskManager)
 	streamingHandler := a2aserver.NewStreamingHandler(taskManager)
 	artifactHandler := a2aserver.NewArtifactHandler(contextStorage, baseURL)
@@ -102,6 +190,30 @@ func main() {
 	api.HandleFunc("/projects/{id}", projectHandler.GetProject).Methods("GET")
 	api.HandleFunc("/projects/{id}", projectHandler.DeleteProject).Methods("DELETE")
 	api.HandleFunc("/projects/{id}/status", projectHandler.UpdateProjectStatus).Methods("PUT")
+	api.HandleFunc("/projects/{id}/assign-strategy", projectHandler.UpdateAssignStrategy).Methods("PUT")
+	api.HandleFunc("/projects/{id}/agent-roles", projectHandler.ListAgentRoles).Methods("GET")
+	api.HandleFunc("/projects/{id}/agent-roles", projectHandler.CreateAgentRole).Methods("POST")
+	api.HandleFunc("/projects/{id}/agent-roles/{role}", projectHandler.DeleteAgentRole).Methods("DELETE")
+	api.HandleFunc("/projects/{id}/prompts", projectHandler.ListPromptTemplates).Methods("GET")
+	api.HandleFunc("/projects/{id}/prompts", projectHandler.CreatePromptTemplate).Methods("POST")
+	api.HandleFunc("/projects/{id}/prompts/{name}", projectHandler.DeletePromptTemplate).Methods("DELETE")
+	api.HandleFunc("/projects/{id}/mcp-tools", projectHandler.ListToolSettings).Methods("GET")
+	api.HandleFunc("/projects/{id}/mcp-tools/{name}", projectHandler.UpdateToolSetting).Methods("PUT")
+	api.HandleFunc("/projects/{id}/mcp-tools/{name}", projectHandler.DeleteToolSetting).Methods("DELETE")
+	api.HandleFunc("/projects/{id}/mcp-approvals", projectHandler.ListToolApprovals).Methods("GET")
+	api.HandleFunc("/projects/{id}/mcp-approvals/{approvalId}/decision", projectHandler.DecideToolApproval).Methods("POST")
+	api.HandleFunc("/projects/{id}/mcp-upstream-calls", projectHandler.ListUpstreamCalls).Methods("GET")
+	api.HandleFunc("/projects/{id}/members", projectHandler.ListProjectMembers).Methods("GET")
+	api.HandleFunc("/projects/{id}/members", projectHandler.SetProjectMember).Methods("PUT")
+	api.HandleFunc("/projects/{id}/members/{memberId}", projectHandler.RemoveProjectMember).Methods("DELETE")
+
+	// Users
+	api.HandleFunc("/users", userHandler.CreateUser).Methods("POST")
+	api.HandleFunc("/users", userHandler.ListUsers).Methods("GET")
+	api.HandleFunc("/users/{userId}/keys", apiKeyHandler.CreateAPIKey).Methods("POST")
+	api.HandleFunc("/users/{userId}/keys", apiKeyHandler.ListAPIKeys).Methods("GET")
+	api.HandleFunc("/users/{userId}/keys/{keyId}", apiKeyHandler.RevokeAPIKey).Methods("DELETE")
+	api.HandleFunc("/users/{userId}/keys/{keyId}/rotate", apiKeyHandler.RotateAPIKey).Methods("POST")
 
 	// Agents
 	api.HandleFunc("/agents", agentHandler.CreateAgent).Methods("POST")
@@ -109,6 +221,14 @@ func main() {
 	api.HandleFunc("/agents/{id}", agentHandler.GetAgent).Methods("GET")
 	api.HandleFunc("/agents/{id}", agentHandler.DeleteAgent).Methods("DELETE")
 	api.HandleFunc("/agents/{id}/status", agentHandler.UpdateAgentStatus).Methods("PUT")
+	api.HandleFunc("/agents/{id}/capabilities", agentHandler.UpdateAgentCapabilities).Methods("PUT")
+	api.HandleFunc("/agents/{id}/presence", agentHandler.GetAgentPresence).Methods("GET")
+
+	// Agent API keys
+	api.HandleFunc("/agents/{id}/keys", apiKeyHandler.CreateAPIKey).Methods("POST")
+	api.HandleFunc("/agents/{id}/keys", apiKeyHandler.ListAPIKeys).Methods("GET")
+	api.HandleFunc("/agents/{id}/keys/{keyId}", apiKeyHandler.RevokeAPIKey).Methods("DELETE")
+	api.HandleFunc("/agents/{id}/keys/{keyId}/rotate", apiKeyHandler.RotateAPIKey).Methods("POST")
 
 	// Tasks
 	api.HandleFunc("/tasks", taskHandler.CreateTask).Methods("POST")
@@ -118,6 +238,19 @@ func main() {
 	api.HandleFunc("/tasks/{id}", taskHandler.DeleteTask).Methods("DELETE")
 	api.HandleFunc("/tasks/{id}/status", taskHandler.UpdateTaskStatus).Methods("PUT")
 	api.HandleFunc("/tasks/{id}/reassign", taskHandler.ReassignTask).Methods("PUT")
+	api.HandleFunc("/tasks/{id}/claim", taskHandler.ClaimTask).Methods("POST")
+	api.HandleFunc("/tasks/{id}/history", taskHandler.GetTaskHistory).Methods("GET")
+	api.HandleFunc("/tasks/{id}/schedule", taskHandler.ScheduleTask).Methods("PUT")
+	api.HandleFunc("/tasks/{id}/subtasks", taskHandler.CreateSubtasks).Methods("POST")
+	api.HandleFunc("/tasks/{id}/subtasks", taskHandler.ListSubtasks).Methods("GET")
+	api.HandleFunc("/tasks/{id}/comments", commentHandler.CreateComment).Methods("POST")
+	api.HandleFunc("/tasks/{id}/comments", commentHandler.ListComments).Methods("GET")
+	api.HandleFunc("/tasks/{id}/comments/{commentId}", commentHandler.UpdateComment).Methods("PUT")
+	api.HandleFunc("/tasks/{id}/comments/{commentId}", commentHandler.DeleteComment).Methods("DELETE")
+	api.HandleFunc("/tasks/{id}/dependencies", taskHandler.AddDependency).Methods("POST")
+	api.HandleFunc("/tasks/{id}/dependencies", taskHandler.ListDependencies).Methods("GET")
+	api.HandleFunc("/tasks/{id}/dependencies/{dependsOnId}", taskHandler.RemoveDependency).Methods("DELETE")
+	api.HandleFunc("/projects/{id}/task-graph", taskHandler.GetTaskGraph).Methods("GET")
 
 	// Contexts
 	api.HandleFunc("/contexts", contextHandler.CreateContext).Methods("POST")
@@ -141,11 +274,11 @@ func main() {
 	a2aserver.RegisterA2ARoutes(r, a2aHandler, streamingHandler, artifactHandler, agentCardHandler)
 
 	// WebSocket
-	r.HandleFunc("/ws", wsHandler.HandleWebSocket)
+	r.Handle("/ws", authService.WebSocketMiddleware(http.HandlerFunc(wsHandler.HandleWebSocket)))
 
 	// MCP Protocol endpoint (root level for VS Code)
-	r.HandleFunc("/", mcpHandler.HandleMCP).Methods("GET", "POST", "OPTIONS")
-	r.HandleFunc("/mcp", mcpHandler.HandleMCP).Methods("GET", "POST", "OPTIONS")
+	r.HandleFunc("/", mcpHandler.HandleMCP).Methods("GET", "POST", "DELETE", "OPTIONS")
+	r.HandleFunc("/mcp", mcpHandler.HandleMCP).Methods("GET", "POST", "DELETE", "OPTIONS")
 	r.HandleFunc("/mcp/message", mcpHandler.HandleMCP).Methods("POST", "OPTIONS")
 
 	// Health check
@@ -174,15 +307,25 @@ func main() {
 
 	// Create a custom handler that routes WebSocket without middleware
 	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		// WebSocket requests bypass all middleware
+		// WebSocket requests bypass the other middleware, which would wrap the
+		// connection, but are authenticated like the API
 		if req.URL.Path == "/ws" {
-			wsHandler.HandleWebSocket(w, req)
+			authService.WebSocketMiddleware(http.HandlerFunc(wsHandler.HandleWebSocket)).ServeHTTP(w, req)
+			return
+		}
+
+		// A2A Protocol API routes - handle with CORS and authentication
+		if strings.HasPrefix(req.URL.Path, "/a2a/") {
+			middleware.Recovery(
+				middleware.Logger(
+					c.Handler(authService.Middleware(r)),
+				),
+			).ServeHTTP(w, req)
 			return
 		}
 
-		// A2A Protocol routes - handle with CORS
-		if req.URL.Path == "/.well-known/agent-card.json" ||
-			strings.HasPrefix(req.URL.Path, "/a2a/") {
+		// A2A discovery stays public so clients can learn the auth schemes
+		if req.URL.Path == "/.well-known/agent-card.json" {
 			middleware.Recovery(
 				middleware.Logger(
 					c.Handler(r),
@@ -193,7 +336,9 @@ func main() {
 
 		// MCP Protocol requests (root, /mcp, /mcp/message) - handle with CORS
 		if req.URL.Path == "/" || req.URL.Path == "/mcp" || len(req.URL.Path) >= 4 && req.URL.Path[:4] == "/mcp" {
-			c.Handler(http.HandlerFunc(mcpHandler.HandleMCP)).ServeHTTP(w, req)
+			middleware.Recovery(
+				c.Handler(authService.Middleware(http.HandlerFunc(mcpHandler.HandleMCP))),
+			).ServeHTTP(w, req)
 			return
 		}
 
@@ -202,7 +347,7 @@ func main() {
 			middleware.Recovery(
 				middleware.Logger(
 					middleware.RequestSizeLimit(10*1024*1024)(
-						c.Handler(api),
+						c.Handler(authService.Middleware(api)),
 					),
 				),
 			).ServeHTTP(w, req)
@@ -408,6 +553,33 @@ func runMigrations(db *database.DB) error {
 	return nil
 }
 
+// getDuration reads a duration such as "10m" from the environment
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+func getUUID(key string) uuid.UUID {
+	value := os.Getenv(key)
+	if value == "" {
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, ignoring it", key, value)
+		return uuid.Nil
+	}
+	return id
+}
+
 func getPort() string {
 	port := os.Getenv("PORT")
 	if port == "" {
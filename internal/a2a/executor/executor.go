@@ -0,0 +1,526 @@
+// Package executor runs inbound A2A tasks as Agent Shaker tasks. Each A2A
+// message becomes a task in a project, or is attached to an existing one,
+// which local agents pick up through MCP. The A2A task completes with the
+// local task's output and linked contexts once an agent finishes it.
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	a2amodels "github.com/techbuzzz/agent-shaker/internal/a2a/models"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/taskstate"
+	"github.com/techbuzzz/agent-shaker/internal/websocket"
+)
+
+// DefaultPollInterval is how often a waiting A2A task re-reads its local
+// task, which catches changes made by other processes
+const DefaultPollInterval = 5 * time.Second
+
+// DefaultTimeout is how long an A2A task waits for its local task when the
+// message sets no metadata.timeout
+const DefaultTimeout = time.Hour
+
+// gatewayAgentName is the project agent that creates tasks for A2A callers
+// that are not agents themselves
+const gatewayAgentName = "A2A Gateway"
+
+var (
+	ErrNoDatabase       = errors.New("database not available")
+	ErrNoProject        = errors.New("project_id is required in the message context or metadata")
+	ErrTaskNotInProject = errors.New("task does not belong to the project")
+	ErrUnknownAgent     = errors.New("agent does not belong to the project")
+)
+
+// Config sets the project used for messages that name none and how often
+// and how long local tasks are waited on
+type Config struct {
+	DefaultProject uuid.UUID
+	PollInterval   time.Duration
+	// Timeout bounds the wait for messages that set no metadata.timeout
+	Timeout time.Duration
+	// BaseURL prefixes the URLs of returned artifacts
+	BaseURL string
+}
+
+// Executor implements task.TaskExecutor on top of the tasks table
+type Executor struct {
+	db     *database.DB
+	hub    *websocket.Hub
+	authz  *auth.Service
+	config Config
+
+	mu      sync.Mutex
+	waiting map[uuid.UUID][]chan struct{}
+}
+
+// New creates an executor, filling in defaults for zero config values. It
+// listens to the hub so waiting A2A tasks notice local changes right away.
+func New(db *database.DB, hub *websocket.Hub, authz *auth.Service, config Config) *Executor {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	e := &Executor{db: db, hub: hub, authz: authz, config: config, waiting: make(map[uuid.UUID][]chan struct{})}
+	if hub != nil {
+		hub.AddListener(e.taskChanged)
+	}
+	return e
+}
+
+// target is where an A2A message is carried out
+type target struct {
+	ProjectID  uuid.UUID
+	TaskID     uuid.UUID
+	CreatedBy  uuid.UUID
+	AssignedTo *uuid.UUID
+}
+
+// localTask is the state of the task an A2A task waits on
+type localTask struct {
+	ID         uuid.UUID
+	ProjectID  uuid.UUID
+	Title      string
+	Status     models.TaskStatus
+	AssignedTo *uuid.UUID
+	Output     string
+}
+
+// finished reports whether no agent will work on the task any more
+func (t *localTask) finished() bool {
+	return t.Status == models.StatusDone || t.Status == models.StatusFailed || t.Status == models.StatusCancelled
+}
+
+// Execute creates or attaches the local task for an A2A task, waits until
+// it is finished and returns its output. Contexts linked to the local task
+// are returned as artifacts. A task it created is cancelled when the A2A task
+// stops waiting for it: on cancellation, timeout or failure.
+func (e *Executor) Execute(ctx context.Context, t *a2amodels.Task) (*a2amodels.Result, error) {
+	if e.db == nil {
+		return nil, ErrNoDatabase
+	}
+	dest, err := resolveTarget(t, e.config.DefaultProject)
+	if err != nil {
+		return nil, err
+	}
+	timeout := e.config.Timeout
+	if t.Metadata.Timeout > 0 {
+		timeout = time.Duration(t.Metadata.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var taskID uuid.UUID
+	created := dest.TaskID == uuid.Nil
+	if created {
+		taskID, err = e.create(ctx, t, dest)
+	} else {
+		taskID, err = e.attach(ctx, dest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("A2A task %s is carried out by task %s", t.ID, taskID)
+
+	local, err := e.wait(ctx, taskID)
+	if err != nil {
+		if created {
+			e.abandon(ctx, taskID)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("task %s did not finish within %s", taskID, timeout)
+		}
+		return nil, err
+	}
+
+	result := resultFor(local)
+	if local.Status != models.StatusDone {
+		return nil, fmt.Errorf("task %s %s: %s", local.ID, local.Status, result.Content)
+	}
+	if result.Artifacts, err = e.artifacts(ctx, local.ID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolveTarget reads the project, an existing task and the agents from the
+// message context, then from the metadata extras
+func resolveTarget(t *a2amodels.Task, defaultProject uuid.UUID) (target, error) {
+	var dest target
+	var err error
+	if dest.ProjectID, err = messageID(t, "project_id"); err != nil {
+		return dest, err
+	}
+	if dest.TaskID, err = messageID(t, "task_id"); err != nil {
+		return dest, err
+	}
+	if dest.CreatedBy, err = messageID(t, "created_by"); err != nil {
+		return dest, err
+	}
+	assignedTo, err := messageID(t, "assigned_to")
+	if err != nil {
+		return dest, err
+	}
+	if assignedTo != uuid.Nil {
+		dest.AssignedTo = &assignedTo
+	}
+
+	if dest.ProjectID == uuid.Nil && dest.TaskID == uuid.Nil {
+		if defaultProject == uuid.Nil {
+			return dest, ErrNoProject
+		}
+		dest.ProjectID = defaultProject
+	}
+	return dest, nil
+}
+
+// messageID parses the ID stored under key, uuid.Nil when it is absent
+func messageID(t *a2amodels.Task, key string) (uuid.UUID, error) {
+	value, _ := t.Message.Context[key].(string)
+	if value == "" {
+		value = t.Metadata.Extra[key]
+	}
+	if value == "" {
+		return uuid.Nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return id, nil
+}
+
+// attach checks that an existing task may be followed by the caller
+func (e *Executor) attach(ctx context.Context, dest target) (uuid.UUID, error) {
+	var projectID uuid.UUID
+	err := e.db.QueryRowContext(ctx, "SELECT project_id FROM tasks WHERE id = $1", dest.TaskID).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("task %s not found", dest.TaskID)
+	} else if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find task: %w", err)
+	}
+	if dest.ProjectID != uuid.Nil && dest.ProjectID != projectID {
+		return uuid.Nil, ErrTaskNotInProject
+	}
+	if e.authz != nil {
+		if err := e.authz.Authorize(ctx, projectID, auth.ActionRead); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	return dest.TaskID, nil
+}
+
+// create inserts a pending task for the message. It is created by the
+// calling agent, or by the project's A2A gateway agent for other callers.
+func (e *Executor) create(ctx context.Context, t *a2amodels.Task, dest target) (uuid.UUID, error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if dest.CreatedBy == uuid.Nil {
+		if p := auth.PrincipalFromContext(ctx); p != nil && p.Kind == auth.PrincipalAgent {
+			dest.CreatedBy = p.AgentID
+		} else if dest.CreatedBy, err = gatewayAgent(ctx, tx, dest.ProjectID); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	for _, agentID := range []*uuid.UUID{&dest.CreatedBy, dest.AssignedTo} {
+		if agentID == nil {
+			continue
+		}
+		var exists bool
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM agents WHERE id = $1 AND project_id = $2)", *agentID, dest.ProjectID).Scan(&exists)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to find agent: %w", err)
+		}
+		if !exists && !auth.PrincipalFromContext(ctx).IsAgent(*agentID) {
+			return uuid.Nil, fmt.Errorf("%w: %s", ErrUnknownAgent, *agentID)
+		}
+	}
+
+	if e.authz != nil {
+		if err := e.authz.Authorize(ctx, dest.ProjectID, auth.ActionCreateTask, dest.CreatedBy); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	now := time.Now()
+	task := models.Task{
+		ID:          uuid.New(),
+		ProjectID:   dest.ProjectID,
+		Title:       taskTitle(t.Message.Content),
+		Description: fmt.Sprintf("%s\n\n_Received over A2A as task %s._", t.Message.Content, t.ID),
+		Status:      models.StatusPending,
+		Priority:    taskPriority(t.Metadata.Priority),
+		CreatedBy:   dest.CreatedBy,
+		AssignedTo:  dest.AssignedTo,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO tasks (id, project_id, title, description, status, priority, created_by, assigned_to, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, task.ID, task.ProjectID, task.Title, task.Description, task.Status, task.Priority, task.CreatedBy, task.AssignedTo, task.CreatedAt, task.UpdatedAt)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create task: %w", err)
+	}
+
+	err = history.Record(ctx, tx, history.ActorFromContext(ctx), models.TaskEvent{
+		TaskID:        task.ID,
+		ProjectID:     task.ProjectID,
+		Event:         models.EventTaskCreated,
+		NewStatus:     task.Status,
+		NewAssignedTo: task.AssignedTo,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record task history: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if e.hub != nil {
+		e.hub.BroadcastToProject(task.ProjectID, "task_update", task)
+	}
+	return task.ID, nil
+}
+
+// gatewayAgent returns the project's A2A gateway agent, creating it on first
+// use. It is registered offline so it is never picked for auto-assignment.
+func gatewayAgent(ctx context.Context, tx *sql.Tx, projectID uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, "SELECT id FROM agents WHERE project_id = $1 AND name = $2 ORDER BY created_at LIMIT 1", projectID, gatewayAgentName).Scan(&id)
+	if err == nil {
+		return id, nil
+	} else if err != sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("failed to find the A2A gateway agent: %w", err)
+	}
+
+	id = uuid.New()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO agents (id, project_id, name, role, status, created_at)
+		VALUES ($1, $2, $3, 'a2a', $4, NOW())
+	`, id, projectID, gatewayAgentName, models.AgentOffline)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create the A2A gateway agent: %w", err)
+	}
+	return id, nil
+}
+
+// taskTitle is the first line of the message, shortened to fit the title column
+func taskTitle(content string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "A2A task"
+	}
+	if utf8.RuneCountInString(title) > 255 {
+		title = string([]rune(title)[:252]) + "..."
+	}
+	return title
+}
+
+// taskPriority maps the A2A priority onto a task priority
+func taskPriority(priority string) string {
+	switch priority {
+	case "low", "medium", "high":
+		return priority
+	}
+	return "medium"
+}
+
+// wait returns the task once it is finished. It wakes up on hub updates for
+// the task and re-reads it every PollInterval.
+func (e *Executor) wait(ctx context.Context, taskID uuid.UUID) (*localTask, error) {
+	wake := e.watch(taskID)
+	defer e.unwatch(taskID, wake)
+
+	ticker := time.NewTicker(e.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		local, err := e.load(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if local.finished() {
+			return local, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// abandon cancels a task the executor created once nobody waits for its
+// result, so agents don't pick up work that will never be collected
+func (e *Executor) abandon(ctx context.Context, taskID uuid.UUID) {
+	// The A2A task's context is done by now; its values still name the actor
+	ctx = context.WithoutCancel(ctx)
+	err := taskstate.Update(ctx, e.db, taskID, taskstate.Change{Status: models.StatusCancelled, Actor: history.ActorFromContext(ctx)})
+	var transition *models.TransitionError
+	if errors.As(err, &transition) || errors.Is(err, taskstate.ErrTaskNotFound) {
+		// Already finished or deleted
+		return
+	} else if err != nil {
+		log.Printf("Failed to cancel task %s: %v", taskID, err)
+		return
+	}
+
+	local, err := e.load(ctx, taskID)
+	if err == nil && e.hub != nil {
+		e.hub.BroadcastToProject(local.ProjectID, "task_update", map[string]interface{}{
+			"id":         local.ID,
+			"project_id": local.ProjectID,
+			"status":     local.Status,
+		})
+	}
+}
+
+func (e *Executor) load(ctx context.Context, taskID uuid.UUID) (*localTask, error) {
+	var t localTask
+	var output sql.NullString
+	err := e.db.QueryRowContext(ctx, "SELECT id, project_id, title, status, assigned_to, output FROM tasks WHERE id = $1", taskID).
+		Scan(&t.ID, &t.ProjectID, &t.Title, &t.Status, &t.AssignedTo, &output)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("task %s was deleted", taskID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read task: %w", err)
+	}
+	t.Output = output.String
+	return &t, nil
+}
+
+func (e *Executor) watch(taskID uuid.UUID) chan struct{} {
+	wake := make(chan struct{}, 1)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.waiting[taskID] = append(e.waiting[taskID], wake)
+	return wake
+}
+
+func (e *Executor) unwatch(taskID uuid.UUID, wake chan struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	chans := e.waiting[taskID]
+	for i, ch := range chans {
+		if ch == wake {
+			e.waiting[taskID] = append(chans[:i:i], chans[i+1:]...)
+			break
+		}
+	}
+	if len(e.waiting[taskID]) == 0 {
+		delete(e.waiting, taskID)
+	}
+}
+
+// taskChanged wakes the A2A tasks waiting on a task named by a hub update
+func (e *Executor) taskChanged(projectID uuid.UUID, messageType string, payload interface{}) {
+	if messageType != "task_update" {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	var fields struct {
+		ID     uuid.UUID `json:"id"`
+		TaskID uuid.UUID `json:"task_id"`
+	}
+	json.Unmarshal(data, &fields)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, id := range []uuid.UUID{fields.ID, fields.TaskID} {
+		for _, wake := range e.waiting[id] {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// resultFor is the A2A result of a finished task
+func resultFor(t *localTask) *a2amodels.Result {
+	content := t.Output
+	if content == "" {
+		content = fmt.Sprintf("Task %q finished with status %s", t.Title, t.Status)
+	}
+	data := map[string]any{
+		"task_id":    t.ID.String(),
+		"project_id": t.ProjectID.String(),
+		"status":     string(t.Status),
+	}
+	if t.AssignedTo != nil {
+		data["assigned_to"] = t.AssignedTo.String()
+	}
+	return &a2amodels.Result{Content: content, Format: "markdown", Data: data}
+}
+
+// artifacts returns the contexts linked to a task as A2A artifacts
+func (e *Executor) artifacts(ctx context.Context, taskID uuid.UUID) ([]a2amodels.Artifact, error) {
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT id, title, content, tags, created_at
+		FROM contexts WHERE task_id = $1
+		ORDER BY created_at
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task contexts: %w", err)
+	}
+	defer rows.Close()
+
+	var artifacts []a2amodels.Artifact
+	for rows.Next() {
+		var id uuid.UUID
+		var title string
+		var content sql.NullString
+		var tags []string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &title, &content, pq.Array(&tags), &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task context: %w", err)
+		}
+		artifacts = append(artifacts, contextArtifact(e.config.BaseURL, taskID, id, title, content.String, tags, createdAt))
+	}
+	return artifacts, rows.Err()
+}
+
+// contextArtifact describes a context the way the A2A artifacts endpoint does
+func contextArtifact(baseURL string, taskID, id uuid.UUID, title, content string, tags []string, createdAt time.Time) a2amodels.Artifact {
+	return a2amodels.Artifact{
+		ID:          id.String(),
+		Name:        title,
+		Type:        "markdown",
+		ContentType: "text/markdown",
+		Content:     content,
+		URL:         baseURL + "/a2a/v1/artifacts/" + id.String(),
+		Size:        int64(len(content)),
+		CreatedAt:   createdAt.Format(time.RFC3339),
+		Metadata: map[string]any{
+			"tags":    tags,
+			"task_id": taskID.String(),
+		},
+	}
+}
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	a2amodels "github.com/techbuzzz/agent-shaker/internal/a2a/models"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+func TestResolveTarget(t *testing.T) {
+	project, other, task := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name        string
+		context     map[string]any
+		extra       map[string]string
+		fallback    uuid.UUID
+		wantProject uuid.UUID
+		wantTask    uuid.UUID
+		wantErr     bool
+	}{
+		{name: "message context", context: map[string]any{"project_id": project.String()}, wantProject: project},
+		{name: "metadata", extra: map[string]string{"project_id": project.String()}, wantProject: project},
+		{name: "context wins", context: map[string]any{"project_id": project.String()}, extra: map[string]string{"project_id": other.String()}, wantProject: project},
+		{name: "default project", fallback: other, wantProject: other},
+		{name: "existing task", context: map[string]any{"task_id": task.String()}, wantTask: task},
+		{name: "no project", wantErr: true},
+		{name: "invalid project", context: map[string]any{"project_id": "p1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a2aTask := &a2amodels.Task{
+				Message:  a2amodels.Message{Content: "Do it", Context: tt.context},
+				Metadata: a2amodels.Metadata{Extra: tt.extra},
+			}
+			dest, err := resolveTarget(a2aTask, tt.fallback)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if dest.ProjectID != tt.wantProject || dest.TaskID != tt.wantTask {
+				t.Errorf("resolveTarget() = %+v, want project %s and task %s", dest, tt.wantProject, tt.wantTask)
+			}
+		})
+	}
+}
+
+func TestTaskTitle(t *testing.T) {
+	if got := taskTitle("  Fix the login page\nIt 500s on submit"); got != "Fix the login page" {
+		t.Errorf("Expected the first line, got %q", got)
+	}
+	if got := taskTitle(" \n "); got != "A2A task" {
+		t.Errorf("Expected a fallback title for an empty message, got %q", got)
+	}
+	if got := taskTitle(strings.Repeat("é", 300)); len([]rune(got)) != 255 || !strings.HasSuffix(got, "...") {
+		t.Errorf("Expected long titles to be cut to 255 characters, got %d", len([]rune(got)))
+	}
+}
+
+func TestResultFor(t *testing.T) {
+	local := &localTask{ID: uuid.New(), ProjectID: uuid.New(), Title: "Ship it", Status: models.StatusDone, Output: "Shipped in v2"}
+	result := resultFor(local)
+	if result.Content != "Shipped in v2" || result.Data["task_id"] != local.ID.String() {
+		t.Errorf("Expected the task output and ID, got %+v", result)
+	}
+
+	local.Output = ""
+	if result := resultFor(local); !strings.Contains(result.Content, "Ship it") {
+		t.Errorf("Expected a summary when the task has no output, got %q", result.Content)
+	}
+}
+
+func TestExecuteNeedsDatabase(t *testing.T) {
+	e := New(nil, nil, nil, Config{})
+	if e.config.PollInterval != DefaultPollInterval {
+		t.Errorf("Expected the default poll interval, got %s", e.config.PollInterval)
+	}
+	if e.config.Timeout != DefaultTimeout {
+		t.Errorf("Expected the default timeout, got %s", e.config.Timeout)
+	}
+	if _, err := e.Execute(context.Background(), &a2amodels.Task{}); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("Expected ErrNoDatabase, got %v", err)
+	}
+}
+
+func TestTaskChangedWakesWaiters(t *testing.T) {
+	e := New(nil, nil, nil, Config{})
+	taskID := uuid.New()
+	wake := e.watch(taskID)
+	defer e.unwatch(taskID, wake)
+
+	e.taskChanged(uuid.New(), "agent_update", map[string]any{"id": taskID})
+	e.taskChanged(uuid.New(), "task_update", models.Task{ID: uuid.New()})
+	select {
+	case <-wake:
+		t.Fatal("Expected updates for other tasks to be ignored")
+	default:
+	}
+
+	e.taskChanged(uuid.New(), "task_update", models.Task{ID: taskID})
+	select {
+	case <-wake:
+	case <-time.After(time.Second):
+		t.Fatal("Expected an update of the task to wake its waiter")
+	}
+}
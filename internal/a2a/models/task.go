@@ -45,6 +45,7 @@ type Task struct {
 	ID          string     `json:"id"`
 	Status      TaskStatus `json:"status"`
 	Message     Message    `json:"message"`
+	Metadata    Metadata   `json:"metadata,omitempty"`
 	Result      *Result    `json:"result,omitempty"`
 	Artifacts   []Artifact `json:"artifacts,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
@@ -57,6 +58,8 @@ type Result struct {
 	Content string         `json:"content"`
 	Format  string         `json:"format"`
 	Data    map[string]any `json:"data,omitempty"`
+	// Artifacts produced by the execution, stored on the task
+	Artifacts []Artifact `json:"-"`
 }
 
 // TaskListResponse represents the response for listing tasks
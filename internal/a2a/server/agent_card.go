@@ -9,16 +9,34 @@ import (
 
 // AgentCardHandler handles requests for the A2A agent card
 type AgentCardHandler struct {
-	version string
-	baseURL string
+	version      string
+	baseURL      string
+	authRequired bool
+}
+
+// AgentCardOption configures an AgentCardHandler
+type AgentCardOption func(*AgentCardHandler)
+
+// WithAuthRequired controls whether the card advertises anonymous access.
+// When authentication is required the "none" scheme is omitted.
+func WithAuthRequired(required bool) AgentCardOption {
+	return func(h *AgentCardHandler) {
+		h.authRequired = required
+	}
 }
 
 // NewAgentCardHandler creates a new AgentCardHandler
-func NewAgentCardHandler(version, baseURL string) *AgentCardHandler {
-	return &AgentCardHandler{
+func NewAgentCardHandler(version, baseURL string, opts ...AgentCardOption) *AgentCardHandler {
+	h := &AgentCardHandler{
 		version: version,
 		baseURL: baseURL,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 // ServeHTTP handles GET requests for /.well-known/agent-card.json
@@ -40,6 +58,31 @@ func (h *AgentCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// authSchemes lists the authentication schemes accepted on /a2a/v1
+func (h *AgentCardHandler) authSchemes() []models.AuthScheme {
+	schemes := []models.AuthScheme{
+		{
+			Scheme:            "apiKey",
+			Description:       "Per-agent API key sent in the X-API-Key header",
+			ServiceIdentifier: "agent-shaker",
+		},
+		{
+			Scheme:            "bearer",
+			Description:       "Per-agent API key sent as 'Authorization: Bearer <key>'",
+			ServiceIdentifier: "agent-shaker",
+		},
+	}
+
+	if !h.authRequired {
+		schemes = append(schemes, models.AuthScheme{
+			Scheme:      "none",
+			Description: "Anonymous access is enabled on this deployment (suitable for development and testing)",
+		})
+	}
+
+	return schemes
+}
+
 // generateAgentCard creates the agent card following the official A2A schema v1.0
 func (h *AgentCardHandler) generateAgentCard() models.AgentCard {
 	return models.AgentCard{
@@ -64,13 +107,7 @@ func (h *AgentCardHandler) generateAgentCard() models.AgentCard {
 			SupportsPushNotifications: true,
 		},
 
-		AuthSchemes: []models.AuthScheme{
-			{
-				Scheme:      "none",
-				Description: "Public endpoints require no authentication (suitable for development and testing)",
-			},
-			// TODO: Add apiKey, oauth2, or bearer schemes for production deployments
-		},
+		AuthSchemes: h.authSchemes(),
 
 		// Optional fields
 		Skills: []models.Skill{
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// KeyPrefix marks Agent Shaker API keys so they are easy to recognise in configs and logs
+const KeyPrefix = "ask_"
+
+// keyBytes is the amount of random data in a generated key
+const keyBytes = 32
+
+// GenerateKey creates a new random API key and returns the plaintext key,
+// a short display prefix and the hash that should be persisted
+func GenerateKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, keyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	key = KeyPrefix + hex.EncodeToString(buf)
+	return key, key[:len(KeyPrefix)+8], HashKey(key), nil
+}
+
+// HashKey returns the hex-encoded SHA-256 hash of an API key.
+// Keys carry 256 bits of entropy, so a fast hash is sufficient for lookups.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ExtractKey reads an API key from the Authorization (Bearer) or X-API-Key header
+func ExtractKey(r *http.Request) string {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		scheme, token, found := strings.Cut(authHeader, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// ExtractWebSocketKey is like ExtractKey but also accepts the api_key query
+// parameter, since browsers cannot set headers on WebSocket upgrade requests
+func ExtractWebSocketKey(r *http.Request) string {
+	if key := ExtractKey(r); key != "" {
+		return key
+	}
+	return strings.TrimSpace(r.URL.Query().Get("api_key"))
+}
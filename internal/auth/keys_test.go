@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateKey(t *testing.T) {
+	key, prefix, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	if !strings.HasPrefix(key, KeyPrefix) {
+		t.Errorf("Expected key to start with %q, got %q", KeyPrefix, key)
+	}
+
+	if !strings.HasPrefix(key, prefix) {
+		t.Errorf("Expected prefix %q to be a prefix of the key", prefix)
+	}
+
+	if hash != HashKey(key) {
+		t.Error("Expected returned hash to match HashKey(key)")
+	}
+
+	other, _, _, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	if other == key {
+		t.Error("Expected two generated keys to differ")
+	}
+}
+
+func TestExtractKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "bearer token",
+			headers: map[string]string{"Authorization": "Bearer ask_abc"},
+			want:    "ask_abc",
+		},
+		{
+			name:    "lowercase bearer scheme",
+			headers: map[string]string{"Authorization": "bearer ask_abc"},
+			want:    "ask_abc",
+		},
+		{
+			name:    "api key header",
+			headers: map[string]string{"X-API-Key": "ask_def"},
+			want:    "ask_def",
+		},
+		{
+			name:    "basic auth is ignored",
+			headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			want:    "",
+		},
+		{
+			name:    "no credentials",
+			headers: map[string]string{},
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/projects", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := ExtractKey(r); got != tt.want {
+				t.Errorf("ExtractKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractWebSocketKey(t *testing.T) {
+	r := httptest.NewRequest("GET", "/ws?project_id=p&api_key=ask_query", nil)
+	if got := ExtractWebSocketKey(r); got != "ask_query" {
+		t.Errorf("ExtractWebSocketKey() = %q, want the query key", got)
+	}
+	if got := ExtractKey(r); got != "" {
+		t.Errorf("ExtractKey() = %q, want the query key to be ignored outside WebSockets", got)
+	}
+
+	r.Header.Set("X-API-Key", "ask_header")
+	if got := ExtractWebSocketKey(r); got != "ask_header" {
+		t.Errorf("ExtractWebSocketKey() = %q, want the header key to take precedence", got)
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// PrincipalKind identifies what kind of caller a principal represents
+type PrincipalKind string
+
+const (
+	PrincipalAdmin PrincipalKind = "admin"
+	PrincipalUser  PrincipalKind = "user"
+	PrincipalAgent PrincipalKind = "agent"
+)
+
+// Principal is the authenticated caller resolved from an API key
+type Principal struct {
+	Kind    PrincipalKind
+	KeyID   uuid.UUID
+	UserID  uuid.UUID
+	AgentID uuid.UUID
+	// ProjectID is the home project of an agent principal
+	ProjectID uuid.UUID
+}
+
+// IsAdmin reports whether the principal has global admin access
+func (p *Principal) IsAdmin() bool {
+	return p != nil && p.Kind == PrincipalAdmin
+}
+
+// IsAgent reports whether the principal is the given agent
+func (p *Principal) IsAgent(agentID uuid.UUID) bool {
+	return p != nil && p.Kind == PrincipalAgent && p.AgentID == agentID
+}
+
+// IsUser reports whether the principal is the given user
+func (p *Principal) IsUser(userID uuid.UUID) bool {
+	return p != nil && p.UserID != uuid.Nil && p.UserID == userID
+}
+
+// Owns reports whether any of the given IDs identify the principal
+func (p *Principal) Owns(ids ...uuid.UUID) bool {
+	if p == nil || p.Kind != PrincipalAgent {
+		return false
+	}
+	for _, id := range ids {
+		if id == p.AgentID {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated principal, or nil for anonymous requests
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
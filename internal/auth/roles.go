@@ -0,0 +1,124 @@
+package auth
+
+// Role is a caller's role within a project
+type Role string
+
+const (
+	// RoleAdmin is global and may do anything
+	RoleAdmin Role = "admin"
+	// RoleMaintainer administers a single project
+	RoleMaintainer Role = "maintainer"
+	// RoleAgent works on tasks and contexts it owns
+	RoleAgent Role = "agent"
+	// RoleObserver has read-only access
+	RoleObserver Role = "observer"
+	// RoleNone means the caller has no access to the project
+	RoleNone Role = ""
+)
+
+// ProjectRoles lists the roles that can be assigned per project
+var ProjectRoles = []Role{RoleMaintainer, RoleAgent, RoleObserver}
+
+// IsProjectRole reports whether role can be assigned to a project member
+func IsProjectRole(role Role) bool {
+	for _, r := range ProjectRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// roleRank orders the roles from least to most privileged
+var roleRank = map[Role]int{RoleObserver: 1, RoleAgent: 2, RoleMaintainer: 3, RoleAdmin: 4}
+
+// AtLeast reports whether role is min or a more privileged role. No role
+// satisfies an empty or unknown min.
+func AtLeast(role, min Role) bool {
+	need, ok := roleRank[min]
+	return ok && roleRank[role] >= need
+}
+
+// Action is an operation that is subject to authorization
+type Action string
+
+const (
+	ActionRead          Action = "read"
+	ActionCreateTask    Action = "create_task"
+	ActionUpdateTask    Action = "update_task"
+	ActionClaimTask     Action = "claim_task"
+	ActionReassignTask  Action = "reassign_task"
+	ActionDeleteTask    Action = "delete_task"
+	ActionWriteContext  Action = "write_context"
+	ActionDeleteContext Action = "delete_context"
+	ActionWriteStandup  Action = "write_standup"
+	ActionWriteComment  Action = "write_comment"
+	ActionUpdateAgent   Action = "update_agent"
+	ActionManageAgents  Action = "manage_agents"
+	ActionManageProject Action = "manage_project"
+	ActionDeleteProject Action = "delete_project"
+)
+
+// grant describes how an action is allowed for a role
+type grant int
+
+const (
+	deny grant = iota
+	// own allows the action only on resources the caller owns
+	// (tasks it created or is assigned to, its own contexts, itself)
+	own
+	allow
+)
+
+// permissions is the role/action matrix. Admins are allowed everything.
+var permissions = map[Role]map[Action]grant{
+	RoleMaintainer: {
+		ActionRead:          allow,
+		ActionCreateTask:    allow,
+		ActionUpdateTask:    allow,
+		ActionClaimTask:     allow,
+		ActionReassignTask:  allow,
+		ActionDeleteTask:    allow,
+		ActionWriteContext:  allow,
+		ActionDeleteContext: allow,
+		ActionWriteStandup:  allow,
+		ActionWriteComment:  allow,
+		ActionUpdateAgent:   allow,
+		ActionManageAgents:  allow,
+		ActionManageProject: allow,
+		ActionDeleteProject: allow,
+	},
+	RoleAgent: {
+		ActionRead:          allow,
+		ActionCreateTask:    own,
+		ActionUpdateTask:    own,
+		ActionClaimTask:     allow,
+		ActionReassignTask:  own,
+		ActionDeleteTask:    own,
+		ActionWriteContext:  own,
+		ActionDeleteContext: own,
+		ActionWriteStandup:  own,
+		ActionWriteComment:  own,
+		ActionUpdateAgent:   own,
+	},
+	RoleObserver: {
+		ActionRead: allow,
+	},
+}
+
+// Allowed reports whether role may perform action. owned tells whether the
+// target resource belongs to the caller.
+func Allowed(role Role, action Action, owned bool) bool {
+	if role == RoleAdmin {
+		return true
+	}
+
+	switch permissions[role][action] {
+	case allow:
+		return true
+	case own:
+		return owned
+	default:
+		return false
+	}
+}
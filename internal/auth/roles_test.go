@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   Role
+		action Action
+		owned  bool
+		want   bool
+	}{
+		{name: "admin can delete project", role: RoleAdmin, action: ActionDeleteProject, want: true},
+		{name: "maintainer can manage agents", role: RoleMaintainer, action: ActionManageAgents, want: true},
+		{name: "maintainer can update any task", role: RoleMaintainer, action: ActionUpdateTask, want: true},
+		{name: "agent can read", role: RoleAgent, action: ActionRead, want: true},
+		{name: "agent can claim unowned task", role: RoleAgent, action: ActionClaimTask, want: true},
+		{name: "agent can update own task", role: RoleAgent, action: ActionUpdateTask, owned: true, want: true},
+		{name: "agent cannot update others task", role: RoleAgent, action: ActionUpdateTask, want: false},
+		{name: "agent cannot manage agents", role: RoleAgent, action: ActionManageAgents, owned: true, want: false},
+		{name: "observer can read", role: RoleObserver, action: ActionRead, want: true},
+		{name: "agent can comment as itself", role: RoleAgent, action: ActionWriteComment, owned: true, want: true},
+		{name: "observer cannot comment", role: RoleObserver, action: ActionWriteComment, owned: true, want: false},
+		{name: "observer cannot create task", role: RoleObserver, action: ActionCreateTask, owned: true, want: false},
+		{name: "no role cannot read", role: RoleNone, action: ActionRead, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Allowed(tt.role, tt.action, tt.owned); got != tt.want {
+				t.Errorf("Allowed(%q, %q, %v) = %v, want %v", tt.role, tt.action, tt.owned, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	tests := []struct {
+		role, min Role
+		want      bool
+	}{
+		{RoleAdmin, RoleMaintainer, true},
+		{RoleMaintainer, RoleAgent, true},
+		{RoleAgent, RoleAgent, true},
+		{RoleObserver, RoleAgent, false},
+		{RoleNone, RoleObserver, false},
+		{RoleAdmin, RoleNone, false},
+	}
+	for _, tt := range tests {
+		if got := AtLeast(tt.role, tt.min); got != tt.want {
+			t.Errorf("AtLeast(%q, %q) = %v, want %v", tt.role, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorizePrincipalAnonymous(t *testing.T) {
+	open := NewService(nil, Config{})
+	if err := open.AuthorizePrincipal(context.Background(), nil, uuid.New(), ActionDeleteProject); err != nil {
+		t.Errorf("Expected anonymous access when auth is optional, got %v", err)
+	}
+
+	required := NewService(nil, Config{Required: true})
+	if err := required.AuthorizePrincipal(context.Background(), nil, uuid.New(), ActionRead); err != ErrForbidden {
+		t.Errorf("Expected ErrForbidden when auth is required, got %v", err)
+	}
+}
+
+func TestAuthorizePrincipalAgentHomeProject(t *testing.T) {
+	svc := NewService(nil, Config{Required: true})
+	projectID := uuid.New()
+	agent := &Principal{Kind: PrincipalAgent, AgentID: uuid.New(), ProjectID: projectID}
+
+	if err := svc.AuthorizePrincipal(context.Background(), agent, projectID, ActionUpdateTask, agent.AgentID); err != nil {
+		t.Errorf("Expected agent to update its own task, got %v", err)
+	}
+	if err := svc.AuthorizePrincipal(context.Background(), agent, projectID, ActionUpdateTask, uuid.New()); err != ErrForbidden {
+		t.Errorf("Expected ErrForbidden for another agent's task, got %v", err)
+	}
+	if err := svc.AuthorizePrincipal(context.Background(), agent, uuid.New(), ActionRead); err != ErrForbidden {
+		t.Errorf("Expected ErrForbidden outside the agent's project, got %v", err)
+	}
+}
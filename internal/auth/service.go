@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing API key")
+	ErrInvalidCredentials = errors.New("invalid, expired or revoked API key")
+	ErrForbidden          = errors.New("permission denied")
+)
+
+// Config controls how requests are authenticated
+type Config struct {
+	// AdminKey is an optional static key (ADMIN_API_KEY) that grants admin access
+	AdminKey string
+	// Required rejects requests without credentials when true. When false,
+	// credentials are still validated if present but anonymous access is allowed.
+	Required bool
+}
+
+// Service authenticates API keys against the database
+type Service struct {
+	db     *database.DB
+	config Config
+}
+
+// NewService creates a new authentication service
+func NewService(db *database.DB, config Config) *Service {
+	return &Service{db: db, config: config}
+}
+
+// Required reports whether anonymous requests are rejected
+func (s *Service) Required() bool {
+	return s.config.Required
+}
+
+// Authenticate resolves an API key to a principal and records its use
+func (s *Service) Authenticate(ctx context.Context, key string) (*Principal, error) {
+	if key == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	if s.config.AdminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.config.AdminKey)) == 1 {
+		return &Principal{Kind: PrincipalAdmin}, nil
+	}
+
+	if s.db == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var keyID uuid.UUID
+	var agentID, projectID, userID uuid.NullUUID
+	var isAdmin bool
+	err := s.db.QueryRowContext(ctx, `
+		WITH k AS (
+			UPDATE api_keys
+			SET last_used_at = NOW()
+			WHERE key_hash = $1
+			  AND revoked_at IS NULL
+			  AND (expires_at IS NULL OR expires_at > NOW())
+			RETURNING id, agent_id, user_id
+		)
+		SELECT k.id, k.agent_id, a.project_id, k.user_id, COALESCE(u.is_admin, FALSE)
+		FROM k
+		LEFT JOIN agents a ON a.id = k.agent_id
+		LEFT JOIN users u ON u.id = k.user_id
+	`, HashKey(key)).Scan(&keyID, &agentID, &projectID, &userID, &isAdmin)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to verify API key: %w", err)
+	}
+
+	if agentID.Valid {
+		return &Principal{Kind: PrincipalAgent, KeyID: keyID, AgentID: agentID.UUID, ProjectID: projectID.UUID}, nil
+	}
+	if isAdmin {
+		return &Principal{Kind: PrincipalAdmin, KeyID: keyID, UserID: userID.UUID}, nil
+	}
+	return &Principal{Kind: PrincipalUser, KeyID: keyID, UserID: userID.UUID}, nil
+}
+
+// RoleFor resolves the principal's role in a project
+func (s *Service) RoleFor(ctx context.Context, p *Principal, projectID uuid.UUID) (Role, error) {
+	if p == nil {
+		return RoleNone, nil
+	}
+	if p.IsAdmin() {
+		return RoleAdmin, nil
+	}
+
+	// Explicit assignments take precedence over an agent's implicit home project role
+	if s.db != nil {
+		var role string
+		var err error
+		if p.Kind == PrincipalAgent {
+			err = s.db.QueryRowContext(ctx, "SELECT role FROM project_roles WHERE project_id = $1 AND agent_id = $2", projectID, p.AgentID).Scan(&role)
+		} else {
+			err = s.db.QueryRowContext(ctx, "SELECT role FROM project_roles WHERE project_id = $1 AND user_id = $2", projectID, p.UserID).Scan(&role)
+		}
+		if err == nil {
+			return Role(role), nil
+		}
+		if err != sql.ErrNoRows {
+			return RoleNone, fmt.Errorf("failed to resolve role: %w", err)
+		}
+	}
+
+	if p.Kind == PrincipalAgent && p.ProjectID == projectID {
+		return RoleAgent, nil
+	}
+	return RoleNone, nil
+}
+
+// Authorize checks whether the principal in ctx may perform action in a project.
+// owners are the agents that own the target resource (creator, assignee, author).
+func (s *Service) Authorize(ctx context.Context, projectID uuid.UUID, action Action, owners ...uuid.UUID) error {
+	return s.AuthorizePrincipal(ctx, PrincipalFromContext(ctx), projectID, action, owners...)
+}
+
+// AuthorizePrincipal is like Authorize for an explicitly supplied principal.
+// Anonymous callers are allowed everything when authentication is not required,
+// which keeps development setups working as before.
+func (s *Service) AuthorizePrincipal(ctx context.Context, p *Principal, projectID uuid.UUID, action Action, owners ...uuid.UUID) error {
+	if p == nil {
+		if s.config.Required {
+			return ErrForbidden
+		}
+		return nil
+	}
+
+	role, err := s.RoleFor(ctx, p, projectID)
+	if err != nil {
+		return err
+	}
+
+	if !Allowed(role, action, p.Owns(owners...)) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// VisibleProjects returns the projects the principal in ctx may read.
+// all is true when no filtering is needed (admins and anonymous open access).
+func (s *Service) VisibleProjects(ctx context.Context) (ids []uuid.UUID, all bool, err error) {
+	p := PrincipalFromContext(ctx)
+	if p == nil || p.IsAdmin() {
+		return nil, p != nil || !s.config.Required, nil
+	}
+	if s.db == nil {
+		return nil, false, nil
+	}
+
+	query := "SELECT project_id FROM project_roles WHERE user_id = $1"
+	if p.Kind == PrincipalAgent {
+		query = "SELECT project_id FROM project_roles WHERE agent_id = $1 UNION SELECT project_id FROM agents WHERE id = $1"
+	}
+	memberID := p.UserID
+	if p.Kind == PrincipalAgent {
+		memberID = p.AgentID
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, memberID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list visible projects: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, false, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, false, rows.Err()
+}
+
+// Middleware authenticates requests and stores the principal in the request context
+func (s *Service) Middleware(next http.Handler) http.Handler {
+	return s.authenticate(next, ExtractKey)
+}
+
+// WebSocketMiddleware is like Middleware for WebSocket upgrade requests,
+// which may also carry the key in the api_key query parameter
+func (s *Service) WebSocketMiddleware(next http.Handler) http.Handler {
+	return s.authenticate(next, ExtractWebSocketKey)
+}
+
+func (s *Service) authenticate(next http.Handler, extractKey func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// CORS preflight requests never carry credentials
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := extractKey(r)
+		if key == "" {
+			if s.config.Required {
+				unauthorized(w, ErrMissingCredentials)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := s.Authenticate(r.Context(), key)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidCredentials) {
+				log.Printf("Authentication error: %v", err)
+			}
+			unauthorized(w, ErrInvalidCredentials)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="agent-shaker"`)
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
@@ -0,0 +1,196 @@
+// Package comments manages threaded discussions on tasks and notifies
+// project subscribers of every change through task_comment events.
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/websocket"
+)
+
+var (
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrParentNotFound  = errors.New("parent comment not found on this task")
+	ErrAgentNotFound   = errors.New("agent not found")
+)
+
+// selectComment reads a comment together with its author's name
+const selectComment = `
+	SELECT c.id, c.task_id, c.parent_id, c.agent_id, COALESCE(a.name, ''), c.body, c.created_at, c.updated_at, c.edited_at
+	FROM task_comments c
+	LEFT JOIN agents a ON a.id = c.agent_id
+`
+
+// Service stores task comments and broadcasts changes to them
+type Service struct {
+	db  *database.DB
+	hub *websocket.Hub
+}
+
+// NewService creates a new comment service
+func NewService(db *database.DB, hub *websocket.Hub) *Service {
+	return &Service{db: db, hub: hub}
+}
+
+// Create adds a comment to a task. A reply must answer a comment on the same task.
+func (s *Service) Create(ctx context.Context, taskID uuid.UUID, req models.CreateCommentRequest) (*models.TaskComment, error) {
+	var projectID uuid.UUID
+	err := s.db.QueryRowContext(ctx, "SELECT project_id FROM tasks WHERE id = $1", taskID).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load task: %w", err)
+	}
+
+	var agentExists bool
+	err = s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM agents WHERE id = $1)", req.AgentID).Scan(&agentExists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify agent: %w", err)
+	}
+	if !agentExists {
+		return nil, ErrAgentNotFound
+	}
+
+	if req.ParentID != nil {
+		var exists bool
+		err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM task_comments WHERE id = $1 AND task_id = $2)", *req.ParentID, taskID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load parent comment: %w", err)
+		}
+		if !exists {
+			return nil, ErrParentNotFound
+		}
+	}
+
+	id := uuid.New()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO task_comments (id, task_id, parent_id, agent_id, body, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+	`, id, taskID, req.ParentID, req.AgentID, req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	comment, _, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.broadcast(projectID, "created", comment)
+	return comment, nil
+}
+
+// List returns a task's comments as threads, oldest first
+func (s *Service) List(ctx context.Context, taskID uuid.UUID) ([]*models.TaskComment, error) {
+	rows, err := s.db.QueryContext(ctx, selectComment+" WHERE c.task_id = $1 ORDER BY c.created_at, c.id", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []models.TaskComment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return models.CommentThreads(comments), nil
+}
+
+// Get returns a single comment (without replies) and the project of its task
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*models.TaskComment, uuid.UUID, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT c.id, c.task_id, c.parent_id, c.agent_id, COALESCE(a.name, ''), c.body, c.created_at, c.updated_at, c.edited_at, t.project_id
+		FROM task_comments c
+		JOIN tasks t ON t.id = c.task_id
+		LEFT JOIN agents a ON a.id = c.agent_id
+		WHERE c.id = $1
+	`, id)
+
+	var c models.TaskComment
+	var parentID uuid.NullUUID
+	var editedAt sql.NullTime
+	var projectID uuid.UUID
+	err := row.Scan(&c.ID, &c.TaskID, &parentID, &c.AgentID, &c.AgentName, &c.Body, &c.CreatedAt, &c.UpdatedAt, &editedAt, &projectID)
+	if err == sql.ErrNoRows {
+		return nil, uuid.Nil, ErrCommentNotFound
+	} else if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("failed to load comment: %w", err)
+	}
+	setNullable(&c, parentID, editedAt)
+	return &c, projectID, nil
+}
+
+// Update replaces a comment's body and marks it as edited
+func (s *Service) Update(ctx context.Context, id uuid.UUID, body string) (*models.TaskComment, error) {
+	result, err := s.db.ExecContext(ctx, "UPDATE task_comments SET body = $1, updated_at = NOW(), edited_at = NOW() WHERE id = $2", body, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrCommentNotFound
+	}
+
+	comment, projectID, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.broadcast(projectID, "updated", comment)
+	return comment, nil
+}
+
+// Delete removes a comment together with its replies
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	comment, projectID, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM task_comments WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	s.broadcast(projectID, "deleted", comment)
+	return nil
+}
+
+func (s *Service) broadcast(projectID uuid.UUID, action string, comment *models.TaskComment) {
+	if s.hub == nil {
+		return
+	}
+	s.hub.BroadcastToProject(projectID, "task_comment", map[string]interface{}{
+		"action":  action,
+		"task_id": comment.TaskID,
+		"comment": comment,
+	})
+}
+
+func scanComment(rows *sql.Rows) (*models.TaskComment, error) {
+	var c models.TaskComment
+	var parentID uuid.NullUUID
+	var editedAt sql.NullTime
+	if err := rows.Scan(&c.ID, &c.TaskID, &parentID, &c.AgentID, &c.AgentName, &c.Body, &c.CreatedAt, &c.UpdatedAt, &editedAt); err != nil {
+		return nil, err
+	}
+	setNullable(&c, parentID, editedAt)
+	return &c, nil
+}
+
+func setNullable(c *models.TaskComment, parentID uuid.NullUUID, editedAt sql.NullTime) {
+	if parentID.Valid {
+		c.ParentID = &parentID.UUID
+	}
+	if editedAt.Valid {
+		c.EditedAt = &editedAt.Time
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+// ListAgentRoles returns the roles agents in a project may take
+func (h *ProjectHandler) ListAgentRoles(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT project_id, name, description, created_at
+		FROM agent_roles
+		WHERE project_id = $1
+		ORDER BY name
+	`, projectID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve roles", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	roles := []models.AgentRoleDefinition{}
+	for rows.Next() {
+		var role models.AgentRoleDefinition
+		if err := rows.Scan(&role.ProjectID, &role.Name, &role.Description, &role.CreatedAt); err != nil {
+			http.Error(w, "Failed to scan role", http.StatusInternalServerError)
+			return
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to retrieve roles", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// CreateAgentRole adds a role to a project's vocabulary, or updates the
+// description of an existing one
+func (h *ProjectHandler) CreateAgentRole(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateAgentRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.ValidateCreateAgentRoleRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionManageProject) {
+		return
+	}
+
+	var role models.AgentRoleDefinition
+	err = h.db.QueryRow(`
+		INSERT INTO agent_roles (project_id, name, description)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (project_id, name) DO UPDATE SET description = EXCLUDED.description
+		RETURNING project_id, name, description, created_at
+	`, projectID, models.NormalizeAgentRole(req.Name), req.Description).Scan(&role.ProjectID, &role.Name, &role.Description, &role.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to create role", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+// DeleteAgentRole removes a role from a project's vocabulary. Roles still
+// held by agents cannot be removed.
+func (h *ProjectHandler) DeleteAgentRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+	name := models.NormalizeAgentRole(models.AgentRole(vars["role"]))
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionManageProject) {
+		return
+	}
+
+	var inUse bool
+	err = h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM agents WHERE project_id = $1 AND LOWER(role) = $2)", projectID, name).Scan(&inUse)
+	if err != nil {
+		http.Error(w, "Failed to check role usage", http.StatusInternalServerError)
+		return
+	}
+	if inUse {
+		http.Error(w, "Role is still assigned to agents", http.StatusConflict)
+		return
+	}
+
+	var deleted string
+	err = h.db.QueryRow("DELETE FROM agent_roles WHERE project_id = $1 AND name = $2 RETURNING name", projectID, name).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Role not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete role", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,24 +3,33 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
 	"github.com/techbuzzz/agent-shaker/internal/database"
 	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/presence"
 	"github.com/techbuzzz/agent-shaker/internal/validator"
 	"github.com/techbuzzz/agent-shaker/internal/websocket"
 )
 
 type AgentHandler struct {
-	db  *database.DB
-	hub *websocket.Hub
+	db       *database.DB
+	hub      *websocket.Hub
+	authz    *auth.Service
+	presence *presence.Tracker
 }
 
-func NewAgentHandler(db *database.DB, hub *websocket.Hub) *AgentHandler {
-	return &AgentHandler{db: db, hub: hub}
+func NewAgentHandler(db *database.DB, hub *websocket.Hub, authz *auth.Service, presence *presence.Tracker) *AgentHandler {
+	return &AgentHandler{db: db, hub: hub, authz: authz, presence: presence}
 }
 
 func (h *AgentHandler) CreateAgent(w http.ResponseWriter, r *http.Request) {
@@ -36,21 +45,40 @@ func (h *AgentHandler) CreateAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, req.ProjectID, auth.ActionManageAgents) {
+		return
+	}
+
 	agent := models.Agent{
-		ID:        uuid.New(),
-		ProjectID: req.ProjectID,
-		Name:      req.Name,
-		Role:      req.Role,
-		Team:      req.Team,
-		Status:    "active",
-		LastSeen:  time.Now(),
-		CreatedAt: time.Now(),
+		ID:           uuid.New(),
+		ProjectID:    req.ProjectID,
+		Name:         req.Name,
+		Role:         models.NormalizeAgentRole(req.Role),
+		Team:         req.Team,
+		Status:       "active",
+		Capabilities: models.NormalizeCapabilities(req.Capabilities),
+		LastSeen:     time.Now(),
+		CreatedAt:    time.Now(),
+	}
+
+	// The role must be part of the project's vocabulary
+	if agent.Role != "" {
+		var defined bool
+		err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM agent_roles WHERE project_id = $1 AND name = $2)", agent.ProjectID, agent.Role).Scan(&defined)
+		if err != nil {
+			http.Error(w, "Failed to verify role", http.StatusInternalServerError)
+			return
+		}
+		if !defined {
+			http.Error(w, fmt.Sprintf("Role %q is not defined for this project", agent.Role), http.StatusBadRequest)
+			return
+		}
 	}
 
 	_, err := h.db.Exec(`
-		INSERT INTO agents (id, project_id, name, role, team, status, last_seen, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	`, agent.ID, agent.ProjectID, agent.Name, agent.Role, agent.Team, agent.Status, agent.LastSeen, agent.CreatedAt)
+		INSERT INTO agents (id, project_id, name, role, team, status, capabilities, last_seen, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, agent.ID, agent.ProjectID, agent.Name, agent.Role, agent.Team, agent.Status, pq.Array(agent.Capabilities), agent.LastSeen, agent.CreatedAt)
 	if err != nil {
 		http.Error(w, "Failed to create agent", http.StatusInternalServerError)
 		return
@@ -64,35 +92,54 @@ func (h *AgentHandler) CreateAgent(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(agent)
 }
 
+// ListAgents lists agents, optionally filtered by project, role, team and
+// capability. capability may be repeated or comma-separated; agents must
+// have all of the listed capabilities.
 func (h *AgentHandler) ListAgents(w http.ResponseWriter, r *http.Request) {
-	projectIDStr := r.URL.Query().Get("project_id")
-
-	var rows *sql.Rows
-	var err error
-
-	if projectIDStr == "" {
-		// If no project_id, return all agents
-		rows, err = h.db.Query(`
-			SELECT id, project_id, name, role, team, status, last_seen, created_at
-			FROM agents
-			ORDER BY created_at DESC
-		`)
-	} else {
-		// If project_id provided, filter by project
-		projectID, parseErr := uuid.Parse(projectIDStr)
-		if parseErr != nil {
+	scope, ok := visibleProjects(w, r, h.authz)
+	if !ok {
+		return
+	}
+
+	query := `
+		SELECT id, project_id, name, role, team, status, capabilities, last_seen, created_at
+		FROM agents
+		WHERE 1=1
+	`
+	args := []interface{}{}
+
+	if projectIDStr := r.URL.Query().Get("project_id"); projectIDStr != "" {
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
 			http.Error(w, "Invalid project_id format", http.StatusBadRequest)
 			return
 		}
+		args = append(args, projectID)
+		query += fmt.Sprintf(" AND project_id = $%d", len(args))
+	}
+
+	if role := models.NormalizeAgentRole(models.AgentRole(r.URL.Query().Get("role"))); role != "" {
+		args = append(args, role)
+		query += fmt.Sprintf(" AND LOWER(role) = $%d", len(args))
+	}
 
-		rows, err = h.db.Query(`
-			SELECT id, project_id, name, role, team, status, last_seen, created_at
-			FROM agents
-			WHERE project_id = $1
-			ORDER BY created_at DESC
-		`, projectID)
+	if team := r.URL.Query().Get("team"); team != "" {
+		args = append(args, team)
+		query += fmt.Sprintf(" AND LOWER(team) = LOWER($%d)", len(args))
 	}
 
+	var capabilities []string
+	for _, value := range r.URL.Query()["capability"] {
+		capabilities = append(capabilities, strings.Split(value, ",")...)
+	}
+	if capabilities = models.NormalizeCapabilities(capabilities); len(capabilities) > 0 {
+		args = append(args, pq.Array(capabilities))
+		query += fmt.Sprintf(" AND capabilities @> $%d::text[]", len(args))
+	}
+
+	query += " ORDER BY created_at DESC"
+
+	rows, err := h.db.Query(query, args...)
 	if err != nil {
 		http.Error(w, "Failed to retrieve agents", http.StatusInternalServerError)
 		return
@@ -102,10 +149,13 @@ func (h *AgentHandler) ListAgents(w http.ResponseWriter, r *http.Request) {
 	var agents []models.Agent
 	for rows.Next() {
 		var a models.Agent
-		if err := rows.Scan(&a.ID, &a.ProjectID, &a.Name, &a.Role, &a.Team, &a.Status, &a.LastSeen, &a.CreatedAt); err != nil {
+		if err := rows.Scan(&a.ID, &a.ProjectID, &a.Name, &a.Role, &a.Team, &a.Status, pq.Array(&a.Capabilities), &a.LastSeen, &a.CreatedAt); err != nil {
 			http.Error(w, "Failed to scan agent", http.StatusInternalServerError)
 			return
 		}
+		if !scope.includes(a.ProjectID) {
+			continue
+		}
 		agents = append(agents, a)
 	}
 
@@ -128,10 +178,10 @@ func (h *AgentHandler) GetAgent(w http.ResponseWriter, r *http.Request) {
 
 	var agent models.Agent
 	err = h.db.QueryRow(`
-		SELECT id, project_id, name, role, team, status, last_seen, created_at
+		SELECT id, project_id, name, role, team, status, capabilities, last_seen, created_at
 		FROM agents
 		WHERE id = $1
-	`, id).Scan(&agent.ID, &agent.ProjectID, &agent.Name, &agent.Role, &agent.Team, &agent.Status, &agent.LastSeen, &agent.CreatedAt)
+	`, id).Scan(&agent.ID, &agent.ProjectID, &agent.Name, &agent.Role, &agent.Team, &agent.Status, pq.Array(&agent.Capabilities), &agent.LastSeen, &agent.CreatedAt)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Agent not found", http.StatusNotFound)
 		return
@@ -140,6 +190,10 @@ func (h *AgentHandler) GetAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, agent.ProjectID, auth.ActionRead) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(agent)
 }
@@ -164,12 +218,15 @@ func (h *AgentHandler) UpdateAgentStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = h.db.Exec(`
-		UPDATE agents
-		SET status = $1, last_seen = $2
-		WHERE id = $3
-	`, req.Status, time.Now(), id)
-	if err != nil {
+	if !authorizeAgent(w, r, h.db, h.authz, id, auth.ActionUpdateAgent) {
+		return
+	}
+
+	err = h.presence.Set(r.Context(), id, req.Status)
+	if errors.Is(err, presence.ErrAgentNotFound) {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		http.Error(w, "Failed to update agent status", http.StatusInternalServerError)
 		return
 	}
@@ -177,10 +234,10 @@ func (h *AgentHandler) UpdateAgentStatus(w http.ResponseWriter, r *http.Request)
 	// Get updated agent
 	var agent models.Agent
 	err = h.db.QueryRow(`
-		SELECT id, project_id, name, role, team, status, last_seen, created_at
+		SELECT id, project_id, name, role, team, status, capabilities, last_seen, created_at
 		FROM agents
 		WHERE id = $1
-	`, id).Scan(&agent.ID, &agent.ProjectID, &agent.Name, &agent.Role, &agent.Team, &agent.Status, &agent.LastSeen, &agent.CreatedAt)
+	`, id).Scan(&agent.ID, &agent.ProjectID, &agent.Name, &agent.Role, &agent.Team, &agent.Status, pq.Array(&agent.Capabilities), &agent.LastSeen, &agent.CreatedAt)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Agent not found", http.StatusNotFound)
 		return
@@ -196,6 +253,49 @@ func (h *AgentHandler) UpdateAgentStatus(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(agent)
 }
 
+// UpdateAgentCapabilities replaces an agent's capability tags
+func (h *AgentHandler) UpdateAgentCapabilities(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid agent ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateAgentCapabilitiesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.ValidateCapabilities(req.Capabilities); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeAgent(w, r, h.db, h.authz, id, auth.ActionUpdateAgent) {
+		return
+	}
+
+	var agent models.Agent
+	err = h.db.QueryRow(`
+		UPDATE agents
+		SET capabilities = $1
+		WHERE id = $2
+		RETURNING id, project_id, name, role, team, status, capabilities, last_seen, created_at
+	`, pq.Array(models.NormalizeCapabilities(req.Capabilities)), id).Scan(&agent.ID, &agent.ProjectID, &agent.Name, &agent.Role, &agent.Team, &agent.Status, pq.Array(&agent.Capabilities), &agent.LastSeen, &agent.CreatedAt)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update agent capabilities", http.StatusInternalServerError)
+		return
+	}
+
+	h.hub.BroadcastToProject(agent.ProjectID, "agent_update", agent)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(agent)
+}
+
 func (h *AgentHandler) DeleteAgent(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -206,6 +306,10 @@ func (h *AgentHandler) DeleteAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeAgent(w, r, h.db, h.authz, id, auth.ActionManageAgents) {
+		return
+	}
+
 	// Begin transaction
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -267,3 +371,35 @@ func (h *AgentHandler) DeleteAgent(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetAgentPresence returns an agent's most recent presence transitions, newest first
+func (h *AgentHandler) GetAgentPresence(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid agent ID format", http.StatusBadRequest)
+		return
+	}
+
+	limitParam := r.URL.Query().Get("limit")
+	if limitParam == "" {
+		limitParam = "50"
+	}
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit <= 0 {
+		http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeAgent(w, r, h.db, h.authz, id, auth.ActionRead) {
+		return
+	}
+
+	events, err := presence.History(r.Context(), h.db, id, limit)
+	if err != nil {
+		http.Error(w, "Failed to retrieve agent presence", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
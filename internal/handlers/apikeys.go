@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// APIKeyHandler manages API keys issued to agents and users
+type APIKeyHandler struct {
+	db    *database.DB
+	authz *auth.Service
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(db *database.DB, authz *auth.Service) *APIKeyHandler {
+	return &APIKeyHandler{db: db, authz: authz}
+}
+
+// keyOwner identifies the agent or user a key belongs to
+type keyOwner struct {
+	column string // "agent_id" or "user_id"
+	id     uuid.UUID
+}
+
+func (o keyOwner) isUser() bool {
+	return o.column == "user_id"
+}
+
+// parseKeyOwner reads the key owner from the route. User key routes use
+// {userId}; agent key routes use {id}.
+func parseKeyOwner(w http.ResponseWriter, r *http.Request) (keyOwner, bool) {
+	vars := mux.Vars(r)
+	if userIDStr, ok := vars["userId"]; ok {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return keyOwner{}, false
+		}
+		return keyOwner{column: "user_id", id: userID}, true
+	}
+
+	agentID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid agent ID format", http.StatusBadRequest)
+		return keyOwner{}, false
+	}
+	return keyOwner{column: "agent_id", id: agentID}, true
+}
+
+// canManageKeys checks whether the caller may manage keys for the owner and
+// writes an error response when it may not. Agent keys can be managed by the
+// agent itself and by maintainers of its project; user keys only by the user.
+// Anonymous callers are only let through when authentication is not required,
+// in which case the auth middleware has already allowed the request.
+func (h *APIKeyHandler) canManageKeys(w http.ResponseWriter, r *http.Request, owner keyOwner) bool {
+	principal := auth.PrincipalFromContext(r.Context())
+	if principal == nil || principal.IsAdmin() {
+		return true
+	}
+
+	if owner.isUser() {
+		if !principal.IsUser(owner.id) {
+			http.Error(w, "Not allowed to manage keys for this user", http.StatusForbidden)
+			return false
+		}
+		return true
+	}
+
+	if principal.IsAgent(owner.id) {
+		return true
+	}
+	return authorizeAgent(w, r, h.db, h.authz, owner.id, auth.ActionManageAgents)
+}
+
+// ownerExists reports whether the agent or user a key is issued to exists
+func (h *APIKeyHandler) ownerExists(owner keyOwner) (bool, error) {
+	table := "agents"
+	if owner.isUser() {
+		table = "users"
+	}
+
+	var exists bool
+	err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM "+table+" WHERE id = $1)", owner.id).Scan(&exists)
+	return exists, err
+}
+
+// CreateAPIKey issues a new API key for an agent or user
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	owner, ok := parseKeyOwner(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.canManageKeys(w, r, owner) {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		http.Error(w, "expires_in_days cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if len(req.Name) > 255 {
+		http.Error(w, "name cannot exceed 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	exists, err := h.ownerExists(owner)
+	if err != nil {
+		http.Error(w, "Failed to verify key owner", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Key owner not found", http.StatusNotFound)
+		return
+	}
+
+	resp, err := insertAPIKey(h.db, owner, strings.TrimSpace(req.Name), req.ExpiresInDays)
+	if err != nil {
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListAPIKeys lists the keys issued to an agent or user (without secrets)
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	owner, ok := parseKeyOwner(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.canManageKeys(w, r, owner) {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, agent_id, user_id, COALESCE(name, ''), key_prefix, created_at, last_used_at, expires_at, revoked_at
+		FROM api_keys
+		WHERE `+owner.column+` = $1
+		ORDER BY created_at DESC
+	`, owner.id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve API keys", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(&k.ID, &k.AgentID, &k.UserID, &k.Name, &k.KeyPrefix, &k.CreatedAt, &k.LastUsedAt, &k.ExpiresAt, &k.RevokedAt); err != nil {
+			http.Error(w, "Failed to scan API key", http.StatusInternalServerError)
+			return
+		}
+		keys = append(keys, k)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeAPIKey revokes an agent's or user's API key
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	owner, keyID, ok := parseAPIKeyVars(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.canManageKeys(w, r, owner) {
+		return
+	}
+
+	result, err := h.db.Exec(`
+		UPDATE api_keys
+		SET revoked_at = $1
+		WHERE id = $2 AND `+owner.column+` = $3 AND revoked_at IS NULL
+	`, time.Now(), keyID, owner.id)
+	if err != nil {
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RotateAPIKey revokes an existing key and issues a replacement with the same name
+func (h *APIKeyHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	owner, keyID, ok := parseAPIKeyVars(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.canManageKeys(w, r, owner) {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var name string
+	err = tx.QueryRow(`
+		UPDATE api_keys
+		SET revoked_at = $1
+		WHERE id = $2 AND `+owner.column+` = $3 AND revoked_at IS NULL
+		RETURNING COALESCE(name, '')
+	`, time.Now(), keyID, owner.id).Scan(&name)
+	if err == sql.ErrNoRows {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to rotate API key", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := insertAPIKey(tx, owner, name, 0)
+	if err != nil {
+		http.Error(w, "Failed to rotate API key", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// execer is satisfied by both *database.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertAPIKey generates and stores a new key, returning the plaintext once
+func insertAPIKey(db execer, owner keyOwner, name string, expiresInDays int) (*models.CreateAPIKeyResponse, error) {
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.CreateAPIKeyResponse{
+		APIKey: models.APIKey{
+			ID:        uuid.New(),
+			Name:      name,
+			KeyPrefix: prefix,
+			CreatedAt: time.Now(),
+		},
+		Key: key,
+	}
+	if owner.isUser() {
+		resp.UserID = &owner.id
+	} else {
+		resp.AgentID = &owner.id
+	}
+	if expiresInDays > 0 {
+		expiresAt := resp.CreatedAt.Add(time.Duration(expiresInDays) * 24 * time.Hour)
+		resp.ExpiresAt = &expiresAt
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO api_keys (id, agent_id, user_id, name, key_prefix, key_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, resp.ID, resp.AgentID, resp.UserID, resp.Name, resp.KeyPrefix, hash, resp.CreatedAt, resp.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func parseAPIKeyVars(w http.ResponseWriter, r *http.Request) (keyOwner, uuid.UUID, bool) {
+	owner, ok := parseKeyOwner(w, r)
+	if !ok {
+		return keyOwner{}, uuid.Nil, false
+	}
+	keyID, err := uuid.Parse(mux.Vars(r)["keyId"])
+	if err != nil {
+		http.Error(w, "Invalid key ID format", http.StatusBadRequest)
+		return keyOwner{}, uuid.Nil, false
+	}
+	return owner, keyID, true
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+)
+
+// authorize checks that the caller may perform action in a project and writes
+// a 403 (or 500) response when it may not. owners are the agents that own the
+// target resource, which lets agents act on their own tasks and contexts.
+func authorize(w http.ResponseWriter, r *http.Request, svc *auth.Service, projectID uuid.UUID, action auth.Action, owners ...uuid.UUID) bool {
+	if svc == nil {
+		return true
+	}
+
+	err := svc.Authorize(r.Context(), projectID, action, owners...)
+	if errors.Is(err, auth.ErrForbidden) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return false
+	} else if err != nil {
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// projectScope is the set of projects a caller is allowed to read
+type projectScope struct {
+	all bool
+	ids map[uuid.UUID]bool
+}
+
+func (s projectScope) includes(projectID uuid.UUID) bool {
+	return s.all || s.ids[projectID]
+}
+
+// where returns a WHERE clause limiting column to the scope's projects, with
+// its argument, for queries that aggregate rows rather than list them
+func (s projectScope) where(column string) (string, []interface{}) {
+	if s.all {
+		return "", nil
+	}
+	ids := make([]string, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id.String())
+	}
+	return " WHERE " + column + " = ANY($1::uuid[])", []interface{}{pq.Array(ids)}
+}
+
+// visibleProjects resolves the caller's readable projects for filtering list endpoints
+func visibleProjects(w http.ResponseWriter, r *http.Request, svc *auth.Service) (projectScope, bool) {
+	if svc == nil {
+		return projectScope{all: true}, true
+	}
+
+	ids, all, err := svc.VisibleProjects(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return projectScope{}, false
+	}
+
+	scope := projectScope{all: all, ids: make(map[uuid.UUID]bool, len(ids))}
+	for _, id := range ids {
+		scope.ids[id] = true
+	}
+	return scope, true
+}
+
+// taskOwners loads the project and owning agents of a task for permission checks
+func taskOwners(db *database.DB, taskID uuid.UUID) (projectID uuid.UUID, owners []uuid.UUID, err error) {
+	var createdBy uuid.UUID
+	var assignedTo uuid.NullUUID
+	err = db.QueryRow("SELECT project_id, created_by, assigned_to FROM tasks WHERE id = $1", taskID).Scan(&projectID, &createdBy, &assignedTo)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	owners = []uuid.UUID{createdBy}
+	if assignedTo.Valid {
+		owners = append(owners, assignedTo.UUID)
+	}
+	return projectID, owners, nil
+}
+
+// authorizeTask loads a task's ownership and authorizes action against it,
+// writing 404/403/500 responses as needed
+func authorizeTask(w http.ResponseWriter, r *http.Request, db *database.DB, svc *auth.Service, taskID uuid.UUID, action auth.Action) bool {
+	projectID, owners, err := taskOwners(db, taskID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		http.Error(w, "Failed to retrieve task", http.StatusInternalServerError)
+		return false
+	}
+	return authorize(w, r, svc, projectID, action, owners...)
+}
+
+// authorizeAgent loads an agent's project and authorizes action against the
+// agent itself, writing 404/403/500 responses as needed
+func authorizeAgent(w http.ResponseWriter, r *http.Request, db *database.DB, svc *auth.Service, agentID uuid.UUID, action auth.Action) bool {
+	var projectID uuid.UUID
+	err := db.QueryRow("SELECT project_id FROM agents WHERE id = $1", agentID).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		http.Error(w, "Failed to retrieve agent", http.StatusInternalServerError)
+		return false
+	}
+	return authorize(w, r, svc, projectID, action, agentID)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/lease"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/taskstate"
+)
+
+// ClaimTask atomically assigns a pending task to an agent and starts its lease.
+// Agents authenticated with their own key may omit agent_id.
+func (h *TaskHandler) ClaimTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ClaimTaskRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	principal := auth.PrincipalFromContext(r.Context())
+	if principal != nil && principal.Kind == auth.PrincipalAgent {
+		if req.AgentID != uuid.Nil && req.AgentID != principal.AgentID {
+			http.Error(w, "Agents can only claim tasks for themselves", http.StatusForbidden)
+			return
+		}
+		req.AgentID = principal.AgentID
+	}
+	if req.AgentID == uuid.Nil {
+		http.Error(w, "agent_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTask(w, r, h.db, h.authz, id, auth.ActionClaimTask) {
+		return
+	}
+
+	task, err := h.leases.Claim(r.Context(), id, req.AgentID, history.ActorFromContext(r.Context()))
+	switch {
+	case errors.Is(err, lease.ErrTaskNotFound):
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	case errors.Is(err, lease.ErrAlreadyClaimed), errors.Is(err, lease.ErrNotClaimable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, taskstate.ErrBlocked):
+		http.Error(w, "Task is blocked by unfinished dependencies", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to claim task", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
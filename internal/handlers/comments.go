@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/comments"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+// CommentHandler serves threaded task comments
+type CommentHandler struct {
+	db       *database.DB
+	authz    *auth.Service
+	comments *comments.Service
+}
+
+// NewCommentHandler creates a new comment handler
+func NewCommentHandler(db *database.DB, authz *auth.Service, comments *comments.Service) *CommentHandler {
+	return &CommentHandler{db: db, authz: authz, comments: comments}
+}
+
+// CreateComment adds a comment or reply to a task. Agents authenticated with
+// their own key may omit agent_id.
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	principal := auth.PrincipalFromContext(r.Context())
+	if principal != nil && principal.Kind == auth.PrincipalAgent && req.AgentID == uuid.Nil {
+		req.AgentID = principal.AgentID
+	}
+
+	// Validate request
+	if err := validator.ValidateCreateCommentRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	projectID, _, err := taskOwners(h.db, taskID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to retrieve task", http.StatusInternalServerError)
+		return
+	}
+	if !authorize(w, r, h.authz, projectID, auth.ActionWriteComment, req.AgentID) {
+		return
+	}
+
+	comment, err := h.comments.Create(r.Context(), taskID, req)
+	if err != nil {
+		writeCommentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// ListComments returns a task's comments as threads, oldest first
+func (h *CommentHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTask(w, r, h.db, h.authz, taskID, auth.ActionRead) {
+		return
+	}
+
+	threads, err := h.comments.List(r.Context(), taskID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(threads)
+}
+
+// UpdateComment edits a comment's body
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.authorizeComment(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if err := validator.ValidateUpdateCommentRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.comments.Update(r.Context(), id, req.Body)
+	if err != nil {
+		writeCommentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// DeleteComment removes a comment and its replies
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.authorizeComment(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.comments.Delete(r.Context(), id); err != nil {
+		writeCommentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeComment parses the comment ID and checks that the caller may
+// change it: its author, or anyone allowed to moderate the project
+func (h *CommentHandler) authorizeComment(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	vars := mux.Vars(r)
+	taskID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(vars["commentId"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID format", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	comment, projectID, err := h.comments.Get(r.Context(), id)
+	if err == nil && comment.TaskID != taskID {
+		err = comments.ErrCommentNotFound
+	}
+	if err != nil {
+		writeCommentError(w, err)
+		return uuid.Nil, false
+	}
+	if !authorize(w, r, h.authz, projectID, auth.ActionWriteComment, comment.AgentID) {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeCommentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, comments.ErrTaskNotFound):
+		http.Error(w, "Task not found", http.StatusNotFound)
+	case errors.Is(err, comments.ErrCommentNotFound):
+		http.Error(w, "Comment not found", http.StatusNotFound)
+	case errors.Is(err, comments.ErrAgentNotFound):
+		http.Error(w, "Agent not found", http.StatusNotFound)
+	case errors.Is(err, comments.ErrParentNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to save comment", http.StatusInternalServerError)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
 	"github.com/techbuzzz/agent-shaker/internal/database"
 	"github.com/techbuzzz/agent-shaker/internal/models"
 	"github.com/techbuzzz/agent-shaker/internal/validator"
@@ -17,12 +18,13 @@ import (
 )
 
 type ContextHandler struct {
-	db  *database.DB
-	hub *websocket.Hub
+	db    *database.DB
+	hub   *websocket.Hub
+	authz *auth.Service
 }
 
-func NewContextHandler(db *database.DB, hub *websocket.Hub) *ContextHandler {
-	return &ContextHandler{db: db, hub: hub}
+func NewContextHandler(db *database.DB, hub *websocket.Hub, authz *auth.Service) *ContextHandler {
+	return &ContextHandler{db: db, hub: hub, authz: authz}
 }
 
 func (h *ContextHandler) CreateContext(w http.ResponseWriter, r *http.Request) {
@@ -38,6 +40,10 @@ func (h *ContextHandler) CreateContext(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, req.ProjectID, auth.ActionWriteContext, req.AgentID) {
+		return
+	}
+
 	ctx := models.Context{
 		ID:        uuid.New(),
 		ProjectID: req.ProjectID,
@@ -80,6 +86,10 @@ func (h *ContextHandler) ListContexts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
 	query := `
 		SELECT id, project_id, agent_id, task_id, title, content, tags, created_at, updated_at
 		FROM contexts
@@ -145,6 +155,10 @@ func (h *ContextHandler) GetContext(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, ctx.ProjectID, auth.ActionRead) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(ctx)
 }
@@ -184,6 +198,10 @@ func (h *ContextHandler) UpdateContext(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, currentCtx.ProjectID, auth.ActionWriteContext, currentCtx.AgentID) {
+		return
+	}
+
 	// Update the context
 	_, err = h.db.Exec(`
 		UPDATE contexts
@@ -223,10 +241,10 @@ func (h *ContextHandler) DeleteContext(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if context exists and get project_id for broadcasting
-	var projectID uuid.UUID
+	var projectID, agentID uuid.UUID
 	err = h.db.QueryRow(`
-		SELECT project_id FROM contexts WHERE id = $1
-	`, id).Scan(&projectID)
+		SELECT project_id, agent_id FROM contexts WHERE id = $1
+	`, id).Scan(&projectID, &agentID)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Context not found", http.StatusNotFound)
 		return
@@ -235,6 +253,10 @@ func (h *ContextHandler) DeleteContext(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, projectID, auth.ActionDeleteContext, agentID) {
+		return
+	}
+
 	// Delete the context
 	result, err := h.db.Exec(`DELETE FROM contexts WHERE id = $1`, id)
 	if err != nil {
@@ -5,17 +5,19 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/techbuzzz/agent-shaker/internal/auth"
 	"github.com/techbuzzz/agent-shaker/internal/database"
 )
 
 // DashboardHandler handles dashboard statistics requests
 type DashboardHandler struct {
-	db *database.DB
+	db    *database.DB
+	authz *auth.Service
 }
 
 // NewDashboardHandler creates a new dashboard handler
-func NewDashboardHandler(db *database.DB) *DashboardHandler {
-	return &DashboardHandler{db: db}
+func NewDashboardHandler(db *database.DB, authz *auth.Service) *DashboardHandler {
+	return &DashboardHandler{db: db, authz: authz}
 }
 
 // DashboardStats represents the dashboard statistics
@@ -48,6 +50,10 @@ type TaskStats struct {
 	InProgress int `json:"in_progress"`
 	Done       int `json:"done"`
 	Blocked    int `json:"blocked"`
+	Failed     int `json:"failed"`
+	Cancelled  int `json:"cancelled"`
+	// Overdue counts open tasks that missed their due date or SLA
+	Overdue int `json:"overdue"`
 }
 
 // ContextStats represents context statistics
@@ -55,13 +61,21 @@ type ContextStats struct {
 	Total int `json:"total"`
 }
 
-// GetDashboardStats returns comprehensive dashboard statistics
+// GetDashboardStats returns comprehensive dashboard statistics, counted over
+// the projects the caller may read
 func (h *DashboardHandler) GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 	if h.db == nil {
 		http.Error(w, "Database connection not available", http.StatusServiceUnavailable)
 		return
 	}
 
+	scope, ok := visibleProjects(w, r, h.authz)
+	if !ok {
+		return
+	}
+	projectFilter, args := scope.where("id")
+	rowFilter, _ := scope.where("project_id")
+
 	stats := DashboardStats{}
 
 	// Get project statistics
@@ -71,8 +85,7 @@ func (h *DashboardHandler) GetDashboardStats(w http.ResponseWriter, r *http.Requ
 			COUNT(*) as total,
 			COUNT(*) FILTER (WHERE status = 'active') as active,
 			COUNT(*) FILTER (WHERE status = 'archived') as archived
-		FROM projects
-	`).Scan(&projectStats.Total, &projectStats.Active, &projectStats.Archived)
+		FROM projects`+projectFilter, args...).Scan(&projectStats.Total, &projectStats.Active, &projectStats.Archived)
 
 	if err != nil {
 		log.Printf("Error fetching project stats: %v", err)
@@ -88,8 +101,7 @@ func (h *DashboardHandler) GetDashboardStats(w http.ResponseWriter, r *http.Requ
 			COUNT(*) FILTER (WHERE status = 'active') as active,
 			COUNT(*) FILTER (WHERE status = 'idle') as idle,
 			COUNT(*) FILTER (WHERE status = 'offline') as offline
-		FROM agents
-	`).Scan(&agentStats.Total, &agentStats.Active, &agentStats.Idle, &agentStats.Offline)
+		FROM agents`+rowFilter, args...).Scan(&agentStats.Total, &agentStats.Active, &agentStats.Idle, &agentStats.Offline)
 
 	if err != nil {
 		log.Printf("Error fetching agent stats: %v", err)
@@ -105,13 +117,15 @@ func (h *DashboardHandler) GetDashboardStats(w http.ResponseWriter, r *http.Requ
 			COUNT(*) FILTER (WHERE status = 'pending') as pending,
 			COUNT(*) FILTER (WHERE status = 'in_progress') as in_progress,
 			COUNT(*) FILTER (WHERE status = 'done') as done,
-			COUNT(*) FILTER (WHERE status = 'blocked') as blocked
-		FROM tasks
-	`).Scan(&taskStats.Total, &taskStats.Pending, &taskStats.InProgress, &taskStats.Done, &taskStats.Blocked)
+			COUNT(*) FILTER (WHERE status = 'blocked') as blocked,
+			COUNT(*) FILTER (WHERE status = 'failed') as failed,
+			COUNT(*) FILTER (WHERE status = 'cancelled') as cancelled,
+			COUNT(*) FILTER (WHERE overdue_at IS NOT NULL AND status IN ('pending', 'in_progress', 'blocked')) as overdue
+		FROM tasks`+rowFilter, args...).Scan(&taskStats.Total, &taskStats.Pending, &taskStats.InProgress, &taskStats.Done, &taskStats.Blocked, &taskStats.Failed, &taskStats.Cancelled, &taskStats.Overdue)
 
 	if err != nil {
 		log.Printf("Error fetching task stats: %v", err)
-		taskStats = TaskStats{Total: 0, Pending: 0, InProgress: 0, Done: 0, Blocked: 0}
+		taskStats = TaskStats{}
 	}
 	stats.Tasks = taskStats
 
@@ -119,8 +133,7 @@ func (h *DashboardHandler) GetDashboardStats(w http.ResponseWriter, r *http.Requ
 	var contextStats ContextStats
 	err = h.db.QueryRow(`
 		SELECT COUNT(*) as total
-		FROM contexts
-	`).Scan(&contextStats.Total)
+		FROM contexts`+rowFilter, args...).Scan(&contextStats.Total)
 
 	if err != nil {
 		log.Printf("Error fetching context stats: %v", err)
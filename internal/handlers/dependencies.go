@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/taskgraph"
+)
+
+// AddDependency makes a task wait on another task in the same project
+func (h *TaskHandler) AddDependency(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AddDependencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DependsOnID == uuid.Nil {
+		http.Error(w, "depends_on_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTask(w, r, h.db, h.authz, id, auth.ActionUpdateTask) {
+		return
+	}
+
+	dep, err := h.graph.AddDependency(r.Context(), id, req.DependsOnID)
+	if err != nil {
+		writeDependencyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dep)
+}
+
+// ListDependencies returns the tasks a task waits on and the tasks waiting on it
+func (h *TaskHandler) ListDependencies(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTask(w, r, h.db, h.authz, id, auth.ActionRead) {
+		return
+	}
+
+	dependsOn, dependents, err := h.graph.Neighbours(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve dependencies", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"task_id":    id,
+		"depends_on": dependsOn,
+		"dependents": dependents,
+	})
+}
+
+// RemoveDependency deletes a dependency edge, unblocking the task if possible
+func (h *TaskHandler) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+	dependsOnID, err := uuid.Parse(vars["dependsOnId"])
+	if err != nil {
+		http.Error(w, "Invalid dependency ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTask(w, r, h.db, h.authz, id, auth.ActionUpdateTask) {
+		return
+	}
+
+	if err := h.graph.RemoveDependency(r.Context(), id, dependsOnID); err != nil {
+		writeDependencyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTaskGraph returns a project's tasks and the dependency edges between them
+func (h *TaskHandler) GetTaskGraph(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
+	graph, err := h.graph.ProjectGraph(r.Context(), projectID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve task graph", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(graph)
+}
+
+func writeDependencyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, taskgraph.ErrTaskNotFound), errors.Is(err, taskgraph.ErrDependencyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, taskgraph.ErrSelfDependency), errors.Is(err, taskgraph.ErrCrossProject):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, taskgraph.ErrCycle):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to update dependencies", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+)
+
+// GetTaskHistory returns a task's audit trail, oldest event first. The
+// history stays readable after the task has been deleted.
+func (h *TaskHandler) GetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	projectID, err := history.ProjectOf(r.Context(), h.db, id)
+	if err == sql.ErrNoRows {
+		// Tasks created before history was recorded have no events yet
+		projectID, _, err = taskOwners(h.db, id)
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to retrieve task", http.StatusInternalServerError)
+		return
+	}
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
+	events, err := history.List(r.Context(), h.db, id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve task history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+// ListProjectMembers lists explicit role assignments in a project.
+// Agents without an assignment implicitly hold the agent role in their own project.
+func (h *ProjectHandler) ListProjectMembers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT pr.id, pr.project_id, pr.user_id, pr.agent_id, COALESCE(u.name, a.name, ''), pr.role, pr.created_at
+		FROM project_roles pr
+		LEFT JOIN users u ON u.id = pr.user_id
+		LEFT JOIN agents a ON a.id = pr.agent_id
+		WHERE pr.project_id = $1
+		ORDER BY pr.created_at
+	`, projectID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve project members", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	members := []models.ProjectMember{}
+	for rows.Next() {
+		var m models.ProjectMember
+		if err := rows.Scan(&m.ID, &m.ProjectID, &m.UserID, &m.AgentID, &m.Name, &m.Role, &m.CreatedAt); err != nil {
+			http.Error(w, "Failed to scan project member", http.StatusInternalServerError)
+			return
+		}
+		members = append(members, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// SetProjectMember assigns or changes the role of a user or agent in a project
+func (h *ProjectHandler) SetProjectMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SetProjectMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if err := validator.ValidateSetProjectMemberRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionManageProject) {
+		return
+	}
+
+	conflict := "(project_id, user_id)"
+	if req.AgentID != nil {
+		conflict = "(project_id, agent_id)"
+	}
+
+	var m models.ProjectMember
+	err = h.db.QueryRow(`
+		INSERT INTO project_roles (project_id, user_id, agent_id, role)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT `+conflict+` DO UPDATE SET role = EXCLUDED.role
+		RETURNING id, project_id, user_id, agent_id, role, created_at
+	`, projectID, req.UserID, req.AgentID, req.Role).Scan(&m.ID, &m.ProjectID, &m.UserID, &m.AgentID, &m.Role, &m.CreatedAt)
+	if err != nil {
+		http.Error(w, "Failed to assign project role", http.StatusInternalServerError)
+		return
+	}
+
+	// Broadcast membership change
+	h.hub.BroadcastToProject(projectID, "project_member_update", m)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
+
+// RemoveProjectMember removes a role assignment from a project
+func (h *ProjectHandler) RemoveProjectMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+	memberID, err := uuid.Parse(vars["memberId"])
+	if err != nil {
+		http.Error(w, "Invalid member ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionManageProject) {
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM project_roles WHERE id = $1 AND project_id = $2", memberID, projectID)
+	if err != nil {
+		http.Error(w, "Failed to remove project member", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		http.Error(w, "Project member not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -8,19 +8,22 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
 	"github.com/techbuzzz/agent-shaker/internal/database"
 	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/routing"
 	"github.com/techbuzzz/agent-shaker/internal/validator"
 	"github.com/techbuzzz/agent-shaker/internal/websocket"
 )
 
 type ProjectHandler struct {
-	db  *database.DB
-	hub *websocket.Hub
+	db    *database.DB
+	hub   *websocket.Hub
+	authz *auth.Service
 }
 
-func NewProjectHandler(db *database.DB, hub *websocket.Hub) *ProjectHandler {
-	return &ProjectHandler{db: db, hub: hub}
+func NewProjectHandler(db *database.DB, hub *websocket.Hub, authz *auth.Service) *ProjectHandler {
+	return &ProjectHandler{db: db, hub: hub, authz: authz}
 }
 
 func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
@@ -36,32 +39,72 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Projects are created by admins and users; agents always belong to an existing project
+	principal := auth.PrincipalFromContext(r.Context())
+	if principal != nil && principal.Kind == auth.PrincipalAgent {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+
 	project := models.Project{
-		ID:          uuid.New(),
-		Name:        req.Name,
-		Description: req.Description,
-		Status:      "active",
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:             uuid.New(),
+		Name:           req.Name,
+		Description:    req.Description,
+		Status:         "active",
+		AssignStrategy: req.AssignStrategy,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if project.AssignStrategy == "" {
+		project.AssignStrategy = routing.DefaultStrategy
 	}
 
 	_, err := h.db.Exec(`
-		INSERT INTO projects (id, name, description, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, project.ID, project.Name, project.Description, project.Status, project.CreatedAt, project.UpdatedAt)
+		INSERT INTO projects (id, name, description, status, assign_strategy, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, project.ID, project.Name, project.Description, project.Status, project.AssignStrategy, project.CreatedAt, project.UpdatedAt)
 	if err != nil {
 		http.Error(w, "Failed to create project", http.StatusInternalServerError)
 		return
 	}
 
+	// Start the project with the default agent role vocabulary
+	for _, role := range models.DefaultAgentRoles {
+		_, err = h.db.Exec(`
+			INSERT INTO agent_roles (project_id, name, description)
+			VALUES ($1, $2, $3)
+		`, project.ID, role.Name, role.Description)
+		if err != nil {
+			http.Error(w, "Failed to create project roles", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The creating user maintains the new project
+	if principal != nil && principal.Kind == auth.PrincipalUser {
+		_, err = h.db.Exec(`
+			INSERT INTO project_roles (project_id, user_id, role)
+			VALUES ($1, $2, $3)
+		`, project.ID, principal.UserID, auth.RoleMaintainer)
+		if err != nil {
+			http.Error(w, "Failed to assign project maintainer", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(project)
 }
 
 func (h *ProjectHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	scope, ok := visibleProjects(w, r, h.authz)
+	if !ok {
+		return
+	}
+
 	rows, err := h.db.Query(`
-		SELECT id, name, description, status, created_at, updated_at
+		SELECT id, name, description, status, assign_strategy, created_at, updated_at
 		FROM projects
 		ORDER BY created_at DESC
 	`)
@@ -74,10 +117,13 @@ func (h *ProjectHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 	var projects []models.Project
 	for rows.Next() {
 		var p models.Project
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Status, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Status, &p.AssignStrategy, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			http.Error(w, "Failed to scan project", http.StatusInternalServerError)
 			return
 		}
+		if !scope.includes(p.ID) {
+			continue
+		}
 		projects = append(projects, p)
 	}
 
@@ -100,10 +146,10 @@ func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 
 	var project models.Project
 	err = h.db.QueryRow(`
-		SELECT id, name, description, status, created_at, updated_at
+		SELECT id, name, description, status, assign_strategy, created_at, updated_at
 		FROM projects
 		WHERE id = $1
-	`, id).Scan(&project.ID, &project.Name, &project.Description, &project.Status, &project.CreatedAt, &project.UpdatedAt)
+	`, id).Scan(&project.ID, &project.Name, &project.Description, &project.Status, &project.AssignStrategy, &project.CreatedAt, &project.UpdatedAt)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Project not found", http.StatusNotFound)
 		return
@@ -112,6 +158,10 @@ func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, project.ID, auth.ActionRead) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(project)
 }
@@ -132,6 +182,10 @@ func (h *ProjectHandler) UpdateProjectStatus(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if !authorize(w, r, h.authz, id, auth.ActionManageProject) {
+		return
+	}
+
 	// Validate status
 	validStatuses := map[string]bool{
 		"active":    true,
@@ -163,10 +217,10 @@ func (h *ProjectHandler) UpdateProjectStatus(w http.ResponseWriter, r *http.Requ
 	// Fetch updated project
 	var project models.Project
 	err = h.db.QueryRow(`
-		SELECT id, name, description, status, created_at, updated_at
+		SELECT id, name, description, status, assign_strategy, created_at, updated_at
 		FROM projects
 		WHERE id = $1
-	`, id).Scan(&project.ID, &project.Name, &project.Description, &project.Status, &project.CreatedAt, &project.UpdatedAt)
+	`, id).Scan(&project.ID, &project.Name, &project.Description, &project.Status, &project.AssignStrategy, &project.CreatedAt, &project.UpdatedAt)
 	if err != nil {
 		http.Error(w, "Failed to retrieve updated project", http.StatusInternalServerError)
 		return
@@ -179,6 +233,49 @@ func (h *ProjectHandler) UpdateProjectStatus(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(project)
 }
 
+// UpdateAssignStrategy selects the strategy used to auto-assign the project's tasks
+func (h *ProjectHandler) UpdateAssignStrategy(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateAssignStrategyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.ValidateUpdateAssignStrategyRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, id, auth.ActionManageProject) {
+		return
+	}
+
+	var project models.Project
+	err = h.db.QueryRow(`
+		UPDATE projects
+		SET assign_strategy = $1, updated_at = $2
+		WHERE id = $3
+		RETURNING id, name, description, status, assign_strategy, created_at, updated_at
+	`, req.Strategy, time.Now(), id).Scan(&project.ID, &project.Name, &project.Description, &project.Status, &project.AssignStrategy, &project.CreatedAt, &project.UpdatedAt)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update assign strategy", http.StatusInternalServerError)
+		return
+	}
+
+	h.hub.BroadcastToProject(id, "project_status_update", project)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(project)
+}
+
 func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -189,6 +286,10 @@ func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, id, auth.ActionDeleteProject) {
+		return
+	}
+
 	// Begin transaction to delete project and related data
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+// ListPromptTemplates returns the MCP prompt templates a project defines
+func (h *ProjectHandler) ListPromptTemplates(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, project_id, name, description, arguments, template, created_at, updated_at
+		FROM prompt_templates
+		WHERE project_id = $1
+		ORDER BY name
+	`, projectID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve prompts", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	prompts := []models.PromptTemplate{}
+	for rows.Next() {
+		var p models.PromptTemplate
+		if err := rows.Scan(&p.ID, &p.ProjectID, &p.Name, &p.Description, &p.Arguments, &p.Template, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			http.Error(w, "Failed to scan prompt", http.StatusInternalServerError)
+			return
+		}
+		prompts = append(prompts, p)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to retrieve prompts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prompts)
+}
+
+// CreatePromptTemplate adds a prompt template to a project, or replaces the
+// template with the same name
+func (h *ProjectHandler) CreatePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreatePromptTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.ValidateCreatePromptTemplateRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionManageProject) {
+		return
+	}
+
+	var p models.PromptTemplate
+	err = h.db.QueryRow(`
+		INSERT INTO prompt_templates (id, project_id, name, description, arguments, template)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (project_id, name) DO UPDATE
+		SET description = EXCLUDED.description, arguments = EXCLUDED.arguments,
+		    template = EXCLUDED.template, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, project_id, name, description, arguments, template, created_at, updated_at
+	`, uuid.New(), projectID, req.Name, req.Description, req.Arguments, req.Template).Scan(
+		&p.ID, &p.ProjectID, &p.Name, &p.Description, &p.Arguments, &p.Template, &p.CreatedAt, &p.UpdatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to create prompt", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+// DeletePromptTemplate removes a prompt template from a project
+func (h *ProjectHandler) DeletePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionManageProject) {
+		return
+	}
+
+	var deleted uuid.UUID
+	err = h.db.QueryRow("DELETE FROM prompt_templates WHERE project_id = $1 AND name = $2 RETURNING id", projectID, vars["name"]).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Prompt not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete prompt", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
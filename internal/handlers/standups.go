@@ -4,24 +4,31 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
 	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/lease"
 	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/presence"
 	"github.com/techbuzzz/agent-shaker/internal/websocket"
 )
 
 type StandupHandler struct {
-	db  *database.DB
-	hub *websocket.Hub
+	db       *database.DB
+	hub      *websocket.Hub
+	authz    *auth.Service
+	leases   *lease.Manager
+	presence *presence.Tracker
 }
 
-func NewStandupHandler(db *database.DB, hub *websocket.Hub) *StandupHandler {
-	return &StandupHandler{db: db, hub: hub}
+func NewStandupHandler(db *database.DB, hub *websocket.Hub, authz *auth.Service, leases *lease.Manager, presence *presence.Tracker) *StandupHandler {
+	return &StandupHandler{db: db, hub: hub, authz: authz, leases: leases, presence: presence}
 }
 
 // CreateStandup creates or updates a daily standup entry
@@ -46,6 +53,10 @@ func (h *StandupHandler) CreateStandup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, req.ProjectID, auth.ActionWriteStandup, req.AgentID) {
+		return
+	}
+
 	// Parse standup date
 	var standupDate time.Time
 	var err error
@@ -111,6 +122,11 @@ func (h *StandupHandler) ListStandups(w http.ResponseWriter, r *http.Request) {
 	agentIDStr := r.URL.Query().Get("agent_id")
 	dateStr := r.URL.Query().Get("date")
 
+	scope, ok := visibleProjects(w, r, h.authz)
+	if !ok {
+		return
+	}
+
 	query := `
 		SELECT s.id, s.agent_id, s.project_id, s.standup_date, s.did, s.doing, s.done, 
 		       s.blockers, s.challenges, s.reference_links, s.created_at, s.updated_at,
@@ -172,6 +188,9 @@ func (h *StandupHandler) ListStandups(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Failed to scan standup: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if !scope.includes(s.ProjectID) {
+			continue
+		}
 		standups = append(standups, s)
 	}
 
@@ -216,6 +235,10 @@ func (h *StandupHandler) GetStandup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, s.ProjectID, auth.ActionRead) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(s)
 }
@@ -235,6 +258,10 @@ func (h *StandupHandler) UpdateStandup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeStandup(w, r, id) {
+		return
+	}
+
 	// Use RETURNING to get the updated standup in a single query
 	var standup models.DailyStandup
 	err = h.db.QueryRow(`
@@ -271,6 +298,10 @@ func (h *StandupHandler) DeleteStandup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeStandup(w, r, id) {
+		return
+	}
+
 	res, err := h.db.Exec("DELETE FROM daily_standups WHERE id = $1", id)
 	if err != nil {
 		http.Error(w, "Failed to delete standup", http.StatusInternalServerError)
@@ -309,6 +340,10 @@ func (h *StandupHandler) RecordHeartbeat(w http.ResponseWriter, r *http.Request)
 		req.Status = "active"
 	}
 
+	if !authorizeAgent(w, r, h.db, h.authz, req.AgentID, auth.ActionWriteStandup) {
+		return
+	}
+
 	heartbeat := models.AgentHeartbeat{
 		ID:            uuid.New(),
 		AgentID:       req.AgentID,
@@ -333,8 +368,15 @@ func (h *StandupHandler) RecordHeartbeat(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Update agent's last_seen timestamp
-	_, _ = h.db.Exec("UPDATE agents SET last_seen = $1 WHERE id = $2", heartbeat.HeartbeatTime, heartbeat.AgentID)
+	// Update agent's last_seen timestamp, bringing idle or offline agents back
+	if err := h.presence.Seen(r.Context(), heartbeat.AgentID, heartbeat.Status); err != nil {
+		log.Printf("Failed to update presence of agent %s: %v", heartbeat.AgentID, err)
+	}
+
+	// Heartbeats keep the agent's task claims alive
+	if _, err := h.leases.Renew(r.Context(), heartbeat.AgentID); err != nil {
+		log.Printf("Failed to renew leases for agent %s: %v", heartbeat.AgentID, err)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -350,6 +392,10 @@ func (h *StandupHandler) GetAgentHeartbeats(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if !authorizeAgent(w, r, h.db, h.authz, agentID, auth.ActionRead) {
+		return
+	}
+
 	limitParam := r.URL.Query().Get("limit")
 	if limitParam == "" {
 		limitParam = "50"
@@ -398,3 +444,17 @@ func (h *StandupHandler) GetAgentHeartbeats(w http.ResponseWriter, r *http.Reque
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(heartbeats)
 }
+
+// authorizeStandup checks that the caller may modify the given standup
+func (h *StandupHandler) authorizeStandup(w http.ResponseWriter, r *http.Request, id uuid.UUID) bool {
+	var projectID, agentID uuid.UUID
+	err := h.db.QueryRow("SELECT project_id, agent_id FROM daily_standups WHERE id = $1", id).Scan(&projectID, &agentID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Standup not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		http.Error(w, "Failed to retrieve standup", http.StatusInternalServerError)
+		return false
+	}
+	return authorize(w, r, h.authz, projectID, auth.ActionWriteStandup, agentID)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/subtasks"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+// CreateSubtasks breaks a task down into several subtasks in one request.
+// Agents authenticated with their own key may omit created_by.
+func (h *TaskHandler) CreateSubtasks(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateSubtasksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	principal := auth.PrincipalFromContext(r.Context())
+	if principal != nil && principal.Kind == auth.PrincipalAgent && req.CreatedBy == uuid.Nil {
+		req.CreatedBy = principal.AgentID
+	}
+
+	// Validate request
+	if err := validator.ValidateCreateSubtasksRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	projectID, _, err := taskOwners(h.db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to retrieve task", http.StatusInternalServerError)
+		return
+	}
+	if !authorize(w, r, h.authz, projectID, auth.ActionCreateTask, req.CreatedBy) {
+		return
+	}
+
+	created, err := h.subtasks.Create(r.Context(), id, req, history.ActorFromContext(r.Context()))
+	if err != nil {
+		writeSubtaskError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ListSubtasks returns a task's direct subtasks together with its roll-up progress
+func (h *TaskHandler) ListSubtasks(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTask(w, r, h.db, h.authz, id, auth.ActionRead) {
+		return
+	}
+
+	children, err := h.subtasks.Children(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve subtasks", http.StatusInternalServerError)
+		return
+	}
+	progress, err := h.subtasks.Progress(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to compute task progress", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"task_id":  id,
+		"progress": progress,
+		"subtasks": children,
+	})
+}
+
+func writeSubtaskError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, subtasks.ErrTaskNotFound):
+		http.Error(w, "Task not found", http.StatusNotFound)
+	case errors.Is(err, subtasks.ErrParentNotFound), errors.Is(err, subtasks.ErrAgentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, subtasks.ErrCrossProject):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, auth.ErrForbidden):
+		http.Error(w, "Permission denied", http.StatusForbidden)
+	case errors.Is(err, subtasks.ErrHasSubtasks):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to update task hierarchy", http.StatusInternalServerError)
+	}
+}
@@ -3,24 +3,39 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
 	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/lease"
 	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/routing"
+	"github.com/techbuzzz/agent-shaker/internal/subtasks"
+	"github.com/techbuzzz/agent-shaker/internal/taskgraph"
+	"github.com/techbuzzz/agent-shaker/internal/taskstate"
 	"github.com/techbuzzz/agent-shaker/internal/validator"
 	"github.com/techbuzzz/agent-shaker/internal/websocket"
 )
 
 type TaskHandler struct {
-	db  *database.DB
-	hub *websocket.Hub
+	db       *database.DB
+	hub      *websocket.Hub
+	authz    *auth.Service
+	graph    *taskgraph.Service
+	leases   *lease.Manager
+	subtasks *subtasks.Service
+	router   *routing.Router
 }
 
-func NewTaskHandler(db *database.DB, hub *websocket.Hub) *TaskHandler {
-	return &TaskHandler{db: db, hub: hub}
+func NewTaskHandler(db *database.DB, hub *websocket.Hub, authz *auth.Service, graph *taskgraph.Service, leases *lease.Manager, subtasks *subtasks.Service, router *routing.Router) *TaskHandler {
+	return &TaskHandler{db: db, hub: hub, authz: authz, graph: graph, leases: leases, subtasks: subtasks, router: router}
 }
 
 func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
@@ -36,6 +51,18 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, h.authz, req.ProjectID, auth.ActionCreateTask, req.CreatedBy) {
+		return
+	}
+
+	// A subtask must live in its parent's project
+	if req.ParentID != nil {
+		if err := h.subtasks.CheckParent(r.Context(), *req.ParentID, req.ProjectID); err != nil {
+			writeSubtaskError(w, err)
+			return
+		}
+	}
+
 	// Set default priority if not provided
 	if req.Priority == "" {
 		req.Priority = "medium"
@@ -46,23 +73,70 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		ProjectID:   req.ProjectID,
 		Title:       req.Title,
 		Description: req.Description,
-		Status:      "pending",
+		Status:      models.StatusPending,
 		Priority:    req.Priority,
 		CreatedBy:   req.CreatedBy,
 		AssignedTo:  req.AssignedTo,
+		ParentID:    req.ParentID,
+		DueAt:       req.DueAt,
+		SLA:         req.SLA,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	_, err := h.db.Exec(`
-		INSERT INTO tasks (id, project_id, title, description, status, priority, created_by, assigned_to, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`, task.ID, task.ProjectID, task.Title, task.Description, task.Status, task.Priority, task.CreatedBy, task.AssignedTo, task.CreatedAt, task.UpdatedAt)
+	tx, err := h.db.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if task.AssignedTo != nil {
+		err := taskstate.CheckAssignee(r.Context(), tx, *task.AssignedTo, task.ProjectID)
+		if errors.Is(err, taskstate.ErrAgentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to verify agent", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Let the project's strategy pick an assignee; the task stays unassigned if nobody fits
+	if req.AutoAssign != nil && task.AssignedTo == nil {
+		task.AssignedTo, err = h.router.Assign(r.Context(), tx, task.ProjectID, *req.AutoAssign)
+		if err != nil {
+			http.Error(w, "Failed to auto-assign task", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO tasks (id, project_id, title, description, status, priority, created_by, assigned_to, parent_id, due_at, sla_seconds, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`, task.ID, task.ProjectID, task.Title, task.Description, task.Status, task.Priority, task.CreatedBy, task.AssignedTo, task.ParentID, task.DueAt, task.SLA, task.CreatedAt, task.UpdatedAt)
 	if err != nil {
 		http.Error(w, "Failed to create task", http.StatusInternalServerError)
 		return
 	}
 
+	err = history.Record(r.Context(), tx, history.ActorFromContext(r.Context()), models.TaskEvent{
+		TaskID:        task.ID,
+		ProjectID:     task.ProjectID,
+		Event:         models.EventTaskCreated,
+		NewStatus:     task.Status,
+		NewAssignedTo: task.AssignedTo,
+	})
+	if err != nil {
+		http.Error(w, "Failed to record task history", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// Broadcast task creation
 	h.hub.BroadcastToProject(task.ProjectID, "task_update", task)
 
@@ -73,13 +147,18 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
 	query := `
-		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, created_at, updated_at
+		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, parent_id, due_at, sla_seconds, started_at, overdue_at, created_at, updated_at
 		FROM tasks
 		WHERE 1=1
 	`
 	args := []interface{}{}
 	argCount := 1
 
+	scope, ok := visibleProjects(w, r, h.authz)
+	if !ok {
+		return
+	}
+
 	// Add optional filters
 	projectIDStr := r.URL.Query().Get("project_id")
 	if projectIDStr != "" {
@@ -123,6 +202,22 @@ func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
 		argCount++
 	}
 
+	parentIDStr := r.URL.Query().Get("parent_id")
+	if parentIDStr != "" {
+		parentID, err := uuid.Parse(parentIDStr)
+		if err != nil {
+			http.Error(w, "Invalid parent_id format", http.StatusBadRequest)
+			return
+		}
+		query += fmt.Sprintf(" AND parent_id = $%d", argCount)
+		args = append(args, parentID)
+		argCount++
+	}
+
+	if r.URL.Query().Get("overdue") == "true" {
+		query += " AND overdue_at IS NOT NULL AND status IN ('pending', 'in_progress', 'blocked')"
+	}
+
 	query += " ORDER BY created_at DESC"
 
 	rows, err := h.db.Query(query, args...)
@@ -138,7 +233,7 @@ func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
 		var assignedToStr sql.NullString
 		var outputStr sql.NullString
 
-		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &t.CreatedBy, &assignedToStr, &outputStr, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &t.CreatedBy, &assignedToStr, &outputStr, &t.ParentID, &t.DueAt, &t.SLA, &t.StartedAt, &t.OverdueAt, &t.CreatedAt, &t.UpdatedAt); err != nil {
 			http.Error(w, "Failed to scan task", http.StatusInternalServerError)
 			return
 		}
@@ -159,6 +254,9 @@ func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
 			t.Output = ""
 		}
 
+		if !scope.includes(t.ProjectID) {
+			continue
+		}
 		tasks = append(tasks, t)
 	}
 
@@ -184,10 +282,10 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 	var outputStr sql.NullString
 
 	err = h.db.QueryRow(`
-		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, created_at, updated_at
+		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, parent_id, due_at, sla_seconds, started_at, overdue_at, created_at, updated_at
 		FROM tasks
 		WHERE id = $1
-	`, id).Scan(&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.CreatedBy, &assignedToStr, &outputStr, &task.CreatedAt, &task.UpdatedAt)
+	`, id).Scan(&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.CreatedBy, &assignedToStr, &outputStr, &task.ParentID, &task.DueAt, &task.SLA, &task.StartedAt, &task.OverdueAt, &task.CreatedAt, &task.UpdatedAt)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Task not found", http.StatusNotFound)
 		return
@@ -212,6 +310,17 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 		task.Output = ""
 	}
 
+	if !authorize(w, r, h.authz, task.ProjectID, auth.ActionRead) {
+		return
+	}
+
+	// Roll up subtask progress
+	task.Progress, err = h.subtasks.Progress(r.Context(), task.ID)
+	if err != nil {
+		http.Error(w, "Failed to compute task progress", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(task)
 }
@@ -236,13 +345,11 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.db.Exec(`
-		UPDATE tasks
-		SET status = $1, output = $2, updated_at = $3
-		WHERE id = $4
-	`, req.Status, req.Output, time.Now(), id)
-	if err != nil {
-		http.Error(w, "Failed to update task", http.StatusInternalServerError)
+	if !authorizeTask(w, r, h.db, h.authz, id, auth.ActionUpdateTask) {
+		return
+	}
+
+	if !h.transition(w, r, id, taskstate.Change{Status: req.Status, Output: &req.Output, Event: models.EventTaskUpdated}) {
 		return
 	}
 
@@ -252,10 +359,10 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	var outputStr sql.NullString
 
 	err = h.db.QueryRow(`
-		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, created_at, updated_at
+		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, parent_id, due_at, sla_seconds, started_at, overdue_at, created_at, updated_at
 		FROM tasks
 		WHERE id = $1
-	`, id).Scan(&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.CreatedBy, &assignedToStr, &outputStr, &task.CreatedAt, &task.UpdatedAt)
+	`, id).Scan(&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.CreatedBy, &assignedToStr, &outputStr, &task.ParentID, &task.DueAt, &task.SLA, &task.StartedAt, &task.OverdueAt, &task.CreatedAt, &task.UpdatedAt)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Task not found", http.StatusNotFound)
 		return
@@ -283,6 +390,11 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	// Broadcast task update
 	h.hub.BroadcastToProject(task.ProjectID, "task_update", task)
 
+	// Unblock dependents once this task is finished, or block them again if it reopened
+	if err := h.graph.TaskChanged(r.Context(), task.ID); err != nil {
+		log.Printf("Failed to update dependents of task %s: %v", task.ID, err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(task)
 }
@@ -296,7 +408,7 @@ func (h *TaskHandler) UpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Status string `json:"status"`
+		Status models.TaskStatus `json:"status"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -309,26 +421,16 @@ func (h *TaskHandler) UpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate status
-	validStatuses := []string{"pending", "in_progress", "completed", "failed", "blocked"}
-	valid := false
-	for _, s := range validStatuses {
-		if req.Status == s {
-			valid = true
-			break
-		}
-	}
-	if !valid {
+	if !req.Status.IsValid() {
 		http.Error(w, "invalid status value", http.StatusBadRequest)
 		return
 	}
 
-	_, err = h.db.Exec(`
-		UPDATE tasks
-		SET status = $1, updated_at = $2
-		WHERE id = $3
-	`, req.Status, time.Now(), id)
-	if err != nil {
-		http.Error(w, "Failed to update task status", http.StatusInternalServerError)
+	if !authorizeTask(w, r, h.db, h.authz, id, auth.ActionUpdateTask) {
+		return
+	}
+
+	if !h.transition(w, r, id, taskstate.Change{Status: req.Status}) {
 		return
 	}
 
@@ -338,10 +440,10 @@ func (h *TaskHandler) UpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 	var outputStr sql.NullString
 
 	err = h.db.QueryRow(`
-		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, created_at, updated_at
+		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, parent_id, due_at, sla_seconds, started_at, overdue_at, created_at, updated_at
 		FROM tasks
 		WHERE id = $1
-	`, id).Scan(&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.CreatedBy, &assignedToStr, &outputStr, &task.CreatedAt, &task.UpdatedAt)
+	`, id).Scan(&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.CreatedBy, &assignedToStr, &outputStr, &task.ParentID, &task.DueAt, &task.SLA, &task.StartedAt, &task.OverdueAt, &task.CreatedAt, &task.UpdatedAt)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Task not found", http.StatusNotFound)
 		return
@@ -369,10 +471,18 @@ func (h *TaskHandler) UpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 	// Broadcast task update
 	h.hub.BroadcastToProject(task.ProjectID, "task_update", task)
 
+	// Unblock dependents once this task is finished, or block them again if it reopened
+	if err := h.graph.TaskChanged(r.Context(), task.ID); err != nil {
+		log.Printf("Failed to update dependents of task %s: %v", task.ID, err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(task)
 }
 
+// DeleteTask removes a task. The children query parameter decides what
+// happens to its subtasks: restrict (default) refuses while any exist,
+// cascade deletes the whole subtree and detach moves them up a level.
 func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -383,17 +493,14 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Begin transaction
-	tx, err := h.db.Begin()
+	mode, err := subtasks.ParseDeleteMode(r.URL.Query().Get("children"))
 	if err != nil {
-		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer tx.Rollback()
 
-	// Get task to retrieve project_id for WebSocket broadcast
-	var task models.Task
-	err = tx.QueryRow("SELECT id, project_id FROM tasks WHERE id = $1", id).Scan(&task.ID, &task.ProjectID)
+	// Only the task's creator (or a maintainer) may delete it
+	projectID, owners, err := taskOwners(h.db, id)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Task not found", http.StatusNotFound)
 		return
@@ -401,39 +508,38 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to retrieve task", http.StatusInternalServerError)
 		return
 	}
-
-	// Delete related contexts first
-	_, err = tx.Exec("DELETE FROM contexts WHERE task_id = $1", id)
-	if err != nil {
-		http.Error(w, "Failed to delete related contexts", http.StatusInternalServerError)
+	if !authorize(w, r, h.authz, projectID, auth.ActionDeleteTask, owners[0]) {
 		return
 	}
 
-	// Delete the task
-	result, err := tx.Exec("DELETE FROM tasks WHERE id = $1", id)
+	// A cascade also needs permission for every subtask it removes
+	authorizeChild := func(owner uuid.UUID) error {
+		if h.authz == nil {
+			return nil
+		}
+		return h.authz.Authorize(r.Context(), projectID, auth.ActionDeleteTask, owner)
+	}
+	deletion, err := h.subtasks.Delete(r.Context(), id, mode, history.ActorFromContext(r.Context()), authorizeChild)
 	if err != nil {
-		http.Error(w, "Failed to delete task", http.StatusInternalServerError)
+		writeSubtaskError(w, err)
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Task not found", http.StatusNotFound)
-		return
+	// Broadcast task deletion
+	for _, taskID := range deletion.Deleted {
+		h.hub.BroadcastToProject(deletion.ProjectID, "task_deleted", map[string]interface{}{
+			"task_id":    taskID,
+			"project_id": deletion.ProjectID,
+			"deleted_at": time.Now(),
+		})
 	}
-
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
-		return
+	if len(deletion.Detached) > 0 {
+		h.graph.BroadcastTasks(r.Context(), deletion.Detached)
 	}
 
-	// Broadcast task deletion
-	h.hub.BroadcastToProject(task.ProjectID, "task_deleted", map[string]interface{}{
-		"task_id":    id,
-		"project_id": task.ProjectID,
-		"deleted_at": time.Now(),
-	})
+	if err := h.graph.Unblock(r.Context(), deletion.Dependents); err != nil {
+		log.Printf("Failed to unblock dependents of task %s: %v", id, err)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -452,24 +558,18 @@ func (h *TaskHandler) ReassignTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify the new agent exists
-	var agentExists bool
-	err = h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM agents WHERE id = $1)", req.AssignedTo).Scan(&agentExists)
-	if err != nil {
-		http.Error(w, "Failed to verify agent", http.StatusInternalServerError)
-		return
-	}
-	if !agentExists {
-		http.Error(w, "Agent not found", http.StatusNotFound)
+	if !authorizeTask(w, r, h.db, h.authz, id, auth.ActionReassignTask) {
 		return
 	}
 
-	_, err = h.db.Exec(`
-		UPDATE tasks
-		SET assigned_to = $1, updated_at = $2
-		WHERE id = $3
-	`, req.AssignedTo, time.Now(), id)
-	if err != nil {
+	err = taskstate.Reassign(r.Context(), h.db, id, req.AssignedTo, history.ActorFromContext(r.Context()))
+	if errors.Is(err, taskstate.ErrTaskNotFound) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, taskstate.ErrAgentNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
 		http.Error(w, "Failed to reassign task", http.StatusInternalServerError)
 		return
 	}
@@ -480,10 +580,10 @@ func (h *TaskHandler) ReassignTask(w http.ResponseWriter, r *http.Request) {
 	var outputStr sql.NullString
 
 	err = h.db.QueryRow(`
-		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, created_at, updated_at
+		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, parent_id, due_at, sla_seconds, started_at, overdue_at, created_at, updated_at
 		FROM tasks
 		WHERE id = $1
-	`, id).Scan(&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.CreatedBy, &assignedToStr, &outputStr, &task.CreatedAt, &task.UpdatedAt)
+	`, id).Scan(&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.CreatedBy, &assignedToStr, &outputStr, &task.ParentID, &task.DueAt, &task.SLA, &task.StartedAt, &task.OverdueAt, &task.CreatedAt, &task.UpdatedAt)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Task not found", http.StatusNotFound)
 		return
@@ -514,3 +614,75 @@ func (h *TaskHandler) ReassignTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(task)
 }
+
+// ScheduleTask sets or clears a task's due date and SLA
+func (h *TaskHandler) ScheduleTask(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ScheduleTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.ValidateScheduleTaskRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTask(w, r, h.db, h.authz, id, auth.ActionUpdateTask) {
+		return
+	}
+
+	err = taskstate.Schedule(r.Context(), h.db, id, req.DueAt, req.SLA, history.ActorFromContext(r.Context()))
+	if errors.Is(err, taskstate.ErrTaskNotFound) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to schedule task", http.StatusInternalServerError)
+		return
+	}
+
+	var task models.Task
+	var output sql.NullString
+	err = h.db.QueryRow(`
+		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, parent_id, due_at, sla_seconds, started_at, overdue_at, created_at, updated_at
+		FROM tasks
+		WHERE id = $1
+	`, id).Scan(&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.CreatedBy, &task.AssignedTo, &output, &task.ParentID, &task.DueAt, &task.SLA, &task.StartedAt, &task.OverdueAt, &task.CreatedAt, &task.UpdatedAt)
+	if err != nil {
+		http.Error(w, "Failed to retrieve task", http.StatusInternalServerError)
+		return
+	}
+	task.Output = output.String
+
+	h.hub.BroadcastToProject(task.ProjectID, "task_update", task)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// transition applies a status change through the task state machine on
+// behalf of the caller, writing 404 for a missing task and 409 for an
+// illegal transition
+func (h *TaskHandler) transition(w http.ResponseWriter, r *http.Request, id uuid.UUID, change taskstate.Change) bool {
+	var transitionErr *models.TransitionError
+	change.Actor = history.ActorFromContext(r.Context())
+	err := taskstate.Update(r.Context(), h.db, id, change)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, taskstate.ErrTaskNotFound):
+		http.Error(w, "Task not found", http.StatusNotFound)
+	case errors.As(err, &transitionErr):
+		http.Error(w, transitionErr.Error(), http.StatusConflict)
+	case errors.Is(err, taskstate.ErrBlocked):
+		http.Error(w, "Task is blocked by unfinished dependencies", http.StatusConflict)
+	default:
+		http.Error(w, "Failed to update task status", http.StatusInternalServerError)
+	}
+	return false
+}
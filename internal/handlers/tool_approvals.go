@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+const toolApprovalColumns = `id, project_id, agent_id, tool_name, arguments, summary, status, reason, decided_by, requested_at, decided_at`
+
+// ListToolApprovals returns the MCP tool calls of a project that needed a
+// human decision, newest first. The status query parameter filters them,
+// e.g. status=pending for the ones still waiting.
+func (h *ProjectHandler) ListToolApprovals(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
+	query := `SELECT ` + toolApprovalColumns + ` FROM mcp_tool_approvals WHERE project_id = $1`
+	args := []interface{}{projectID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += ` AND status = $2`
+		args = append(args, status)
+	}
+	query += ` ORDER BY requested_at DESC LIMIT 100`
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to retrieve tool approvals", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	approvals := []models.ToolApproval{}
+	for rows.Next() {
+		a, err := scanToolApproval(rows)
+		if err != nil {
+			http.Error(w, "Failed to scan tool approval", http.StatusInternalServerError)
+			return
+		}
+		approvals = append(approvals, a)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to retrieve tool approvals", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(approvals)
+}
+
+// DecideToolApproval approves or rejects a pending MCP tool call. The MCP
+// session waiting on it is released through the hub broadcast.
+func (h *ProjectHandler) DecideToolApproval(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+	approvalID, err := uuid.Parse(vars["approvalId"])
+	if err != nil {
+		http.Error(w, "Invalid approval ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.DecideToolApprovalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.ValidateDecideToolApprovalRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionManageProject) {
+		return
+	}
+
+	status := models.ApprovalRejected
+	if *req.Approved {
+		status = models.ApprovalApproved
+	}
+	var decidedBy *uuid.UUID
+	if p := auth.PrincipalFromContext(r.Context()); p != nil && p.UserID != uuid.Nil {
+		decidedBy = &p.UserID
+	}
+
+	a, err := scanToolApproval(h.db.QueryRow(`
+		UPDATE mcp_tool_approvals
+		SET status = $3, reason = NULLIF($4, ''), decided_by = $5, decided_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND project_id = $2 AND status = 'pending'
+		RETURNING `+toolApprovalColumns,
+		approvalID, projectID, status, req.Reason, decidedBy))
+	if err == sql.ErrNoRows {
+		// Tell a missing approval apart from one that was already decided
+		var current models.ToolApprovalStatus
+		err = h.db.QueryRow("SELECT status FROM mcp_tool_approvals WHERE id = $1 AND project_id = $2", approvalID, projectID).Scan(&current)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Tool approval not found", http.StatusNotFound)
+		} else if err != nil {
+			http.Error(w, "Failed to retrieve tool approval", http.StatusInternalServerError)
+		} else {
+			http.Error(w, "Tool approval is already "+string(current), http.StatusConflict)
+		}
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to decide tool approval", http.StatusInternalServerError)
+		return
+	}
+
+	h.hub.BroadcastToProject(projectID, "mcp_approval_decided", a)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanToolApproval(row rowScanner) (models.ToolApproval, error) {
+	var a models.ToolApproval
+	var arguments []byte
+	var reason sql.NullString
+	err := row.Scan(&a.ID, &a.ProjectID, &a.AgentID, &a.ToolName, &arguments, &a.Summary, &a.Status, &reason, &a.DecidedBy, &a.RequestedAt, &a.DecidedAt)
+	a.Arguments = json.RawMessage(arguments)
+	a.Reason = reason.String
+	return a, err
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+// ListToolSettings returns the MCP tools a project has switched on or off,
+// for the whole project and for its agents
+func (h *ProjectHandler) ListToolSettings(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT project_id, agent_id, tool_name, enabled, updated_at
+		FROM mcp_tool_settings
+		WHERE project_id = $1
+		ORDER BY tool_name, agent_id NULLS FIRST
+	`, projectID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve tool settings", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	settings := []models.ToolSetting{}
+	for rows.Next() {
+		var s models.ToolSetting
+		if err := rows.Scan(&s.ProjectID, &s.AgentID, &s.ToolName, &s.Enabled, &s.UpdatedAt); err != nil {
+			http.Error(w, "Failed to scan tool setting", http.StatusInternalServerError)
+			return
+		}
+		settings = append(settings, s)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to retrieve tool settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateToolSetting switches an MCP tool on or off for a project, or for one
+// of its agents when agent_id is given
+func (h *ProjectHandler) UpdateToolSetting(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateToolSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.ToolName = vars["name"]
+	if err := validator.ValidateUpdateToolSettingRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionManageProject) {
+		return
+	}
+
+	if req.AgentID != nil && !h.agentInProject(w, *req.AgentID, projectID) {
+		return
+	}
+
+	s := models.ToolSetting{ProjectID: projectID, AgentID: req.AgentID, ToolName: req.ToolName, Enabled: *req.Enabled}
+	err = h.db.QueryRow(`
+		INSERT INTO mcp_tool_settings (id, project_id, agent_id, tool_name, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (project_id, tool_name, COALESCE(agent_id, '00000000-0000-0000-0000-000000000000'::uuid)) DO UPDATE
+		SET enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at
+	`, uuid.New(), projectID, req.AgentID, req.ToolName, s.Enabled).Scan(&s.UpdatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update tool setting", http.StatusInternalServerError)
+		return
+	}
+
+	// Open MCP sessions of the project are told to refetch their tool list
+	h.hub.BroadcastToProject(projectID, "mcp_tools_update", s)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// DeleteToolSetting removes a tool switch so the tool falls back to the
+// project setting (for an agent) or to enabled. The agent is chosen with the
+// agent_id query parameter.
+func (h *ProjectHandler) DeleteToolSetting(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	var agentID *uuid.UUID
+	if raw := r.URL.Query().Get("agent_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid agent ID format", http.StatusBadRequest)
+			return
+		}
+		agentID = &id
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionManageProject) {
+		return
+	}
+
+	var deleted uuid.UUID
+	err = h.db.QueryRow(`
+		DELETE FROM mcp_tool_settings
+		WHERE project_id = $1 AND tool_name = $2 AND agent_id IS NOT DISTINCT FROM $3
+		RETURNING id
+	`, projectID, vars["name"], agentID).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Tool setting not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete tool setting", http.StatusInternalServerError)
+		return
+	}
+
+	h.hub.BroadcastToProject(projectID, "mcp_tools_update", map[string]interface{}{
+		"project_id": projectID,
+		"agent_id":   agentID,
+		"tool_name":  vars["name"],
+		"deleted":    true,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// agentInProject reports whether the agent belongs to the project, writing
+// an error response when it does not
+func (h *ProjectHandler) agentInProject(w http.ResponseWriter, agentID, projectID uuid.UUID) bool {
+	var exists bool
+	err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM agents WHERE id = $1 AND project_id = $2)", agentID, projectID).Scan(&exists)
+	if err != nil {
+		http.Error(w, "Failed to look up agent", http.StatusInternalServerError)
+		return false
+	}
+	if !exists {
+		http.Error(w, "Agent not found in project", http.StatusNotFound)
+		return false
+	}
+	return true
+}
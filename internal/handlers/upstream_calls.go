@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// ListUpstreamCalls returns the audit log of tool calls a project's agents
+// made to downstream MCP servers, newest first. The server query parameter
+// narrows it to one server.
+func (h *ProjectHandler) ListUpstreamCalls(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
+	query := `
+		SELECT id, project_id, agent_id, server, tool_name, arguments, is_error, error, duration_ms, called_at
+		FROM mcp_upstream_calls WHERE project_id = $1`
+	args := []interface{}{projectID}
+	if server := r.URL.Query().Get("server"); server != "" {
+		query += ` AND server = $2`
+		args = append(args, server)
+	}
+	query += ` ORDER BY called_at DESC LIMIT 100`
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to retrieve upstream calls", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	calls := []models.UpstreamCall{}
+	for rows.Next() {
+		var c models.UpstreamCall
+		var arguments []byte
+		var callErr sql.NullString
+		if err := rows.Scan(&c.ID, &c.ProjectID, &c.AgentID, &c.Server, &c.ToolName, &arguments, &c.IsError, &callErr, &c.DurationMS, &c.CalledAt); err != nil {
+			http.Error(w, "Failed to scan upstream call", http.StatusInternalServerError)
+			return
+		}
+		c.Arguments = json.RawMessage(arguments)
+		c.Error = callErr.String
+		calls = append(calls, c)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to retrieve upstream calls", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calls)
+}
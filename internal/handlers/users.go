@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+// UserHandler manages human operator accounts
+type UserHandler struct {
+	db *database.DB
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(db *database.DB) *UserHandler {
+	return &UserHandler{db: db}
+}
+
+// requireAdmin allows admins, and anonymous callers when authentication is not required
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	principal := auth.PrincipalFromContext(r.Context())
+	if principal != nil && !principal.IsAdmin() {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// CreateUser creates a user account. Only admins may create users.
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	var req models.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if err := validator.ValidateCreateUserRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := models.User{
+		ID:        uuid.New(),
+		Name:      strings.TrimSpace(req.Name),
+		Email:     strings.TrimSpace(req.Email),
+		IsAdmin:   req.IsAdmin,
+		CreatedAt: time.Now(),
+	}
+
+	var email interface{}
+	if user.Email != "" {
+		email = user.Email
+	}
+
+	_, err := h.db.Exec(`
+		INSERT INTO users (id, name, email, is_admin, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, user.ID, user.Name, email, user.IsAdmin, user.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "A user with this email already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// ListUsers lists all user accounts. Only admins may list users.
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, name, COALESCE(email, ''), is_admin, created_at
+		FROM users
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.IsAdmin, &u.CreatedAt); err != nil {
+			http.Error(w, "Failed to scan user", http.StatusInternalServerError)
+			return
+		}
+		users = append(users, u)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
 	ws "github.com/techbuzzz/agent-shaker/internal/websocket"
 )
 
@@ -16,11 +17,12 @@ var upgrader = websocket.Upgrader{
 }
 
 type WebSocketHandler struct {
-	hub *ws.Hub
+	hub   *ws.Hub
+	authz *auth.Service
 }
 
-func NewWebSocketHandler(hub *ws.Hub) *WebSocketHandler {
-	return &WebSocketHandler{hub: hub}
+func NewWebSocketHandler(hub *ws.Hub, authz *auth.Service) *WebSocketHandler {
+	return &WebSocketHandler{hub: hub, authz: authz}
 }
 
 func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -39,6 +41,11 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Project events are only streamed to callers who may read the project
+	if !authorize(w, r, h.authz, projectID, auth.ActionRead) {
+		return
+	}
+
 	log.Printf("WebSocket upgrading connection for project %s", projectID)
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -0,0 +1,125 @@
+// Package history records and reads the append-only audit trail of task changes.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// Actor identifies who made a change. The zero value is the system.
+type Actor struct {
+	AgentID *uuid.UUID
+	UserID  *uuid.UUID
+}
+
+// AgentActor returns an actor for changes made by an agent
+func AgentActor(agentID uuid.UUID) Actor {
+	return Actor{AgentID: &agentID}
+}
+
+// ActorFromPrincipal returns the actor for an authenticated caller, or the
+// system actor for anonymous calls
+func ActorFromPrincipal(p *auth.Principal) Actor {
+	var a Actor
+	if p == nil {
+		return a
+	}
+	if p.AgentID != uuid.Nil {
+		id := p.AgentID
+		a.AgentID = &id
+	}
+	if p.UserID != uuid.Nil {
+		id := p.UserID
+		a.UserID = &id
+	}
+	return a
+}
+
+// ActorFromContext returns the actor for the principal carried by ctx
+func ActorFromContext(ctx context.Context) Actor {
+	return ActorFromPrincipal(auth.PrincipalFromContext(ctx))
+}
+
+// Execer is satisfied by *sql.DB, *sql.Tx and *database.DB so events can be
+// written in the same transaction as the change they describe
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Querier reads events
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Record appends an event attributed to actor
+func Record(ctx context.Context, db Execer, actor Actor, e models.TaskEvent) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO task_events (id, task_id, project_id, event, actor_agent_id, actor_user_id,
+		                         old_status, new_status, old_assigned_to, new_assigned_to, output, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, NOW())
+	`, uuid.New(), e.TaskID, e.ProjectID, string(e.Event), actor.AgentID, actor.UserID,
+		string(e.OldStatus), string(e.NewStatus), e.OldAssignedTo, e.NewAssignedTo, e.Output)
+	if err != nil {
+		return fmt.Errorf("failed to record task event: %w", err)
+	}
+	return nil
+}
+
+// List returns a task's events, oldest first
+func List(ctx context.Context, db Querier, taskID uuid.UUID) ([]models.TaskEvent, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, task_id, project_id, event, actor_agent_id, actor_user_id,
+		       old_status, new_status, old_assigned_to, new_assigned_to, output, created_at
+		FROM task_events
+		WHERE task_id = $1
+		ORDER BY created_at, id
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task history: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.TaskEvent{}
+	for rows.Next() {
+		var e models.TaskEvent
+		var actorAgent, actorUser, oldAssignee, newAssignee uuid.NullUUID
+		var oldStatus, newStatus, output sql.NullString
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.ProjectID, &e.Event, &actorAgent, &actorUser,
+			&oldStatus, &newStatus, &oldAssignee, &newAssignee, &output, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.ActorAgentID = uuidPtr(actorAgent)
+		e.ActorUserID = uuidPtr(actorUser)
+		e.OldAssignedTo = uuidPtr(oldAssignee)
+		e.NewAssignedTo = uuidPtr(newAssignee)
+		e.OldStatus = models.TaskStatus(oldStatus.String)
+		e.NewStatus = models.TaskStatus(newStatus.String)
+		if output.Valid {
+			e.Output = &output.String
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// ProjectOf returns the project a task belonged to according to its history,
+// which still answers after the task itself was deleted. It returns
+// sql.ErrNoRows when the task has no recorded events.
+func ProjectOf(ctx context.Context, db *database.DB, taskID uuid.UUID) (uuid.UUID, error) {
+	var projectID uuid.UUID
+	err := db.QueryRowContext(ctx, "SELECT project_id FROM task_events WHERE task_id = $1 ORDER BY created_at DESC LIMIT 1", taskID).Scan(&projectID)
+	return projectID, err
+}
+
+func uuidPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
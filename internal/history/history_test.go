@@ -0,0 +1,44 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+func TestActorFromPrincipal(t *testing.T) {
+	agentID := uuid.New()
+	userID := uuid.New()
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		wantAgent *uuid.UUID
+		wantUser  *uuid.UUID
+	}{
+		{name: "anonymous", principal: nil},
+		{name: "agent", principal: &auth.Principal{Kind: auth.PrincipalAgent, AgentID: agentID}, wantAgent: &agentID},
+		{name: "user", principal: &auth.Principal{Kind: auth.PrincipalUser, UserID: userID}, wantUser: &userID},
+		{name: "admin", principal: &auth.Principal{Kind: auth.PrincipalAdmin, UserID: userID}, wantUser: &userID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ActorFromPrincipal(tt.principal)
+			if !sameID(got.AgentID, tt.wantAgent) {
+				t.Errorf("AgentID = %v, want %v", got.AgentID, tt.wantAgent)
+			}
+			if !sameID(got.UserID, tt.wantUser) {
+				t.Errorf("UserID = %v, want %v", got.UserID, tt.wantUser)
+			}
+		})
+	}
+}
+
+func sameID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,255 @@
+// Package lease implements atomic task claiming. A claim holds a lease that
+// the agent renews through heartbeats; a reaper returns tasks whose lease
+// expired to pending so other agents can pick them up.
+package lease
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/taskstate"
+	"github.com/techbuzzz/agent-shaker/internal/websocket"
+)
+
+const (
+	DefaultDuration     = 10 * time.Minute
+	DefaultReapInterval = 30 * time.Second
+)
+
+var (
+	ErrTaskNotFound   = errors.New("task not found")
+	ErrAlreadyClaimed = errors.New("task is already claimed by another agent")
+	ErrNotClaimable   = errors.New("task is not pending")
+)
+
+// Config controls lease length and how often expired leases are reaped
+type Config struct {
+	Duration     time.Duration
+	ReapInterval time.Duration
+}
+
+// Manager claims tasks, renews leases and reaps expired ones
+type Manager struct {
+	db     *database.DB
+	hub    *websocket.Hub
+	config Config
+}
+
+// NewManager creates a lease manager, filling in defaults for zero config values
+func NewManager(db *database.DB, hub *websocket.Hub, config Config) *Manager {
+	if config.Duration <= 0 {
+		config.Duration = DefaultDuration
+	}
+	if config.ReapInterval <= 0 {
+		config.ReapInterval = DefaultReapInterval
+	}
+	return &Manager{db: db, hub: hub, config: config}
+}
+
+// Duration returns the configured lease length
+func (m *Manager) Duration() time.Duration {
+	return m.config.Duration
+}
+
+// Claim atomically assigns a pending task to agentID and starts its lease.
+// It only succeeds when the task is pending and either unassigned or already
+// assigned to the same agent, so concurrent claims cannot both win. Tasks with
+// open prerequisites cannot be claimed (taskstate.ErrBlocked). The claim is
+// recorded in the task's history on behalf of actor.
+func (m *Manager) Claim(ctx context.Context, taskID, agentID uuid.UUID, actor history.Actor) (*models.Task, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var t models.Task
+	var assignedTo, previous uuid.NullUUID
+	var output sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		UPDATE tasks t
+		SET assigned_to = $1, status = 'in_progress', lease_expires_at = NOW() + $3 * INTERVAL '1 second', updated_at = NOW(),
+		    started_at = NOW(), overdue_at = CASE WHEN t.due_at < NOW() THEN t.overdue_at ELSE NULL END
+		FROM (SELECT id, assigned_to FROM tasks WHERE id = $2 FOR UPDATE) old
+		WHERE t.id = old.id AND t.status = 'pending' AND (t.assigned_to IS NULL OR t.assigned_to = $1)
+		RETURNING t.id, t.project_id, t.title, t.description, t.status, t.priority, t.created_by, t.assigned_to, t.output, t.lease_expires_at, t.due_at, t.sla_seconds, t.started_at, t.overdue_at, t.created_at, t.updated_at, old.assigned_to
+	`, agentID, taskID, m.config.Duration.Seconds()).Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &t.CreatedBy, &assignedTo, &output, &t.LeaseExpiresAt, &t.DueAt, &t.SLA, &t.StartedAt, &t.OverdueAt, &t.CreatedAt, &t.UpdatedAt, &previous)
+	if err == sql.ErrNoRows {
+		return nil, m.claimFailure(ctx, taskID, agentID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to claim task: %w", err)
+	}
+	if err := taskstate.CheckPrerequisites(ctx, tx, taskID); err != nil {
+		return nil, err
+	}
+
+	if assignedTo.Valid {
+		t.AssignedTo = &assignedTo.UUID
+	}
+	t.Output = output.String
+
+	claimed := models.TaskEvent{
+		TaskID:        t.ID,
+		ProjectID:     t.ProjectID,
+		Event:         models.EventTaskClaimed,
+		OldStatus:     models.StatusPending,
+		NewStatus:     t.Status,
+		NewAssignedTo: t.AssignedTo,
+	}
+	if previous.Valid {
+		claimed.OldAssignedTo = &previous.UUID
+	}
+	if err := history.Record(ctx, tx, actor, claimed); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit claim: %w", err)
+	}
+
+	m.broadcast(t.ProjectID, "task_update", t)
+	return &t, nil
+}
+
+// claimFailure explains why a compare-and-set claim matched no rows
+func (m *Manager) claimFailure(ctx context.Context, taskID, agentID uuid.UUID) error {
+	var status string
+	var assignedTo uuid.NullUUID
+	err := m.db.QueryRowContext(ctx, "SELECT status, assigned_to FROM tasks WHERE id = $1", taskID).Scan(&status, &assignedTo)
+	if err == sql.ErrNoRows {
+		return ErrTaskNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to load task: %w", err)
+	}
+
+	if assignedTo.Valid && assignedTo.UUID != agentID {
+		return ErrAlreadyClaimed
+	}
+	return fmt.Errorf("%w (status is %s)", ErrNotClaimable, status)
+}
+
+// Renew extends the leases of every in-progress task held by agentID and
+// returns how many were renewed
+func (m *Manager) Renew(ctx context.Context, agentID uuid.UUID) (int64, error) {
+	result, err := m.db.ExecContext(ctx, `
+		UPDATE tasks
+		SET lease_expires_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE assigned_to = $1 AND status = 'in_progress' AND lease_expires_at IS NOT NULL
+	`, agentID, m.config.Duration.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to renew leases: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Run reaps expired leases every ReapInterval until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := m.Reap(ctx); err != nil {
+				log.Printf("Lease reaper: %v", err)
+			} else if n > 0 {
+				log.Printf("Lease reaper: returned %d expired task(s) to pending", n)
+			}
+		}
+	}
+}
+
+// Reap returns in-progress tasks whose lease has expired to pending,
+// recording the release and notifying each project through the hub
+func (m *Manager) Reap(ctx context.Context) (int, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH expired AS (
+			SELECT id, assigned_to
+			FROM tasks
+			WHERE status = 'in_progress' AND lease_expires_at < NOW()
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE tasks t
+		SET status = 'pending', assigned_to = NULL, lease_expires_at = NULL, updated_at = NOW()
+		FROM expired e
+		WHERE t.id = e.id
+		RETURNING t.id, t.project_id, t.title, t.description, t.status, t.priority, t.created_by, t.output, t.created_at, t.updated_at, e.assigned_to
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reap expired leases: %w", err)
+	}
+
+	type expiredTask struct {
+		task     models.Task
+		previous uuid.NullUUID
+	}
+	var expired []expiredTask
+	for rows.Next() {
+		var e expiredTask
+		var output sql.NullString
+		if err := rows.Scan(&e.task.ID, &e.task.ProjectID, &e.task.Title, &e.task.Description, &e.task.Status, &e.task.Priority, &e.task.CreatedBy, &output, &e.task.CreatedAt, &e.task.UpdatedAt, &e.previous); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		e.task.Output = output.String
+		expired = append(expired, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, e := range expired {
+		released := models.TaskEvent{
+			TaskID:    e.task.ID,
+			ProjectID: e.task.ProjectID,
+			Event:     models.EventTaskLeaseExpired,
+			OldStatus: models.StatusInProgress,
+			NewStatus: e.task.Status,
+		}
+		if e.previous.Valid {
+			released.OldAssignedTo = &e.previous.UUID
+		}
+		if err := history.Record(ctx, tx, history.Actor{}, released); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit reaped leases: %w", err)
+	}
+
+	for _, e := range expired {
+		m.broadcast(e.task.ProjectID, "task_update", e.task)
+		payload := map[string]interface{}{
+			"task_id":    e.task.ID,
+			"project_id": e.task.ProjectID,
+			"title":      e.task.Title,
+		}
+		if e.previous.Valid {
+			payload["previous_assignee"] = e.previous.UUID
+		}
+		m.broadcast(e.task.ProjectID, "task_lease_expired", payload)
+	}
+	return len(expired), nil
+}
+
+func (m *Manager) broadcast(projectID uuid.UUID, messageType string, payload interface{}) {
+	if m.hub != nil {
+		m.hub.BroadcastToProject(projectID, messageType, payload)
+	}
+}
@@ -0,0 +1,21 @@
+package lease
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewManagerDefaults(t *testing.T) {
+	m := NewManager(nil, nil, Config{})
+	if m.config.Duration != DefaultDuration {
+		t.Errorf("Expected default duration %s, got %s", DefaultDuration, m.config.Duration)
+	}
+	if m.config.ReapInterval != DefaultReapInterval {
+		t.Errorf("Expected default reap interval %s, got %s", DefaultReapInterval, m.config.ReapInterval)
+	}
+
+	m = NewManager(nil, nil, Config{Duration: time.Minute, ReapInterval: time.Second})
+	if m.Duration() != time.Minute || m.config.ReapInterval != time.Second {
+		t.Errorf("Expected configured values to be kept, got %+v", m.config)
+	}
+}
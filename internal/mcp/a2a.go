@@ -0,0 +1,328 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	a2aClient "github.com/techbuzzz/agent-shaker/internal/a2a/client"
+	a2aModels "github.com/techbuzzz/agent-shaker/internal/a2a/models"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/task"
+)
+
+// a2aTools delegate work to external A2A agents
+var a2aTools = []ToolDefinition{
+	{
+		Name:        "discover_a2a_agent",
+		Description: "Discover an external A2A agent by fetching its agent card. Returns the agent's capabilities, endpoints, and metadata.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"agent_url": map[string]interface{}{
+					"type":        "string",
+					"description": "The base URL of the A2A agent to discover (e.g., https://agent.example.com)",
+				},
+			},
+			Required: []string{"agent_url"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"success":      successField,
+			"agent_url":    outputField("string", "Base URL of the agent"),
+			"name":         outputField("string", "Agent name"),
+			"description":  outputField("string", "Agent description"),
+			"version":      outputField("string", "Agent version"),
+			"capabilities": outputField("object", "Protocol capabilities from the agent card"),
+			"endpoints":    outputNullable("array", "Endpoints from the agent card"),
+			"metadata":     outputNullable("object", "Agent card metadata"),
+		}, "success", "agent_url", "name"),
+		Access:  ToolAccess{Global: true},
+		Handler: (*MCPHandler).executeDiscoverA2AAgent,
+	},
+	{
+		Name:        "delegate_to_a2a_agent",
+		Description: "Delegate a task to an external A2A agent. The agent will process the message and return a task ID for tracking.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"agent_url": map[string]interface{}{
+					"type":        "string",
+					"description": "The base URL of the A2A agent",
+				},
+				"message": map[string]interface{}{
+					"type":        "string",
+					"description": "The message/task content to send to the agent",
+				},
+				"wait_for_completion": map[string]interface{}{
+					"type":        "boolean",
+					"description": "If true, wait for the task to complete before returning (default: false). Send a progressToken to get notifications/progress as the task changes state.",
+				},
+				"timeout_seconds": map[string]interface{}{
+					"type":        "integer",
+					"description": "Timeout in seconds when waiting for completion (default: 60)",
+				},
+			},
+			Required: []string{"agent_url", "message"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"success":      successField,
+			"agent_url":    outputField("string", "Base URL of the agent"),
+			"task_id":      outputField("string", "Task ID on the remote agent"),
+			"status":       outputField("string", "Task status when sent"),
+			"created_at":   outputField("string", "Creation time"),
+			"final_status": outputField("string", "Task status after waiting, with wait_for_completion"),
+			"task":         outputField("object", "The finished task, with wait_for_completion"),
+			"wait_error":   outputField("string", "Why waiting stopped early"),
+		}, "success", "agent_url", "task_id", "status"),
+		Access:   ToolAccess{Action: auth.ActionCreateTask, Project: connectionProject},
+		Handler:  (*MCPHandler).executeDelegateToA2AAgent,
+		Approval: approveDelegation,
+	},
+	{
+		Name:        "get_a2a_task_status",
+		Description: "Get the status of a task from an external A2A agent",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"agent_url": map[string]interface{}{
+					"type":        "string",
+					"description": "The base URL of the A2A agent",
+				},
+				"task_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The task ID to check",
+				},
+			},
+			Required: []string{"agent_url", "task_id"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"success":   successField,
+			"agent_url": outputField("string", "Base URL of the agent"),
+			"task":      outputField("object", "The remote task"),
+		}, "success", "agent_url", "task"),
+		Access:  ToolAccess{Global: true},
+		Handler: (*MCPHandler).executeGetA2ATaskStatus,
+	},
+}
+
+func (h *MCPHandler) executeDiscoverA2AAgent(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	agentURL, ok := args["agent_url"].(string)
+	if !ok || agentURL == "" {
+		return nil, invalidArgument("agent_url is required")
+	}
+
+	// Create A2A client and discover agent
+	client := createA2AClient()
+	card, err := client.Discover(ctx.Context(), agentURL)
+	if err != nil {
+		return nil, unavailable("Failed to discover agent: %s", err)
+	}
+
+	return map[string]interface{}{
+		"success":      true,
+		"agent_url":    agentURL,
+		"name":         card.Name,
+		"description":  card.Description,
+		"version":      card.Version,
+		"capabilities": card.Capabilities,
+		"endpoints":    card.Endpoints,
+		"metadata":     card.Metadata,
+	}, nil
+}
+
+func (h *MCPHandler) executeDelegateToA2AAgent(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	agentURL, ok := args["agent_url"].(string)
+	if !ok || agentURL == "" {
+		return nil, invalidArgument("agent_url is required")
+	}
+
+	message, ok := args["message"].(string)
+	if !ok || message == "" {
+		return nil, invalidArgument("message is required")
+	}
+
+	waitForCompletion := false
+	if wait, ok := args["wait_for_completion"].(bool); ok {
+		waitForCompletion = wait
+	}
+
+	timeoutSeconds := 60
+	if timeout, ok := args["timeout_seconds"].(float64); ok {
+		timeoutSeconds = int(timeout)
+	}
+
+	// Create A2A client
+	client := createA2AClient()
+
+	req := &a2aModels.SendMessageRequest{
+		Message: a2aModels.Message{
+			Content: message,
+			Format:  "text",
+		},
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx.Context(), time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	// With a progressToken the message goes over the agent's SSE stream so
+	// every state change reaches the client; agents without one are polled
+	var resp *a2aModels.SendMessageResponse
+	var updates <-chan task.TaskUpdate
+	var err error
+	if waitForCompletion && ctx.WantsProgress() {
+		if resp, updates, err = streamA2AMessage(waitCtx, agentURL, req); err != nil {
+			return nil, unavailable("Failed to send message: %s", err)
+		}
+	}
+	if resp == nil {
+		if resp, err = client.SendMessage(ctx.Context(), agentURL, req); err != nil {
+			return nil, unavailable("Failed to send message: %s", err)
+		}
+	}
+
+	result := map[string]interface{}{
+		"success":    true,
+		"agent_url":  agentURL,
+		"task_id":    resp.TaskID,
+		"status":     resp.Status,
+		"created_at": resp.CreatedAt,
+	}
+
+	// If waiting for completion, follow the task until done or the client
+	// cancels the call
+	if waitForCompletion {
+		progress := a2aProgress(ctx, resp.TaskID)
+		progress(a2aModels.TaskStatus(resp.Status))
+
+		remote, err := waitForA2ATask(waitCtx, client, agentURL, resp.TaskID, updates, progress)
+		if err != nil {
+			result["wait_error"] = err.Error()
+			result["final_status"] = "unknown"
+		} else {
+			result["final_status"] = remote.Status
+			result["task"] = remote
+		}
+	}
+
+	return result, nil
+}
+
+func (h *MCPHandler) executeGetA2ATaskStatus(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	agentURL, ok := args["agent_url"].(string)
+	if !ok || agentURL == "" {
+		return nil, invalidArgument("agent_url is required")
+	}
+
+	taskID, ok := args["task_id"].(string)
+	if !ok || taskID == "" {
+		return nil, invalidArgument("task_id is required")
+	}
+
+	// Create A2A client and get task
+	client := createA2AClient()
+	task, err := client.GetTask(ctx.Context(), agentURL, taskID)
+	if err != nil {
+		return nil, unavailable("Failed to get task: %s", err)
+	}
+
+	return map[string]interface{}{
+		"success":   true,
+		"agent_url": agentURL,
+		"task":      task,
+	}, nil
+}
+
+func createA2AClient() *a2aClient.HTTPClient {
+	return a2aClient.NewHTTPClient(a2aClient.WithTimeout(30 * time.Second))
+}
+
+// streamA2AMessage sends a message over the agent's SSE endpoint and returns
+// the created task and its remaining updates. It returns no response and no
+// error when the agent does not stream, so the caller can send the message
+// the usual way.
+func streamA2AMessage(ctx context.Context, agentURL string, req *a2aModels.SendMessageRequest) (*a2aModels.SendMessageResponse, <-chan task.TaskUpdate, error) {
+	// The stream lasts as long as the task, so only ctx bounds it
+	client := a2aClient.NewHTTPClient(a2aClient.WithHTTPClient(&http.Client{}))
+	updates, err := client.StreamMessage(ctx, agentURL, req)
+	if err != nil {
+		log.Printf("A2A agent %s does not stream, polling instead: %v", agentURL, err)
+		return nil, nil, nil
+	}
+
+	// The first event names the task. The agent accepted the message, so
+	// sending it again would start a second task.
+	created, ok := <-updates
+	data, _ := created.Data.(map[string]any)
+	taskID, _ := data["task_id"].(string)
+	if !ok || created.Event != "task_created" || taskID == "" {
+		return nil, nil, fmt.Errorf("stream ended before the agent created a task")
+	}
+
+	status, _ := data["status"].(string)
+	createdAt, _ := data["created_at"].(string)
+	return &a2aModels.SendMessageResponse{TaskID: taskID, Status: status, CreatedAt: createdAt}, updates, nil
+}
+
+// waitForA2ATask follows a remote task until it finishes, reporting each
+// status it goes through. Updates from the agent's stream are used while
+// they last; after that the task is polled.
+func waitForA2ATask(ctx context.Context, client *a2aClient.HTTPClient, agentURL, taskID string, updates <-chan task.TaskUpdate, progress func(a2aModels.TaskStatus)) (*a2aModels.Task, error) {
+	for update := range updates {
+		data, _ := update.Data.(map[string]any)
+		if status, ok := data["status"].(string); ok {
+			progress(a2aModels.TaskStatus(status))
+		}
+		if update.IsFinal {
+			var t a2aModels.Task
+			if raw, err := json.Marshal(update.Data); err == nil && json.Unmarshal(raw, &t) == nil && t.ID != "" {
+				return &t, nil
+			}
+			return client.GetTask(ctx, agentURL, taskID)
+		}
+	}
+
+	return pollTaskUntilComplete(ctx, client, agentURL, taskID, progress)
+}
+
+// a2aProgress returns a function that sends a progress notification each
+// time the remote task changes status
+func a2aProgress(ctx MCPContext, taskID string) func(a2aModels.TaskStatus) {
+	var last a2aModels.TaskStatus
+	step := 0
+	return func(status a2aModels.TaskStatus) {
+		if status == "" || status == last {
+			return
+		}
+		last = status
+		step++
+		ctx.Progress(float64(step), 0, fmt.Sprintf("A2A task %s is %s", taskID, status))
+	}
+}
+
+func pollTaskUntilComplete(ctx context.Context, client *a2aClient.HTTPClient, agentURL, taskID string, progress func(a2aModels.TaskStatus)) (*a2aModels.Task, error) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.Canceled {
+				return nil, fmt.Errorf("stopped waiting for task completion: call cancelled")
+			}
+			return nil, fmt.Errorf("timeout waiting for task completion")
+		case <-ticker.C:
+			task, err := client.GetTask(ctx, agentURL, taskID)
+			if err != nil {
+				return nil, err
+			}
+			progress(task.Status)
+
+			if task.Status == a2aModels.TaskStatusCompleted || task.Status == a2aModels.TaskStatusFailed {
+				return task, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// streamingAgent is an A2A agent whose message:stream endpoint runs a task
+// through running to completed
+func streamingAgent() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/a2a/v1/message:stream" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range []struct{ event, data string }{
+			{"task_created", `{"task_id":"t1","status":"pending","created_at":"2026-01-01T00:00:00Z"}`},
+			{"status", `{"task_id":"t1","status":"running"}`},
+			{"completed", `{"id":"t1","status":"completed","message":{"content":"hi","format":"text"}}`},
+		} {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.event, e.data)
+			w.(http.Flusher).Flush()
+		}
+	}))
+}
+
+func TestDelegateToA2AAgentProgress(t *testing.T) {
+	agent := streamingAgent()
+	defer agent.Close()
+
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	session := newSession(MCPContext{})
+	ctx := session.context(MCPContext{})
+	ctx.progressToken = "tok"
+
+	result, err := h.executeDelegateToA2AAgent(map[string]interface{}{
+		"agent_url":           agent.URL,
+		"message":             "hi",
+		"wait_for_completion": true,
+		"timeout_seconds":     5.0,
+	}, ctx)
+	if err != nil {
+		t.Fatalf("delegate_to_a2a_agent failed: %v", err)
+	}
+	if got := result.(map[string]interface{}); got["task_id"] != "t1" || fmt.Sprint(got["final_status"]) != "completed" {
+		t.Errorf("Expected task t1 to complete, got %+v", got)
+	}
+
+	events, _ := session.pending(nil, 0)
+	var messages []string
+	for i, e := range events {
+		var n struct {
+			Method string         `json:"method"`
+			Params ProgressParams `json:"params"`
+		}
+		json.Unmarshal(e.Data, &n)
+		if n.Method != "notifications/progress" || n.Params.ProgressToken != "tok" || n.Params.Progress != float64(i+1) {
+			t.Errorf("Expected progress %d for tok, got %s", i+1, e.Data)
+		}
+		messages = append(messages, n.Params.Message)
+	}
+	if len(messages) != 3 || messages[2] != "A2A task t1 is completed" {
+		t.Errorf("Expected pending, running and completed progress, got %v", messages)
+	}
+}
+
+func TestDelegateToA2AAgentWithoutProgressToken(t *testing.T) {
+	agent := streamingAgent()
+	defer agent.Close()
+
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	session := newSession(MCPContext{})
+
+	// Without a progressToken the agent is not streamed from; this one
+	// cannot take plain messages
+	_, err := h.executeDelegateToA2AAgent(map[string]interface{}{
+		"agent_url":           agent.URL,
+		"message":             "hi",
+		"wait_for_completion": true,
+	}, session.context(MCPContext{}))
+	if err == nil || asToolError(err).Code != CodeUnavailable {
+		t.Errorf("Expected the plain send to fail with unavailable, got %v", err)
+	}
+	if events, _ := session.pending(nil, 0); len(events) != 0 {
+		t.Errorf("Expected no progress notifications, got %d", len(events))
+	}
+}
@@ -0,0 +1,201 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// agentTools read agents
+var agentTools = []ToolDefinition{
+	{
+		Name:        "list_agents",
+		Description: "List agents, optionally filtered by project, role, team, status and capabilities, a page at a time",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"project_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional project ID to filter agents",
+				},
+				"role": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional role to filter agents, such as qa or devops",
+				},
+				"team": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional team to filter agents",
+				},
+				"capabilities": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Only return agents that have all of these capabilities",
+				},
+				"status": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional status filter, such as active or idle",
+				},
+				"fields": fieldsArgument("project_id", "name", "role", "team", "status", "capabilities", "created_at"),
+				"cursor": cursorArgument,
+				"limit":  limitArgument,
+			},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"agents":     outputList("Agents matching the filters, newest first"),
+			"count":      countField,
+			"nextCursor": nextCursorField,
+		}, "agents", "count"),
+		Access:  ToolAccess{Action: auth.ActionRead, Project: listProject},
+		Handler: (*MCPHandler).executeListAgents,
+	},
+	{
+		Name:        "get_agent",
+		Description: "Get details of a specific agent",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"agent_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The agent ID (UUID)",
+				},
+			},
+			Required: []string{"agent_id"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"id":           outputField("string", "Agent ID"),
+			"project_id":   outputField("string", "Project the agent belongs to"),
+			"name":         outputField("string", "Agent name"),
+			"role":         outputField("string", "Agent role"),
+			"team":         outputField("string", "Agent team, when set"),
+			"status":       outputField("string", "Agent status"),
+			"capabilities": outputNullable("array", "Capability tags"),
+			"created_at":   outputField("string", "Creation time"),
+		}, "id", "project_id", "name", "role", "status"),
+		Access:  ToolAccess{Action: auth.ActionRead, Project: agentProject},
+		Handler: (*MCPHandler).executeGetAgent,
+	},
+}
+
+func (h *MCPHandler) executeListAgents(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	pg, err := parsePage("agents", args)
+	if err != nil {
+		return nil, err
+	}
+	fields := fieldSelection(args)
+
+	scope, err := h.visibleProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, project_id, name, role, status, team, capabilities, created_at FROM agents WHERE 1=1`
+	var queryArgs []interface{}
+
+	if args != nil {
+		if projectID, ok := args["project_id"].(string); ok && projectID != "" {
+			queryArgs = append(queryArgs, projectID)
+			query += fmt.Sprintf(" AND project_id = $%d", len(queryArgs))
+		}
+		if role, ok := args["role"].(string); ok && role != "" {
+			queryArgs = append(queryArgs, models.NormalizeAgentRole(models.AgentRole(role)))
+			query += fmt.Sprintf(" AND LOWER(role) = $%d", len(queryArgs))
+		}
+		if team, ok := args["team"].(string); ok && team != "" {
+			queryArgs = append(queryArgs, team)
+			query += fmt.Sprintf(" AND LOWER(team) = LOWER($%d)", len(queryArgs))
+		}
+		if capabilities := models.NormalizeCapabilities(stringList(args["capabilities"])); len(capabilities) > 0 {
+			queryArgs = append(queryArgs, pq.Array(capabilities))
+			query += fmt.Sprintf(" AND capabilities @> $%d::text[]", len(queryArgs))
+		}
+		if status, ok := args["status"].(string); ok && status != "" {
+			queryArgs = append(queryArgs, status)
+			query += fmt.Sprintf(" AND status = $%d", len(queryArgs))
+		}
+	}
+	query += scope.filter("project_id", &queryArgs)
+	query += pg.clause("", &queryArgs)
+
+	rows, err := h.db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	agents := []map[string]interface{}{}
+	for rows.Next() {
+		var id, projectID, name, role, status string
+		var team *string
+		var capabilities []string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &projectID, &name, &role, &status, &team, pq.Array(&capabilities), &createdAt); err != nil {
+			continue
+		}
+		if !pg.keep(createdAt, id) {
+			break
+		}
+		agent := map[string]interface{}{
+			"id":           id,
+			"project_id":   projectID,
+			"name":         name,
+			"role":         role,
+			"status":       status,
+			"capabilities": capabilities,
+			"created_at":   createdAt,
+		}
+		if team != nil {
+			agent["team"] = *team
+		}
+		agents = append(agents, selectFields(agent, fields))
+	}
+
+	return pg.result(map[string]interface{}{
+		"agents": agents,
+		"count":  len(agents),
+	}), nil
+}
+
+func (h *MCPHandler) executeGetAgent(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	agentID, ok := args["agent_id"].(string)
+	if !ok {
+		return nil, invalidArgument("agent_id is required")
+	}
+
+	var id, projectID, name, role, status string
+	var team *string
+	var capabilities []string
+	var createdAt interface{}
+	err := h.db.QueryRow(`
+		SELECT id, project_id, name, role, status, team, capabilities, created_at 
+		FROM agents WHERE id = $1
+	`, agentID).Scan(&id, &projectID, &name, &role, &status, &team, pq.Array(&capabilities), &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	agent := map[string]interface{}{
+		"id":           id,
+		"project_id":   projectID,
+		"name":         name,
+		"role":         role,
+		"status":       status,
+		"capabilities": capabilities,
+		"created_at":   createdAt,
+	}
+	if team != nil {
+		agent["team"] = *team
+	}
+
+	return agent, nil
+}
@@ -0,0 +1,332 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+const (
+	// approvalRequestedMessage and approvalDecidedMessage are broadcast when
+	// a tool call is queued for approval and when it is decided
+	approvalRequestedMessage = "mcp_approval_requested"
+	approvalDecidedMessage   = "mcp_approval_decided"
+)
+
+// approvalTimeout is how long a tool call waits for a human before giving up
+var approvalTimeout = 10 * time.Minute
+
+// approvalPollInterval is how often a waiting tool call re-reads its approval,
+// which is how it learns about decisions made through another process
+var approvalPollInterval = time.Second
+
+// ApprovalRequest describes a tool call that must be confirmed by a human
+// before it runs
+type ApprovalRequest struct {
+	// ProjectID is the project whose maintainers decide on the call
+	ProjectID uuid.UUID
+	// Summary tells the human what the call will do
+	Summary string
+}
+
+// ApprovalPolicy decides whether a tool call needs a human's approval. It
+// returns nil when the call may run right away.
+type ApprovalPolicy func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) *ApprovalRequest
+
+// approvalDecision is what a waiting tool call learns about its approval
+type approvalDecision struct {
+	status models.ToolApprovalStatus
+	reason string
+}
+
+// approve holds a tool call whose policy asks for approval until a human
+// decides. Clients that support elicitation ask their user directly; for the
+// others the call is queued for the project's maintainers, who decide from
+// the REST API or the web UI. It returns nil when the call may run.
+func (h *MCPHandler) approve(def *ToolDefinition, args map[string]interface{}, ctx MCPContext) *ToolError {
+	if def.Approval == nil {
+		return nil
+	}
+	req := def.Approval(h, args, ctx)
+	if req == nil {
+		return nil
+	}
+
+	if ctx.Session.supportsElicitation() {
+		approved, err := h.elicitApproval(def, req, ctx)
+		switch {
+		case err == nil && approved:
+			return nil
+		case err == nil:
+			return &ToolError{Code: CodePermissionDenied, Message: fmt.Sprintf("%s was not approved", def.Name)}
+		case ctx.Context().Err() != nil:
+			return unavailable("%s was cancelled while waiting for approval", def.Name)
+		case errors.Is(err, context.DeadlineExceeded):
+			return &ToolError{Code: CodePermissionDenied, Message: fmt.Sprintf("%s was not approved within %s", def.Name, approvalTimeout)}
+		}
+		// A client that fails to ask its user falls back to the queue
+		log.Printf("MCP elicitation for %s failed, queueing for approval: %v", def.Name, err)
+	}
+
+	return h.queueApproval(def, req, args, ctx)
+}
+
+// supportsElicitation reports whether the client declared the elicitation
+// capability when it initialized the session
+func (s *Session) supportsElicitation() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.ClientCapabilities["elicitation"]
+	return ok
+}
+
+// elicitApproval asks the client's user to approve a tool call with an
+// elicitation/create request and reports whether they accepted
+func (h *MCPHandler) elicitApproval(def *ToolDefinition, req *ApprovalRequest, ctx MCPContext) (bool, error) {
+	waitCtx, cancel := context.WithTimeout(ctx.Context(), approvalTimeout)
+	defer cancel()
+
+	result, err := ctx.Session.request(waitCtx, ctx.stream, "elicitation/create", map[string]interface{}{
+		"message": fmt.Sprintf("Approve %s? %s", def.Name, req.Summary),
+		"requestedSchema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"approve": map[string]interface{}{
+					"type":        "boolean",
+					"title":       "Approve",
+					"description": "Let the tool call run",
+				},
+			},
+			"required": []string{"approve"},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var answer struct {
+		Action  string `json:"action"`
+		Content struct {
+			Approve bool `json:"approve"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(result, &answer); err != nil {
+		return false, fmt.Errorf("invalid elicitation result: %w", err)
+	}
+	return answer.Action == "accept" && answer.Content.Approve, nil
+}
+
+// queueApproval records a pending approval, tells the project about it and
+// waits until it is decided, the wait times out or the client cancels.
+// Decisions made by this process arrive through the hub; the approval row is
+// also polled so a server that only shares the database, such as mcp-stdio,
+// sees them too.
+func (h *MCPHandler) queueApproval(def *ToolDefinition, req *ApprovalRequest, args map[string]interface{}, ctx MCPContext) *ToolError {
+	if h.db == nil {
+		return errNoDatabase
+	}
+	if req.ProjectID == uuid.Nil {
+		return &ToolError{Code: CodeNotConfigured, Message: fmt.Sprintf("%s needs approval, but no project is configured to approve it in. Add ?project_id=UUID to the URL.", def.Name)}
+	}
+
+	arguments, err := json.Marshal(args)
+	if err != nil {
+		return invalidArgument("arguments cannot be encoded: %v", err)
+	}
+	approval := models.ToolApproval{
+		ID:        uuid.New(),
+		ProjectID: req.ProjectID,
+		ToolName:  def.Name,
+		Arguments: arguments,
+		Summary:   req.Summary,
+		Status:    models.ApprovalPending,
+	}
+	if agentID := parseID(ctx.AgentID); agentID != uuid.Nil {
+		approval.AgentID = &agentID
+	}
+
+	// The waiter is registered first so a quick decision is not missed
+	decided := make(chan approvalDecision, 1)
+	h.approvals.Store(approval.ID, decided)
+	defer h.approvals.Delete(approval.ID)
+
+	err = h.db.QueryRow(`
+		INSERT INTO mcp_tool_approvals (id, project_id, agent_id, tool_name, arguments, summary, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING requested_at
+	`, approval.ID, approval.ProjectID, approval.AgentID, approval.ToolName, []byte(approval.Arguments), approval.Summary, approval.Status).Scan(&approval.RequestedAt)
+	if err != nil {
+		return asToolError(fmt.Errorf("failed to queue approval: %w", err))
+	}
+	if h.hub != nil {
+		h.hub.BroadcastToProject(approval.ProjectID, approvalRequestedMessage, approval)
+	}
+	log.Printf("MCP tool %s is waiting for approval %s", def.Name, approval.ID)
+
+	timeout := time.NewTimer(approvalTimeout)
+	defer timeout.Stop()
+	poll := time.NewTicker(approvalPollInterval)
+	defer poll.Stop()
+
+	var decision approvalDecision
+wait:
+	for {
+		select {
+		case decision = <-decided:
+			break wait
+		case <-poll.C:
+			if d, ok := h.readDecision(approval.ID); ok {
+				decision = d
+				break wait
+			}
+		case <-timeout.C:
+			decision = h.expireApproval(approval)
+			break wait
+		case <-ctx.Context().Done():
+			h.expireApproval(approval)
+			return unavailable("%s was cancelled while waiting for approval", def.Name)
+		}
+	}
+
+	details := map[string]interface{}{"approval_id": approval.ID.String(), "status": decision.status}
+	switch decision.status {
+	case models.ApprovalApproved:
+		return nil
+	case models.ApprovalRejected:
+		if decision.reason != "" {
+			details["reason"] = decision.reason
+		}
+		return &ToolError{Code: CodePermissionDenied, Message: fmt.Sprintf("%s was rejected", def.Name), Details: details}
+	}
+	return &ToolError{Code: CodePermissionDenied, Message: fmt.Sprintf("%s was not approved within %s", def.Name, approvalTimeout), Details: details}
+}
+
+// expireApproval marks an approval nobody decided in time as expired. When
+// a decision raced the timeout, that decision is returned instead.
+func (h *MCPHandler) expireApproval(approval models.ToolApproval) approvalDecision {
+	var decidedAt time.Time
+	err := h.db.QueryRow(`
+		UPDATE mcp_tool_approvals
+		SET status = 'expired', decided_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'
+		RETURNING decided_at
+	`, approval.ID).Scan(&decidedAt)
+	if err == nil {
+		approval.Status = models.ApprovalExpired
+		approval.DecidedAt = &decidedAt
+		if h.hub != nil {
+			h.hub.BroadcastToProject(approval.ProjectID, approvalDecidedMessage, approval)
+		}
+		return approvalDecision{status: models.ApprovalExpired}
+	}
+
+	if err != sql.ErrNoRows {
+		log.Printf("Failed to expire approval %s: %v", approval.ID, err)
+		return approvalDecision{status: models.ApprovalExpired}
+	}
+	if decision, ok := h.readDecision(approval.ID); ok {
+		return decision
+	}
+	return approvalDecision{status: models.ApprovalExpired}
+}
+
+// readDecision loads an approval and reports whether it has been decided
+func (h *MCPHandler) readDecision(id uuid.UUID) (approvalDecision, bool) {
+	var decision approvalDecision
+	var reason sql.NullString
+	if err := h.db.QueryRow("SELECT status, reason FROM mcp_tool_approvals WHERE id = $1", id).Scan(&decision.status, &reason); err != nil {
+		log.Printf("Failed to read approval %s: %v", id, err)
+		return decision, false
+	}
+	decision.reason = reason.String
+	return decision, decision.status != models.ApprovalPending
+}
+
+// approvalDecided is registered with the hub and releases the tool call
+// waiting on an approval decided through this server's REST API right away
+func (h *MCPHandler) approvalDecided(projectID uuid.UUID, messageType string, payload interface{}) {
+	if messageType != approvalDecidedMessage {
+		return
+	}
+	fields := payloadFields(payload)
+	waiter, ok := h.approvals.Load(fieldID(fields, "id"))
+	if !ok {
+		return
+	}
+
+	status, _ := fields["status"].(string)
+	reason, _ := fields["reason"].(string)
+	select {
+	case waiter.(chan approvalDecision) <- approvalDecision{status: models.ToolApprovalStatus(status), reason: reason}:
+	default:
+	}
+}
+
+// Approval policies of the built-in tools
+
+// approveOthersTask asks for approval before a task assigned to another
+// agent is taken away from it. Only the authenticated agent counts as the
+// assignee, since the connection's agent_id can be set by any caller.
+func approveOthersTask(h *MCPHandler, args map[string]interface{}, ctx MCPContext) *ApprovalRequest {
+	if h.db == nil {
+		return nil
+	}
+	taskID, _ := args["task_id"].(string)
+	newAgent, _ := args["agent_id"].(string)
+
+	var projectID uuid.UUID
+	var title string
+	var assignedTo uuid.NullUUID
+	err := h.db.QueryRow("SELECT project_id, title, assigned_to FROM tasks WHERE id = $1", parseID(taskID)).Scan(&projectID, &title, &assignedTo)
+	if err != nil || !assignedTo.Valid {
+		// Missing tasks are reported by the tool; unassigned ones are free to take
+		return nil
+	}
+	if ctx.Principal.IsAgent(assignedTo.UUID) || assignedTo.UUID == parseID(newAgent) {
+		return nil
+	}
+	return &ApprovalRequest{
+		ProjectID: projectID,
+		Summary:   fmt.Sprintf("Reassign task %q from agent %s to agent %s", title, assignedTo.UUID, newAgent),
+	}
+}
+
+// approveCancelTask asks for approval before a task is cancelled, which is
+// how MCP clients drop a task
+func approveCancelTask(h *MCPHandler, args map[string]interface{}, ctx MCPContext) *ApprovalRequest {
+	if args["status"] != string(models.StatusCancelled) || h.db == nil {
+		return nil
+	}
+	taskID, _ := args["task_id"].(string)
+
+	var projectID uuid.UUID
+	var title string
+	if err := h.db.QueryRow("SELECT project_id, title FROM tasks WHERE id = $1", parseID(taskID)).Scan(&projectID, &title); err != nil {
+		return nil
+	}
+	return &ApprovalRequest{ProjectID: projectID, Summary: fmt.Sprintf("Cancel task %q", title)}
+}
+
+// approveDelegation asks for approval before work is sent to an external
+// A2A agent
+func approveDelegation(h *MCPHandler, args map[string]interface{}, ctx MCPContext) *ApprovalRequest {
+	agentURL, _ := args["agent_url"].(string)
+	message, _ := args["message"].(string)
+	if runes := []rune(message); len(runes) > 200 {
+		message = string(runes[:200]) + "…"
+	}
+	return &ApprovalRequest{
+		ProjectID: parseID(ctx.ProjectID),
+		Summary:   fmt.Sprintf("Send to the A2A agent at %s: %s", agentURL, message),
+	}
+}
@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// approvalHandler serves a tool that always asks for approval
+func approvalHandler() *MCPHandler {
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	h.Tools().Register(ToolDefinition{
+		Name:   "drop_everything",
+		Access: ToolAccess{Global: true},
+		Handler: func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+			return map[string]interface{}{"dropped": true}, nil
+		},
+		Approval: func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) *ApprovalRequest {
+			return &ApprovalRequest{Summary: "Drop every task"}
+		},
+	})
+	return h
+}
+
+// callWithElicitation calls drop_everything in a session whose client
+// supports elicitation and answers the elicitation/create request with answer
+func callWithElicitation(t *testing.T, answer string) ToolResult {
+	h := approvalHandler()
+	session := newSession(MCPContext{})
+	session.ClientCapabilities = map[string]interface{}{"elicitation": map[string]interface{}{}}
+	ctx := session.context(MCPContext{})
+
+	call, _ := parsePayload([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"drop_everything"}}`))
+	done := make(chan interface{})
+	go func() { done <- h.run(context.Background(), call, ctx) }()
+
+	var request JSONRPCRequest
+	for deadline := time.Now().Add(2 * time.Second); request.Method == ""; {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the server to send an elicitation/create request")
+		}
+		events, wake := session.pending(nil, 0)
+		if len(events) == 0 {
+			<-wake
+			continue
+		}
+		json.Unmarshal(events[0].Data, &request)
+	}
+	if request.Method != "elicitation/create" || request.ID == nil {
+		t.Fatalf("Expected an elicitation/create request, got %+v", request)
+	}
+
+	reply, _ := parsePayload([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%q,"result":%s}`, request.ID, answer)))
+	if got := h.run(context.Background(), reply, ctx); got != nil {
+		t.Errorf("Expected no reply to a response, got %+v", got)
+	}
+
+	select {
+	case got := <-done:
+		return got.(JSONRPCResponse).Result.(ToolResult)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the tool call to finish once the client answered")
+	}
+	return ToolResult{}
+}
+
+func TestApprovalByElicitation(t *testing.T) {
+	if result := callWithElicitation(t, `{"action":"accept","content":{"approve":true}}`); result.IsError {
+		t.Errorf("Expected an approved call to run, got %+v", result)
+	}
+
+	for _, answer := range []string{
+		`{"action":"accept","content":{"approve":false}}`,
+		`{"action":"decline"}`,
+	} {
+		result := callWithElicitation(t, answer)
+		if err, ok := result.StructuredContent.(*ToolError); !result.IsError || !ok || err.Code != CodePermissionDenied {
+			t.Errorf("Expected %s to deny the call, got %+v", answer, result)
+		}
+	}
+}
+
+func TestApprovalQueueNeedsDatabase(t *testing.T) {
+	h := approvalHandler()
+
+	// Without elicitation the call is queued, which needs the database
+	result, _ := h.handleToolsCall(json.RawMessage(`{"name":"drop_everything"}`), MCPContext{})
+	if err, ok := result.(ToolResult).StructuredContent.(*ToolError); !ok || err.Code != CodeUnavailable {
+		t.Errorf("Expected the queue to be unavailable without a database, got %+v", result)
+	}
+}
+
+func TestApprovalDecided(t *testing.T) {
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	id := uuid.New()
+	decided := make(chan approvalDecision, 1)
+	h.approvals.Store(id, decided)
+
+	h.approvalDecided(uuid.New(), "task_update", models.ToolApproval{ID: id})
+	h.approvalDecided(uuid.New(), approvalDecidedMessage, models.ToolApproval{ID: uuid.New(), Status: models.ApprovalApproved})
+	select {
+	case d := <-decided:
+		t.Fatalf("Expected only this approval's decision to be delivered, got %+v", d)
+	default:
+	}
+
+	h.approvalDecided(uuid.New(), approvalDecidedMessage, models.ToolApproval{ID: id, Status: models.ApprovalRejected, Reason: "not now"})
+	select {
+	case d := <-decided:
+		if d.status != models.ApprovalRejected || d.reason != "not now" {
+			t.Errorf("Expected the rejection with its reason, got %+v", d)
+		}
+	default:
+		t.Error("Expected the decision to reach the waiting call")
+	}
+}
@@ -0,0 +1,294 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+)
+
+// ToolAccess declares who may call a tool. Every tool must declare either
+// the permission it needs, the minimum role it needs, or that it is global.
+type ToolAccess struct {
+	// Action is the permission a call needs in the project Project resolves to
+	Action auth.Action
+	// Role, when set instead of Action, is the minimum project role a call
+	// needs. Unlike actions it is never granted to anonymous callers, even
+	// when authentication is optional; admins always hold it.
+	Role auth.Role
+	// Project resolves the project a call acts on
+	Project ProjectResolver
+	// Global marks tools that do not act on a single project, such as
+	// identity and discovery tools. Tools listing data of several projects
+	// limit it to the caller's visible projects themselves.
+	Global bool
+}
+
+// declared reports whether the access rules are complete
+func (a ToolAccess) declared() bool {
+	if a.Global {
+		return a.Action == "" && a.Role == "" && a.Project == nil
+	}
+	return a.Project != nil && (a.Action != "") != (a.Role != "")
+}
+
+// ToolScope is the project a tool call acts on and the agents that own its
+// target (creator, assignee, author), which some actions are limited to
+type ToolScope struct {
+	ProjectID uuid.UUID
+	Owners    []uuid.UUID
+}
+
+// ProjectResolver finds the project a tool call acts on. It returns a tool
+// error when the call cannot be scoped, such as when the arguments name a
+// task or agent that cannot be found; a call is never allowed without a scope.
+type ProjectResolver func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError)
+
+// authorizeTool checks the caller's project role against the tool's declared
+// access before it runs. It returns a tool error when the call is denied.
+func (h *MCPHandler) authorizeTool(def *ToolDefinition, args map[string]interface{}, ctx MCPContext) *ToolError {
+	scope, denied := h.toolScope(def, args, ctx)
+	if denied != nil {
+		return denied
+	}
+	return h.authorizeScope(def, scope, ctx)
+}
+
+// toolScope resolves the project a tool call acts on. Global tools have no scope.
+func (h *MCPHandler) toolScope(def *ToolDefinition, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError) {
+	access := def.Access
+	if !access.declared() {
+		return nil, &ToolError{Code: CodeInternal, Message: fmt.Sprintf("tool %s declares no access rules", def.Name)}
+	}
+	if access.Global {
+		return nil, nil
+	}
+
+	scope, err := access.Project(h, args, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if scope == nil {
+		return nil, &ToolError{Code: CodeInternal, Message: fmt.Sprintf("tool %s resolved no project", def.Name)}
+	}
+	return scope, nil
+}
+
+// authorizeScope checks the caller may call a tool in the scope toolScope resolved
+func (h *MCPHandler) authorizeScope(def *ToolDefinition, scope *ToolScope, ctx MCPContext) *ToolError {
+	access := def.Access
+	if access.Global {
+		return nil
+	}
+	if access.Role != auth.RoleNone {
+		return h.checkRole(def.Name, access.Role, scope, ctx)
+	}
+	if h.auth == nil {
+		return nil
+	}
+	return h.check(ctx, scope.ProjectID, access.Action, scope.Owners...)
+}
+
+// checkRole checks a tool that needs a minimum role. Anonymous callers are
+// denied, as is everyone but admins when the tool needs the admin role.
+func (h *MCPHandler) checkRole(name string, min auth.Role, scope *ToolScope, ctx MCPContext) *ToolError {
+	denied := &ToolError{Code: CodePermissionDenied, Message: fmt.Sprintf("permission denied: %s needs the %s role", name, min)}
+	if min == auth.RoleAdmin {
+		denied.Message = fmt.Sprintf("permission denied: %s is reserved to admins", name)
+	}
+
+	if ctx.Principal == nil || h.auth == nil {
+		return denied
+	}
+	if ctx.Principal.IsAdmin() {
+		return nil
+	}
+	if min == auth.RoleAdmin {
+		return denied
+	}
+
+	role, err := h.auth.RoleFor(context.Background(), ctx.Principal, scope.ProjectID)
+	if err != nil {
+		return &ToolError{Code: CodeInternal, Message: "failed to check permissions"}
+	}
+	if !auth.AtLeast(role, min) {
+		return denied
+	}
+	return nil
+}
+
+// Project resolvers of the built-in tools
+
+// argProject scopes a call to the project named by the projectKey argument,
+// falling back to the connection's project. ownerKey, when set, names the
+// agent argument that owns the target, falling back to the connection's agent.
+func argProject(projectKey, ownerKey string) ProjectResolver {
+	return func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError) {
+		scope := &ToolScope{ProjectID: parseID(argOrDefault(args, projectKey, ctx.ProjectID))}
+		if ownerKey != "" {
+			scope.Owners = []uuid.UUID{parseID(argOrDefault(args, ownerKey, ctx.AgentID))}
+		}
+		return scope, nil
+	}
+}
+
+// connectionProject scopes a call to the connection's project and agent
+func connectionProject(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError) {
+	return &ToolScope{ProjectID: parseID(ctx.ProjectID), Owners: []uuid.UUID{parseID(ctx.AgentID)}}, nil
+}
+
+// taskOwners scopes a call to the project of the task named by key, owned by
+// the task's creator and assignee
+func taskOwners(key string) ProjectResolver {
+	return func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError) {
+		if h.db == nil {
+			return nil, errNoDatabase
+		}
+		taskID, _ := args[key].(string)
+		projectID, owners, ok := h.lookupTaskOwners(taskID)
+		if !ok {
+			return nil, notFound("Task not found")
+		}
+		return &ToolScope{ProjectID: projectID, Owners: owners}, nil
+	}
+}
+
+// taskProject scopes a call to the project of the task named by key, for
+// something the calling agent adds to it
+func taskProject(key string) ProjectResolver {
+	return func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError) {
+		if h.db == nil {
+			return nil, errNoDatabase
+		}
+		taskID, _ := args[key].(string)
+		projectID, _, ok := h.lookupTaskOwners(taskID)
+		if !ok {
+			return nil, notFound("Task not found")
+		}
+		return &ToolScope{ProjectID: projectID, Owners: []uuid.UUID{parseID(ctx.AgentID)}}, nil
+	}
+}
+
+// historyProject scopes a call to the project of a task, resolved through its
+// recorded history so deleted tasks are found too
+func historyProject(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+	taskID, _ := args["task_id"].(string)
+	projectID, err := history.ProjectOf(context.Background(), h.db, parseID(taskID))
+	if err != nil {
+		return nil, notFound("No history found for task")
+	}
+	return &ToolScope{ProjectID: projectID}, nil
+}
+
+// agentProject scopes a call to the project of the agent named by agent_id
+func agentProject(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+	agentID, _ := args["agent_id"].(string)
+	var projectID uuid.UUID
+	if err := h.db.QueryRow("SELECT project_id FROM agents WHERE id = $1", parseID(agentID)).Scan(&projectID); err != nil {
+		return nil, notFound("Agent not found")
+	}
+	return &ToolScope{ProjectID: projectID}, nil
+}
+
+// listProject scopes a listing to its project_id argument. Listings without
+// one are limited to the caller's own project unless they are an admin.
+// Admins and anonymous callers get the nil project, which only they are
+// allowed, so anonymous callers may list everything on open servers only.
+func listProject(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError) {
+	projectID, _ := args["project_id"].(string)
+	if projectID == "" && ctx.Principal != nil && !ctx.Principal.IsAdmin() {
+		if ctx.ProjectID == "" {
+			return nil, invalidArgument("project_id is required")
+		}
+		projectID = ctx.ProjectID
+		args["project_id"] = projectID
+	}
+	return &ToolScope{ProjectID: parseID(projectID)}, nil
+}
+
+// projectScope is the set of projects a caller may read
+type projectScope struct {
+	all bool
+	ids []string
+}
+
+// visibleProjects resolves the projects the caller may read, for tools and
+// resources that list data of several projects
+func (h *MCPHandler) visibleProjects(ctx MCPContext) (projectScope, error) {
+	if h.auth == nil {
+		return projectScope{all: true}, nil
+	}
+	ids, all, err := h.auth.VisibleProjects(auth.WithPrincipal(context.Background(), ctx.Principal))
+	if err != nil {
+		return projectScope{}, err
+	}
+	scope := projectScope{all: all, ids: make([]string, len(ids))}
+	for i, id := range ids {
+		scope.ids[i] = id.String()
+	}
+	return scope, nil
+}
+
+// filter returns a condition limiting column to the scope's projects, to be
+// added to a query's WHERE clause, and appends its argument to queryArgs
+func (s projectScope) filter(column string, queryArgs *[]interface{}) string {
+	if s.all {
+		return ""
+	}
+	*queryArgs = append(*queryArgs, pq.Array(s.ids))
+	return fmt.Sprintf(" AND %s = ANY($%d::uuid[])", column, len(*queryArgs))
+}
+
+// check authorizes the MCP caller and converts a denial into a tool error
+func (h *MCPHandler) check(ctx MCPContext, projectID uuid.UUID, action auth.Action, owners ...uuid.UUID) *ToolError {
+	err := h.auth.AuthorizePrincipal(context.Background(), ctx.Principal, projectID, action, owners...)
+	if errors.Is(err, auth.ErrForbidden) {
+		return &ToolError{Code: CodePermissionDenied, Message: "permission denied: your role does not allow " + string(action) + " in this project"}
+	} else if err != nil {
+		return &ToolError{Code: CodeInternal, Message: "failed to check permissions"}
+	}
+	return nil
+}
+
+func (h *MCPHandler) lookupTaskOwners(taskID string) (uuid.UUID, []uuid.UUID, bool) {
+	id, err := uuid.Parse(taskID)
+	if err != nil || h.db == nil {
+		return uuid.Nil, nil, false
+	}
+
+	var projectID, createdBy uuid.UUID
+	var assignedTo uuid.NullUUID
+	err = h.db.QueryRow("SELECT project_id, created_by, assigned_to FROM tasks WHERE id = $1", id).Scan(&projectID, &createdBy, &assignedTo)
+	if err != nil {
+		return uuid.Nil, nil, false
+	}
+
+	owners := []uuid.UUID{createdBy}
+	if assignedTo.Valid {
+		owners = append(owners, assignedTo.UUID)
+	}
+	return projectID, owners, true
+}
+
+func argOrDefault(args map[string]interface{}, key, fallback string) string {
+	if v, ok := args[key].(string); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// parseID parses a UUID, returning uuid.Nil for empty or malformed input
+func parseID(s string) uuid.UUID {
+	id, _ := uuid.Parse(s)
+	return id
+}
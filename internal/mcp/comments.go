@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/comments"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+// commentTools post and read task comments
+var commentTools = []ToolDefinition{
+	{
+		Name:        "comment_on_task",
+		Description: "Post a markdown comment on a task, or reply to an existing comment (requires agent_id in connection URL)",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"task_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The task ID",
+				},
+				"body": map[string]interface{}{
+					"type":        "string",
+					"description": "Comment text in markdown",
+				},
+				"parent_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional comment ID to reply to",
+				},
+			},
+			Required: []string{"task_id", "body"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"success": successField,
+			"comment": outputField("object", "The posted comment"),
+		}, "success", "comment"),
+		Requires: RequiresAgent,
+		Access:   ToolAccess{Action: auth.ActionWriteComment, Project: taskProject("task_id")},
+		Handler:  (*MCPHandler).executeCommentOnTask,
+	},
+	{
+		Name:        "list_task_comments",
+		Description: "List the discussion on a task as threads, oldest first, with replies nested under their parent",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"task_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The task ID",
+				},
+			},
+			Required: []string{"task_id"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"task_id":  outputField("string", "Task ID"),
+			"comments": outputList("Top-level comments with their replies"),
+		}, "task_id", "comments"),
+		Access:  ToolAccess{Action: auth.ActionRead, Project: taskProject("task_id")},
+		Handler: (*MCPHandler).executeListTaskComments,
+	},
+}
+
+func (h *MCPHandler) executeCommentOnTask(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil || h.comments == nil {
+		return nil, errNoDatabase
+	}
+
+	taskID, err := uuid.Parse(fmt.Sprint(args["task_id"]))
+	if err != nil {
+		return nil, invalidArgument("task_id must be a valid UUID")
+	}
+	agentID, err := uuid.Parse(ctx.AgentID)
+	if err != nil {
+		return nil, invalidArgument("agent_id must be a valid UUID")
+	}
+
+	body, _ := args["body"].(string)
+	req := models.CreateCommentRequest{AgentID: agentID, Body: body}
+	if parent, _ := args["parent_id"].(string); parent != "" {
+		parentID, err := uuid.Parse(parent)
+		if err != nil {
+			return nil, invalidArgument("parent_id must be a valid UUID")
+		}
+		req.ParentID = &parentID
+	}
+
+	if err := validator.ValidateCreateCommentRequest(&req); err != nil {
+		return nil, invalidArgument("%s", err)
+	}
+
+	comment, err := h.comments.Create(context.Background(), taskID, req)
+	if errors.Is(err, comments.ErrTaskNotFound) || errors.Is(err, comments.ErrParentNotFound) || errors.Is(err, comments.ErrAgentNotFound) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("Failed to post comment: %w", err)
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"comment": comment,
+	}, nil
+}
+
+func (h *MCPHandler) executeListTaskComments(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil || h.comments == nil {
+		return nil, errNoDatabase
+	}
+
+	taskID, err := uuid.Parse(fmt.Sprint(args["task_id"]))
+	if err != nil {
+		return nil, invalidArgument("task_id must be a valid UUID")
+	}
+
+	threads, err := h.comments.List(context.Background(), taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"task_id":  taskID,
+		"comments": threads,
+	}, nil
+}
@@ -0,0 +1,276 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+// contextTools share and read contexts
+var contextTools = []ToolDefinition{
+	{
+		Name:        "list_contexts",
+		Description: "List documentation and contexts shared by agents in the project, newest first, a page at a time. Content is in markdown format for easy reading; ask only for id, title and preview to browse without full contents.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"project_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional project ID to filter contexts (uses connection URL context if not provided)",
+				},
+				"agent_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Only return contexts shared by this agent",
+				},
+				"tags": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Only return contexts that have all of these tags",
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Only return contexts whose title contains this text",
+				},
+				"fields": fieldsArgument("project_id", "agent_id", "agent_name", "title", "content", "preview", "format", "tags", "created_at"),
+				"cursor": cursorArgument,
+				"limit":  limitArgument,
+			},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"contexts":   outputList("Contexts, newest first"),
+			"count":      countField,
+			"nextCursor": nextCursorField,
+			"note":       messageField,
+		}, "contexts", "count"),
+		Access:  ToolAccess{Action: auth.ActionRead, Project: listProject},
+		Handler: (*MCPHandler).executeListContexts,
+	},
+	{
+		Name:        "add_context",
+		Description: "Add documentation or context to share with other agents in the project. Supports full markdown formatting for better readability. If connected with project_id and agent_id in URL, those will be used automatically. Other agents can read this context to understand your work.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"project_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The project ID (optional if project_id in MCP connection URL)",
+				},
+				"agent_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Agent ID who creates the context (optional, will use agent_id from URL or first agent)",
+				},
+				"title": map[string]interface{}{
+					"type":        "string",
+					"description": "Context title - make it descriptive so other agents can find it",
+				},
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "Context content in markdown format. Use headings (# ## ###), code blocks (```), lists (- item), bold (**text**), italic (*text*), links ([text](url)), etc. This will be rendered beautifully for other agents to read.",
+				},
+				"tags": map[string]interface{}{
+					"type":        "array",
+					"description": "Tags for categorization",
+					"items":       map[string]interface{}{"type": "string"},
+				},
+			},
+			Required: []string{"title", "content"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"success":     successField,
+			"id":          outputField("string", "New context ID"),
+			"title":       outputField("string", "Context title"),
+			"agent_id":    outputField("string", "Authoring agent ID"),
+			"agent_name":  outputField("string", "Authoring agent name"),
+			"tags":        outputNullable("array", "Context tags"),
+			"preview":     outputField("string", "First 200 characters of the content"),
+			"format":      outputField("string", "Content format"),
+			"created_at":  outputField("string", "Creation time"),
+			"shared_with": messageField,
+		}, "success", "id", "title", "agent_id"),
+		Access:  ToolAccess{Action: auth.ActionWriteContext, Project: argProject("project_id", "agent_id")},
+		Handler: (*MCPHandler).executeAddContext,
+	},
+}
+
+func (h *MCPHandler) executeListContexts(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	pg, err := parsePage("contexts", args)
+	if err != nil {
+		return nil, err
+	}
+	fields := fieldSelection(args)
+
+	scope, err := h.visibleProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT c.id, c.project_id, c.agent_id, a.name as agent_name, c.title, c.content, c.tags, c.created_at 
+	          FROM contexts c 
+	          LEFT JOIN agents a ON c.agent_id = a.id
+	          WHERE 1=1`
+	var queryArgs []interface{}
+
+	if args != nil {
+		if projectID, ok := args["project_id"].(string); ok && projectID != "" {
+			queryArgs = append(queryArgs, projectID)
+			query += fmt.Sprintf(" AND c.project_id = $%d", len(queryArgs))
+		}
+		if agentID, ok := args["agent_id"].(string); ok && agentID != "" {
+			queryArgs = append(queryArgs, agentID)
+			query += fmt.Sprintf(" AND c.agent_id = $%d", len(queryArgs))
+		}
+		if tags := stringList(args["tags"]); len(tags) > 0 {
+			queryArgs = append(queryArgs, pq.Array(tags))
+			query += fmt.Sprintf(" AND c.tags @> $%d::text[]", len(queryArgs))
+		}
+		if text, ok := args["query"].(string); ok && text != "" {
+			queryArgs = append(queryArgs, "%"+text+"%")
+			query += fmt.Sprintf(" AND c.title ILIKE $%d", len(queryArgs))
+		}
+	}
+	query += scope.filter("c.project_id", &queryArgs)
+	query += pg.clause("c.", &queryArgs)
+
+	rows, err := h.db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contexts := []map[string]interface{}{}
+	for rows.Next() {
+		var id, projectID, agentID, title, content string
+		var agentName *string
+		var tags interface{}
+		var createdAt time.Time
+		if err := rows.Scan(&id, &projectID, &agentID, &agentName, &title, &content, &tags, &createdAt); err != nil {
+			continue
+		}
+		if !pg.keep(createdAt, id) {
+			break
+		}
+
+		// Create a preview of the content
+		preview := content
+		if len(preview) > 200 {
+			preview = preview[:200] + "..."
+		}
+
+		agentNameStr := "Unknown"
+		if agentName != nil {
+			agentNameStr = *agentName
+		}
+
+		contexts = append(contexts, selectFields(map[string]interface{}{
+			"id":         id,
+			"project_id": projectID,
+			"agent_id":   agentID,
+			"agent_name": agentNameStr,
+			"title":      title,
+			"content":    content,
+			"preview":    preview,
+			"format":     "markdown",
+			"tags":       tags,
+			"created_at": createdAt,
+		}, fields))
+	}
+
+	return pg.result(map[string]interface{}{
+		"contexts": contexts,
+		"count":    len(contexts),
+		"note":     "Content is in markdown format - render it for best readability",
+	}), nil
+}
+
+func (h *MCPHandler) executeAddContext(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		// Use project_id from context if not provided in args
+		if ctx.ProjectID != "" {
+			projectID = ctx.ProjectID
+		} else {
+			return nil, invalidArgument("project_id is required")
+		}
+	}
+
+	title, ok := args["title"].(string)
+	if !ok {
+		return nil, invalidArgument("title is required")
+	}
+	content, ok := args["content"].(string)
+	if !ok {
+		return nil, invalidArgument("content is required")
+	}
+
+	agentID, _ := args["agent_id"].(string)
+
+	// Use agent_id from context if not provided in args
+	if agentID == "" && ctx.AgentID != "" {
+		agentID = ctx.AgentID
+	}
+
+	// If still no agent_id, try to use the first agent from the project
+	if agentID == "" {
+		err := h.db.QueryRow(`SELECT id FROM agents WHERE project_id = $1 LIMIT 1`, projectID).Scan(&agentID)
+		if err != nil {
+			return nil, invalidArgument("agent_id is required or no agents found in project")
+		}
+	}
+
+	// Convert tags to PostgreSQL array format using pq.Array
+	var tags []string
+	if tagsInterface, ok := args["tags"].([]interface{}); ok {
+		for _, tag := range tagsInterface {
+			if tagStr, ok := tag.(string); ok {
+				tags = append(tags, tagStr)
+			}
+		}
+	}
+
+	id := uuid.New().String()
+	query := `INSERT INTO contexts (id, project_id, agent_id, title, content, tags) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at`
+
+	var createdAt interface{}
+	// Use pq.Array for proper PostgreSQL array handling
+	err := h.db.QueryRow(query, id, projectID, agentID, title, content, pq.Array(tags)).Scan(&createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a preview of the content (first 200 chars)
+	preview := content
+	if len(preview) > 200 {
+		preview = preview[:200] + "..."
+	}
+
+	// Get agent name for better feedback
+	var agentName string
+	h.db.QueryRow(`SELECT name FROM agents WHERE id = $1`, agentID).Scan(&agentName)
+	if agentName == "" {
+		agentName = "Unknown Agent"
+	}
+
+	return map[string]interface{}{
+		"success":     true,
+		"id":          id,
+		"title":       title,
+		"agent_id":    agentID,
+		"agent_name":  agentName,
+		"tags":        tags,
+		"preview":     preview,
+		"format":      "markdown",
+		"created_at":  createdAt,
+		"shared_with": "All agents in the project can now read this context",
+	}, nil
+}
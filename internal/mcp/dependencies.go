@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/taskgraph"
+)
+
+// dependencyTools build and read the task dependency graph
+var dependencyTools = []ToolDefinition{
+	{
+		Name:        "add_dependency",
+		Description: "Make a task wait on another task. The task is blocked until the prerequisite is done and unblocked automatically afterwards.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"task_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The task that has to wait",
+				},
+				"depends_on_task_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The prerequisite task that must be done first",
+				},
+			},
+			Required: []string{"task_id", "depends_on_task_id"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"success":            successField,
+			"dependency":         outputField("object", "The recorded dependency edge"),
+			"blocked":            outputField("boolean", "Whether the task now waits on open prerequisites"),
+			"open_prerequisites": outputNullable("array", "Prerequisites that are not done yet"),
+		}, "success", "dependency", "blocked"),
+		Access:  ToolAccess{Action: auth.ActionUpdateTask, Project: taskOwners("task_id")},
+		Handler: (*MCPHandler).executeAddDependency,
+	},
+	{
+		Name:        "get_task_graph",
+		Description: "Get the task dependency graph of a project: every task as a node and each dependency as an edge",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"project_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional project ID (uses connection URL context if not provided)",
+				},
+			},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"project_id": outputField("string", "Project ID"),
+			"nodes":      outputList("Tasks of the project"),
+			"edges":      outputList("Dependency edges between the tasks"),
+		}, "project_id", "nodes", "edges"),
+		Access:  ToolAccess{Action: auth.ActionRead, Project: argProject("project_id", "")},
+		Handler: (*MCPHandler).executeGetTaskGraph,
+	},
+}
+
+func (h *MCPHandler) executeAddDependency(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil || h.graph == nil {
+		return nil, errNoDatabase
+	}
+
+	taskID, err := uuid.Parse(fmt.Sprint(args["task_id"]))
+	if err != nil {
+		return nil, invalidArgument("task_id must be a valid UUID")
+	}
+	dependsOnID, err := uuid.Parse(fmt.Sprint(args["depends_on_task_id"]))
+	if err != nil {
+		return nil, invalidArgument("depends_on_task_id must be a valid UUID")
+	}
+
+	dep, err := h.graph.AddDependency(context.Background(), taskID, dependsOnID)
+	if errors.Is(err, taskgraph.ErrCycle) {
+		return nil, conflict("Dependency would create a cycle")
+	} else if err != nil {
+		return nil, err
+	}
+
+	open, _ := h.graph.OpenPrerequisites(context.Background(), taskID)
+
+	return map[string]interface{}{
+		"success":            true,
+		"dependency":         dep,
+		"blocked":            len(open) > 0,
+		"open_prerequisites": open,
+	}, nil
+}
+
+func (h *MCPHandler) executeGetTaskGraph(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil || h.graph == nil {
+		return nil, errNoDatabase
+	}
+
+	projectIDStr, _ := args["project_id"].(string)
+	if projectIDStr == "" {
+		projectIDStr = ctx.ProjectID
+	}
+	projectID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		return nil, invalidArgument("project_id is required (pass it or set it in the connection URL)")
+	}
+
+	graph, err := h.graph.ProjectGraph(context.Background(), projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph, nil
+}
+
+// blockedConflict explains a change refused because prerequisites of the
+// task are open, listing them when they can be loaded
+func (h *MCPHandler) blockedConflict(taskID uuid.UUID) *ToolError {
+	blocked := conflict("Task is blocked by unfinished dependencies")
+	if h.graph == nil {
+		return blocked
+	}
+	if open, err := h.graph.OpenPrerequisites(context.Background(), taskID); err == nil && len(open) > 0 {
+		blocked.Details = map[string]interface{}{"open_prerequisites": open}
+	}
+	return blocked
+}
+
+// notifyTaskChanged unblocks or re-blocks dependents after a task's status changed
+func (h *MCPHandler) notifyTaskChanged(taskID string) {
+	id, err := uuid.Parse(taskID)
+	if h.graph == nil || err != nil {
+		return
+	}
+	if err := h.graph.TaskChanged(context.Background(), id); err != nil {
+		log.Printf("Failed to update dependents of task %s: %v", taskID, err)
+	}
+}
@@ -0,0 +1,300 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// errRequestCancelled is the cause of a request context cancelled by a
+// notifications/cancelled from the client
+var errRequestCancelled = errors.New("request cancelled by the client")
+
+// rpcMessage is one message of a POST body or stdio line. err is set when
+// the message is not a valid request or notification, and reply when it is
+// the client's response to a request from the server.
+type rpcMessage struct {
+	req   JSONRPCRequest
+	err   *JSONRPCError
+	reply *rpcReply
+}
+
+// rpcEnvelope decodes any JSON-RPC message: requests and notifications carry
+// a method, responses a result or an error
+type rpcEnvelope struct {
+	JSONRPCRequest
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *JSONRPCError   `json:"error,omitempty"`
+}
+
+// rpcReply is the client's response to a request sent by the server
+type rpcReply struct {
+	result json.RawMessage
+	err    *JSONRPCError
+}
+
+// rpcPayload is what a client sends in one POST or stdio line: a single
+// JSON-RPC message or a JSON-RPC 2.0 batch
+type rpcPayload struct {
+	batch    bool
+	messages []rpcMessage
+}
+
+// parsePayload decodes a single message or a batch. Malformed JSON and empty
+// batches cannot be answered message by message and are returned as an error.
+func parsePayload(data []byte) (*rpcPayload, *JSONRPCError) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, &JSONRPCError{Code: -32700, Message: "Parse error", Data: err.Error()}
+		}
+		if len(raw) == 0 {
+			return nil, &JSONRPCError{Code: -32600, Message: "Invalid Request", Data: "empty batch"}
+		}
+
+		p := &rpcPayload{batch: true}
+		for _, m := range raw {
+			var e rpcEnvelope
+			if err := json.Unmarshal(m, &e); err != nil {
+				p.messages = append(p.messages, rpcMessage{err: &JSONRPCError{Code: -32600, Message: "Invalid Request", Data: err.Error()}})
+				continue
+			}
+			p.messages = append(p.messages, checkMessage(e, true))
+		}
+		return p, nil
+	}
+
+	var e rpcEnvelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, &JSONRPCError{Code: -32700, Message: "Parse error", Data: err.Error()}
+	}
+	return &rpcPayload{messages: []rpcMessage{checkMessage(e, false)}}, nil
+}
+
+func checkMessage(e rpcEnvelope, inBatch bool) rpcMessage {
+	req := e.JSONRPCRequest
+	switch {
+	case req.Method == "" && req.ID != nil && (e.Result != nil || e.Error != nil):
+		return rpcMessage{req: req, reply: &rpcReply{result: e.Result, err: e.Error}}
+	case req.Method == "":
+		return rpcMessage{req: req, err: &JSONRPCError{Code: -32600, Message: "Invalid Request", Data: "method is required"}}
+	case inBatch && req.Method == "initialize":
+		return rpcMessage{req: req, err: &JSONRPCError{Code: -32600, Message: "Invalid Request", Data: "initialize must not be part of a batch"}}
+	}
+	return rpcMessage{req: req}
+}
+
+// method returns the method of a single message, "" for a batch
+func (p *rpcPayload) method() string {
+	if p.batch {
+		return ""
+	}
+	return p.messages[0].req.Method
+}
+
+// expectsReply reports whether any message of the payload is answered:
+// requests are, notifications and responses are not
+func (p *rpcPayload) expectsReply() bool {
+	for _, m := range p.messages {
+		if m.err != nil || (m.req.ID != nil && m.reply == nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// run handles the messages of a payload, those of a batch concurrently, and
+// returns the reply: a response, the responses of a batch as an array, or nil
+// when nothing is to be answered
+func (h *MCPHandler) run(parent context.Context, p *rpcPayload, ctx MCPContext) interface{} {
+	responses := make([]*JSONRPCResponse, len(p.messages))
+
+	var wg sync.WaitGroup
+	for i, m := range p.messages {
+		if m.err != nil {
+			responses[i] = &JSONRPCResponse{JSONRPC: "2.0", ID: m.req.ID, Error: m.err}
+			continue
+		}
+		if m.reply != nil {
+			// Responses to the server's own requests are not answered
+			if ctx.Session != nil {
+				ctx.Session.resolve(requestKey(m.req.ID), m.reply)
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, req JSONRPCRequest) {
+			defer wg.Done()
+			responses[i] = h.handleMessage(parent, req, ctx)
+		}(i, m.req)
+	}
+	wg.Wait()
+
+	replies := []JSONRPCResponse{}
+	for _, resp := range responses {
+		if resp != nil {
+			replies = append(replies, *resp)
+		}
+	}
+	switch {
+	case len(replies) == 0:
+		return nil
+	case !p.batch:
+		return replies[0]
+	}
+	return replies
+}
+
+// handleMessage runs a request and returns its response. Notifications get no
+// response, and neither do requests the client cancelled.
+func (h *MCPHandler) handleMessage(parent context.Context, req JSONRPCRequest, ctx MCPContext) *JSONRPCResponse {
+	if req.ID == nil {
+		h.handleNotification(req, ctx)
+		return nil
+	}
+
+	reqCtx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+	ctx.request = reqCtx
+
+	// Cancellation needs a session to correlate the notification with the
+	// request; initialize itself cannot be cancelled
+	if ctx.Session != nil && req.Method != "initialize" {
+		key := requestKey(req.ID)
+		running := ctx.Session.track(key, cancel)
+		defer ctx.Session.untrack(key, running)
+	}
+
+	resp := h.respond(req, ctx)
+	if context.Cause(reqCtx) == errRequestCancelled {
+		return nil
+	}
+	return &resp
+}
+
+// handleNotification handles a message from the client that expects no reply
+func (h *MCPHandler) handleNotification(req JSONRPCRequest, ctx MCPContext) {
+	switch req.Method {
+	case "notifications/cancelled":
+		var params struct {
+			RequestID interface{} `json:"requestId"`
+			Reason    string      `json:"reason,omitempty"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.RequestID == nil || ctx.Session == nil {
+			return
+		}
+		// The request may already have finished, which is not an error
+		if ctx.Session.cancel(requestKey(params.RequestID)) {
+			log.Printf("MCP request %v cancelled by the client: %s", params.RequestID, params.Reason)
+		}
+	case "initialized", "notifications/initialized":
+		// Client notification that initialization is complete
+	default:
+		// Unknown notifications are ignored
+	}
+}
+
+// requestKey identifies a request ID within a session. The JSON encoding keeps
+// the number 1 and the string "1" apart.
+func requestKey(id interface{}) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
+
+// inflightRequest is a request of a session that is still running
+type inflightRequest struct {
+	cancel context.CancelCauseFunc
+}
+
+// track records a running request so notifications/cancelled can stop it
+func (s *Session) track(key string, cancel context.CancelCauseFunc) *inflightRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inflight == nil {
+		s.inflight = make(map[string]*inflightRequest)
+	}
+	running := &inflightRequest{cancel: cancel}
+	s.inflight[key] = running
+	return running
+}
+
+// untrack forgets a finished request, unless its ID has been reused since
+func (s *Session) untrack(key string, running *inflightRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inflight[key] == running {
+		delete(s.inflight, key)
+	}
+}
+
+// cancel stops a running request and reports whether there was one
+func (s *Session) cancel(key string) bool {
+	s.mu.Lock()
+	running, ok := s.inflight[key]
+	delete(s.inflight, key)
+	s.mu.Unlock()
+
+	if ok {
+		running.cancel(errRequestCancelled)
+	}
+	return ok
+}
+
+// request sends a request to the client on stream and waits for its
+// response. It gives up when ctx is done or the session ends.
+func (s *Session) request(ctx context.Context, stream, method string, params interface{}) (json.RawMessage, error) {
+	s.mu.Lock()
+	s.calls++
+	id := fmt.Sprintf("server-%d", s.calls)
+	key := requestKey(id)
+	if s.waiting == nil {
+		s.waiting = make(map[string]chan *rpcReply)
+	}
+	replies := make(chan *rpcReply, 1)
+	s.waiting[key] = replies
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.waiting, key)
+		s.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode params: %w", err)
+	}
+	if err := s.send(stream, JSONRPCRequest{JSONRPC: "2.0", ID: id, Method: method, Params: data}, false); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-replies:
+		if reply.err != nil {
+			return nil, fmt.Errorf("%s failed: %s (%d)", method, reply.err.Message, reply.err.Code)
+		}
+		return reply.result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.done:
+		return nil, errors.New("session closed")
+	}
+}
+
+// resolve hands the client's response to the request waiting for it.
+// Responses nobody waits for any more are dropped.
+func (s *Session) resolve(key string, reply *rpcReply) {
+	s.mu.Lock()
+	replies, ok := s.waiting[key]
+	delete(s.waiting, key)
+	s.mu.Unlock()
+
+	if ok {
+		replies <- reply
+	}
+}
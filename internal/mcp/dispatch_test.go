@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantErr   int
+		batch     bool
+		messages  int
+		invalid   int
+		wantReply bool
+	}{
+		{"request", `{"jsonrpc":"2.0","id":1,"method":"ping"}`, 0, false, 1, 0, true},
+		{"notification", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, 0, false, 1, 0, false},
+		{"batch", `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"}]`, 0, true, 2, 0, true},
+		{"notification batch", ` [{"jsonrpc":"2.0","method":"notifications/initialized"}]`, 0, true, 1, 0, false},
+		{"invalid batch entries", `[1,{"jsonrpc":"2.0","id":2},{"jsonrpc":"2.0","id":3,"method":"initialize"}]`, 0, true, 3, 3, true},
+		{"response", `{"jsonrpc":"2.0","id":"server-1","result":{"action":"accept"}}`, 0, false, 1, 0, false},
+		{"error response", `{"jsonrpc":"2.0","id":"server-2","error":{"code":-32601,"message":"Method not found"}}`, 0, false, 1, 0, false},
+		{"empty batch", `[]`, -32600, false, 0, 0, false},
+		{"malformed", `{"jsonrpc":`, -32700, false, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		p, rpcErr := parsePayload([]byte(tt.body))
+		if tt.wantErr != 0 {
+			if rpcErr == nil || rpcErr.Code != tt.wantErr {
+				t.Errorf("%s: expected error %d, got %+v", tt.name, tt.wantErr, rpcErr)
+			}
+			continue
+		}
+		if rpcErr != nil {
+			t.Fatalf("%s: unexpected error %+v", tt.name, rpcErr)
+		}
+
+		invalid := 0
+		for _, m := range p.messages {
+			if m.err != nil {
+				invalid++
+			}
+		}
+		if p.batch != tt.batch || len(p.messages) != tt.messages || invalid != tt.invalid || p.expectsReply() != tt.wantReply {
+			t.Errorf("%s: got batch=%v messages=%d invalid=%d reply=%v", tt.name, p.batch, len(p.messages), invalid, p.expectsReply())
+		}
+	}
+}
+
+func TestHandleJSONRPCBatch(t *testing.T) {
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	body := `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":"b","method":"nope"}]`
+	w := httptest.NewRecorder()
+	h.HandleMCP(w, httptest.NewRequest("POST", "/mcp", strings.NewReader(body)))
+
+	var replies []JSONRPCResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &replies); err != nil {
+		t.Fatalf("Expected an array of responses, got %s", w.Body.String())
+	}
+	if len(replies) != 2 || replies[0].ID != 1.0 || replies[1].ID != "b" || replies[1].Error == nil || replies[1].Error.Code != -32601 {
+		t.Errorf("Expected responses to requests 1 and b only, in order, got %+v", replies)
+	}
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`[{"jsonrpc":"2.0","method":"initialized"},{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":9}}]`,
+	} {
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, httptest.NewRequest("POST", "/mcp", strings.NewReader(body)))
+		if w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+			t.Errorf("Expected 202 without a body for %s, got %d %q", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	started := make(chan struct{})
+	h.Tools().Register(ToolDefinition{
+		Name:   "wait",
+		Access: ToolAccess{Global: true},
+		Handler: func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+			close(started)
+			select {
+			case <-ctx.Context().Done():
+				return nil, unavailable("cancelled")
+			case <-time.After(5 * time.Second):
+				return map[string]interface{}{"waited": true}, nil
+			}
+		},
+	})
+
+	session := newSession(MCPContext{})
+	ctx := session.context(MCPContext{})
+	call, _ := parsePayload([]byte(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"wait"}}`))
+	cancel, _ := parsePayload([]byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user abort"}}`))
+
+	done := make(chan interface{})
+	go func() { done <- h.run(context.Background(), call, ctx) }()
+
+	<-started
+	if reply := h.run(context.Background(), cancel, ctx); reply != nil {
+		t.Errorf("Expected no reply to a notification, got %+v", reply)
+	}
+
+	select {
+	case reply := <-done:
+		if reply != nil {
+			t.Errorf("Expected a cancelled request to get no response, got %+v", reply)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the tool to stop when its request was cancelled")
+	}
+	if session.cancel(requestKey(7.0)) {
+		t.Error("Expected the finished request to be forgotten")
+	}
+}
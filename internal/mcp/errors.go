@@ -0,0 +1,167 @@
+package mcp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/comments"
+	"github.com/techbuzzz/agent-shaker/internal/lease"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/presence"
+	"github.com/techbuzzz/agent-shaker/internal/subtasks"
+	"github.com/techbuzzz/agent-shaker/internal/taskgraph"
+	"github.com/techbuzzz/agent-shaker/internal/taskstate"
+)
+
+// ToolErrorCode classifies why a tool call failed
+type ToolErrorCode string
+
+const (
+	// CodeInvalidArgument means the arguments were missing or malformed
+	CodeInvalidArgument ToolErrorCode = "invalid_argument"
+	// CodeNotConfigured means the connection URL lacks the project or agent the tool needs
+	CodeNotConfigured ToolErrorCode = "not_configured"
+	// CodeNotFound means a referenced project, agent, task or comment does not exist
+	CodeNotFound ToolErrorCode = "not_found"
+	// CodePermissionDenied means the caller's project role does not allow the call
+	CodePermissionDenied ToolErrorCode = "permission_denied"
+	// CodeConflict means the call is valid but the current state does not allow it
+	CodeConflict ToolErrorCode = "conflict"
+	// CodeUnavailable means a backing service (database, remote agent) is unreachable
+	CodeUnavailable ToolErrorCode = "unavailable"
+	// CodeInternal is any other failure
+	CodeInternal ToolErrorCode = "internal"
+)
+
+// ToolError is the typed error of a tool call. It is returned to the client as
+// structuredContent and, serialized, as the text content, so the message is
+// always valid JSON. Message keeps the "error" key older clients read.
+type ToolError struct {
+	Code    ToolErrorCode          `json:"code"`
+	Message string                 `json:"error"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *ToolError) Error() string {
+	return e.Message
+}
+
+var (
+	errNoDatabase = &ToolError{Code: CodeUnavailable, Message: "Database not connected"}
+	errNoAgent    = &ToolError{Code: CodeNotConfigured, Message: "No agent_id configured in MCP connection URL. Add ?agent_id=UUID to the URL."}
+	errNoProject  = &ToolError{Code: CodeNotConfigured, Message: "No project_id configured in MCP connection URL. Add ?project_id=UUID to the URL."}
+)
+
+func invalidArgument(format string, args ...interface{}) *ToolError {
+	return &ToolError{Code: CodeInvalidArgument, Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) *ToolError {
+	return &ToolError{Code: CodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func conflict(format string, args ...interface{}) *ToolError {
+	return &ToolError{Code: CodeConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+func unavailable(format string, args ...interface{}) *ToolError {
+	return &ToolError{Code: CodeUnavailable, Message: fmt.Sprintf(format, args...)}
+}
+
+// notFoundErrors are the service errors reported as not_found
+var notFoundErrors = []error{
+	sql.ErrNoRows,
+	taskstate.ErrTaskNotFound,
+	taskstate.ErrAgentNotFound,
+	lease.ErrTaskNotFound,
+	comments.ErrTaskNotFound,
+	comments.ErrCommentNotFound,
+	comments.ErrParentNotFound,
+	comments.ErrAgentNotFound,
+	subtasks.ErrTaskNotFound,
+	subtasks.ErrParentNotFound,
+	subtasks.ErrAgentNotFound,
+	taskgraph.ErrTaskNotFound,
+	taskgraph.ErrDependencyNotFound,
+	presence.ErrAgentNotFound,
+}
+
+// conflictErrors are the service errors reported as conflict
+var conflictErrors = []error{
+	lease.ErrAlreadyClaimed,
+	lease.ErrNotClaimable,
+	taskstate.ErrBlocked,
+	taskgraph.ErrCycle,
+	taskgraph.ErrSelfDependency,
+	taskgraph.ErrCrossProject,
+	subtasks.ErrCrossProject,
+	subtasks.ErrHasSubtasks,
+}
+
+// asToolError classifies err. Errors that already are a ToolError are kept,
+// well-known service and database errors get their matching code, and
+// everything else is internal.
+func asToolError(err error) *ToolError {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr
+	}
+
+	for _, target := range notFoundErrors {
+		if errors.Is(err, target) {
+			return &ToolError{Code: CodeNotFound, Message: err.Error()}
+		}
+	}
+	for _, target := range conflictErrors {
+		if errors.Is(err, target) {
+			return &ToolError{Code: CodeConflict, Message: err.Error()}
+		}
+	}
+
+	var transitionErr *models.TransitionError
+	if errors.As(err, &transitionErr) {
+		return &ToolError{Code: CodeConflict, Message: err.Error()}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23503":
+			return &ToolError{Code: CodeNotFound, Message: "referenced row does not exist: " + pqErr.Message}
+		case "23505":
+			return &ToolError{Code: CodeConflict, Message: pqErr.Message}
+		case "22P02":
+			return &ToolError{Code: CodeInvalidArgument, Message: pqErr.Message}
+		}
+	}
+
+	return &ToolError{Code: CodeInternal, Message: err.Error()}
+}
+
+// toolResult wraps the value or error of a tool as a tools/call result
+func toolResult(value interface{}, err error) ToolResult {
+	if err != nil {
+		toolErr := asToolError(err)
+		return ToolResult{
+			Content:           []ToolResultContent{{Type: "text", Text: marshalText(toolErr)}},
+			StructuredContent: toolErr,
+			IsError:           true,
+		}
+	}
+	return ToolResult{
+		Content:           []ToolResultContent{{Type: "text", Text: marshalText(value)}},
+		StructuredContent: value,
+	}
+}
+
+// marshalText renders a tool result as indented JSON for the text content
+func marshalText(v interface{}) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		data, _ = json.Marshal(&ToolError{Code: CodeInternal, Message: err.Error()})
+	}
+	return string(data)
+}
@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/techbuzzz/agent-shaker/internal/lease"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+func TestAsToolError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ToolErrorCode
+	}{
+		{invalidArgument("task_id is required"), CodeInvalidArgument},
+		{errNoAgent, CodeNotConfigured},
+		{fmt.Errorf("Task not found: %w", sql.ErrNoRows), CodeNotFound},
+		{lease.ErrAlreadyClaimed, CodeConflict},
+		{&models.TransitionError{From: models.StatusDone, To: models.StatusPending}, CodeConflict},
+		{errors.New("connection reset"), CodeInternal},
+	}
+
+	for _, tt := range tests {
+		if got := asToolError(tt.err); got.Code != tt.want {
+			t.Errorf("asToolError(%v).Code = %q, want %q", tt.err, got.Code, tt.want)
+		}
+	}
+}
+
+func TestToolResultErrorIsValidJSON(t *testing.T) {
+	result := toolResult(nil, invalidArgument(`title "x" is not allowed`))
+	if !result.IsError {
+		t.Fatal("error result is not flagged as an error")
+	}
+
+	var decoded ToolError
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &decoded); err != nil {
+		t.Fatalf("error text is not valid JSON: %v", err)
+	}
+	if decoded.Code != CodeInvalidArgument || decoded.Message != `title "x" is not allowed` {
+		t.Errorf("decoded error = %+v", decoded)
+	}
+	if _, ok := result.StructuredContent.(*ToolError); !ok {
+		t.Errorf("structuredContent = %T, want *ToolError", result.StructuredContent)
+	}
+}
+
+func TestEveryToolDeclaresAnOutputSchema(t *testing.T) {
+	for _, def := range builtinTools() {
+		schema := def.OutputSchema
+		if schema == nil {
+			t.Errorf("tool %s has no output schema", def.Name)
+			continue
+		}
+		for _, field := range schema.Required {
+			if _, ok := schema.Properties[field]; !ok {
+				t.Errorf("tool %s requires undeclared field %s", def.Name, field)
+			}
+		}
+	}
+}
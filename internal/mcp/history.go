@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+)
+
+// historyTools read the task audit trail
+var historyTools = []ToolDefinition{
+	{
+		Name:        "get_task_history",
+		Description: "Get the audit trail of a task: every status change, claim, reassignment and lease expiry with who made it and when, oldest first",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"task_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The task ID (history remains available after the task is deleted)",
+				},
+			},
+			Required: []string{"task_id"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"task_id": outputField("string", "Task ID"),
+			"events":  outputList("History events, oldest first"),
+		}, "task_id", "events"),
+		Access:  ToolAccess{Action: auth.ActionRead, Project: historyProject},
+		Handler: (*MCPHandler).executeGetTaskHistory,
+	},
+}
+
+// toolActor attributes changes made through MCP to the authenticated caller,
+// falling back to the agent named in the connection URL
+func toolActor(ctx MCPContext) history.Actor {
+	if ctx.Principal != nil {
+		return history.ActorFromPrincipal(ctx.Principal)
+	}
+	if agentID, err := uuid.Parse(ctx.AgentID); err == nil {
+		return history.AgentActor(agentID)
+	}
+	return history.Actor{}
+}
+
+func (h *MCPHandler) executeGetTaskHistory(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	taskID, err := uuid.Parse(fmt.Sprint(args["task_id"]))
+	if err != nil {
+		return nil, invalidArgument("task_id must be a valid UUID")
+	}
+
+	events, err := history.List(context.Background(), h.db, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, notFound("No history found for task")
+	}
+
+	return map[string]interface{}{
+		"task_id": taskID,
+		"events":  events,
+	}, nil
+}
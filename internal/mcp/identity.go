@@ -0,0 +1,367 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/presence"
+)
+
+// identityTools are the context-aware tools that act as the connection's own agent
+var identityTools = []ToolDefinition{
+	{
+		Name:        "get_my_identity",
+		Description: "Get the current agent's identity and assigned project based on MCP connection configuration",
+		InputSchema: InputSchema{
+			Type:       "object",
+			Properties: map[string]interface{}{},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"configured":    outputField("boolean", "Whether the connection URL names a project or agent"),
+			"authenticated": outputField("boolean", "Whether the call carried an API key"),
+			"project_id":    outputField("string", "Configured project ID"),
+			"agent_id":      outputField("string", "Configured agent ID"),
+			"project":       outputField("object", "Name, description and status of the configured project"),
+			"agent":         outputField("object", "Name, role, status and project of the configured agent"),
+			"message":       messageField,
+		}, "configured", "authenticated"),
+		Access:  ToolAccess{Global: true},
+		Handler: (*MCPHandler).executeGetMyIdentity,
+	},
+	{
+		Name:        "get_my_project",
+		Description: "Get details of the project assigned to this MCP connection (requires project_id in connection URL)",
+		InputSchema: InputSchema{
+			Type:       "object",
+			Properties: map[string]interface{}{},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"id":          outputField("string", "Project ID"),
+			"name":        outputField("string", "Project name"),
+			"description": outputField("string", "Project description"),
+			"status":      outputField("string", "Project status"),
+			"created_at":  outputField("string", "Creation time"),
+			"updated_at":  outputField("string", "Last update time"),
+			"agents":      outputField("integer", "Number of agents in the project"),
+			"tasks":       outputField("object", "Task counts by status, plus overdue and total"),
+		}, "id", "name", "status", "agents", "tasks"),
+		Requires: RequiresProject,
+		Access:   ToolAccess{Action: auth.ActionRead, Project: connectionProject},
+		Handler:  (*MCPHandler).executeGetMyProject,
+	},
+	{
+		Name:        "get_my_tasks",
+		Description: "Get tasks assigned to the current agent (requires agent_id in connection URL)",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"status": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional status filter (pending, in_progress, blocked, done, failed, cancelled)",
+				},
+			},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"agent_id": outputField("string", "The agent the tasks are assigned to"),
+			"count":    countField,
+			"tasks":    outputList("Tasks assigned to the agent, newest first"),
+		}, "agent_id", "count", "tasks"),
+		Requires: RequiresAgent,
+		Access:   ToolAccess{Action: auth.ActionRead, Project: connectionProject},
+		Handler:  (*MCPHandler).executeGetMyTasks,
+	},
+	{
+		Name:        "update_my_status",
+		Description: "Update the current agent's status (requires agent_id in connection URL)",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"status": map[string]interface{}{
+					"type":        "string",
+					"description": "New status: idle, working, blocked, offline",
+					"enum":        []string{"idle", "working", "blocked", "offline"},
+				},
+			},
+			Required: []string{"status"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"success":  successField,
+			"agent_id": outputField("string", "Agent ID"),
+			"status":   outputField("string", "The recorded status"),
+			"message":  messageField,
+		}, "success", "agent_id", "status"),
+		Requires: RequiresAgent,
+		Access:   ToolAccess{Action: auth.ActionUpdateAgent, Project: connectionProject},
+		Handler:  (*MCPHandler).executeUpdateMyStatus,
+	},
+	{
+		Name:        "heartbeat",
+		Description: "Record a heartbeat and renew the leases on all tasks you have claimed (requires agent_id in connection URL)",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"status": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional status to record with the heartbeat (default: active)",
+				},
+			},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"success":        successField,
+			"agent_id":       outputField("string", "Agent ID"),
+			"leases_renewed": outputField("integer", "Number of task leases extended"),
+			"lease_duration": outputField("string", "How long each renewed lease lasts"),
+		}, "success", "agent_id", "leases_renewed", "lease_duration"),
+		Requires: RequiresAgent,
+		Access:   ToolAccess{Action: auth.ActionUpdateAgent, Project: connectionProject},
+		Handler:  (*MCPHandler).executeHeartbeat,
+	},
+}
+
+func (h *MCPHandler) executeGetMyIdentity(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	identity := map[string]interface{}{
+		"configured":    ctx.ProjectID != "" || ctx.AgentID != "",
+		"authenticated": ctx.Principal != nil,
+	}
+
+	if ctx.ProjectID != "" {
+		identity["project_id"] = ctx.ProjectID
+		// Fetch project details
+		if h.db != nil {
+			var name, description, status string
+			err := h.db.QueryRow("SELECT name, description, status FROM projects WHERE id = $1", ctx.ProjectID).
+				Scan(&name, &description, &status)
+			if err == nil {
+				identity["project"] = map[string]string{
+					"name":        name,
+					"description": description,
+					"status":      status,
+				}
+			}
+		}
+	}
+
+	if ctx.AgentID != "" {
+		identity["agent_id"] = ctx.AgentID
+		// Fetch agent details
+		if h.db != nil {
+			var name, role, status string
+			var projectID interface{}
+			err := h.db.QueryRow("SELECT name, role, status, project_id FROM agents WHERE id = $1", ctx.AgentID).
+				Scan(&name, &role, &status, &projectID)
+			if err == nil {
+				identity["agent"] = map[string]interface{}{
+					"name":       name,
+					"role":       role,
+					"status":     status,
+					"project_id": projectID,
+				}
+			}
+		}
+	}
+
+	if !identity["configured"].(bool) {
+		identity["message"] = "No project_id or agent_id configured in MCP connection URL. Add ?project_id=UUID&agent_id=UUID to the URL."
+	}
+
+	return identity, nil
+}
+
+func (h *MCPHandler) executeGetMyProject(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	var id, name, description, status string
+	var createdAt, updatedAt interface{}
+	err := h.db.QueryRow("SELECT id, name, description, status, created_at, updated_at FROM projects WHERE id = $1", ctx.ProjectID).
+		Scan(&id, &name, &description, &status, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("Project not found: %w", err)
+	}
+
+	// Get agents count
+	var agentCount int
+	h.db.QueryRow("SELECT COUNT(*) FROM agents WHERE project_id = $1", ctx.ProjectID).Scan(&agentCount)
+
+	// Get tasks summary
+	var pendingTasks, inProgressTasks, doneTasks, blockedTasks, failedTasks, cancelledTasks, overdueTasks int
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE project_id = $1 AND status = 'pending'", ctx.ProjectID).Scan(&pendingTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE project_id = $1 AND status = 'in_progress'", ctx.ProjectID).Scan(&inProgressTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE project_id = $1 AND status = 'done'", ctx.ProjectID).Scan(&doneTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE project_id = $1 AND status = 'blocked'", ctx.ProjectID).Scan(&blockedTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE project_id = $1 AND status = 'failed'", ctx.ProjectID).Scan(&failedTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE project_id = $1 AND status = 'cancelled'", ctx.ProjectID).Scan(&cancelledTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE project_id = $1 AND overdue_at IS NOT NULL AND status IN ('pending', 'in_progress', 'blocked')", ctx.ProjectID).Scan(&overdueTasks)
+
+	return map[string]interface{}{
+		"id":          id,
+		"name":        name,
+		"description": description,
+		"status":      status,
+		"created_at":  createdAt,
+		"updated_at":  updatedAt,
+		"agents":      agentCount,
+		"tasks": map[string]int{
+			"pending":     pendingTasks,
+			"in_progress": inProgressTasks,
+			"done":        doneTasks,
+			"blocked":     blockedTasks,
+			"failed":      failedTasks,
+			"cancelled":   cancelledTasks,
+			"overdue":     overdueTasks,
+			"total":       pendingTasks + inProgressTasks + doneTasks + blockedTasks + failedTasks + cancelledTasks,
+		},
+	}, nil
+}
+
+func (h *MCPHandler) executeGetMyTasks(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	query := "SELECT id, project_id, title, description, status, priority, assigned_to, created_at, updated_at FROM tasks WHERE assigned_to = $1"
+	queryArgs := []interface{}{ctx.AgentID}
+
+	if args != nil {
+		if status, ok := args["status"].(string); ok && status != "" {
+			query += " AND status = $2"
+			queryArgs = append(queryArgs, status)
+		}
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := h.db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []map[string]interface{}{}
+	for rows.Next() {
+		var id, projectID, title, status, priority string
+		var description, assignedTo interface{}
+		var createdAt, updatedAt interface{}
+		if err := rows.Scan(&id, &projectID, &title, &description, &status, &priority, &assignedTo, &createdAt, &updatedAt); err != nil {
+			continue
+		}
+		tasks = append(tasks, map[string]interface{}{
+			"id":          id,
+			"project_id":  projectID,
+			"title":       title,
+			"description": description,
+			"status":      status,
+			"priority":    priority,
+			"assigned_to": assignedTo,
+			"created_at":  createdAt,
+			"updated_at":  updatedAt,
+		})
+	}
+
+	return map[string]interface{}{
+		"agent_id": ctx.AgentID,
+		"count":    len(tasks),
+		"tasks":    tasks,
+	}, nil
+}
+
+func (h *MCPHandler) executeUpdateMyStatus(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	status, ok := args["status"].(string)
+	if !ok {
+		return nil, invalidArgument("status is required (idle, working, blocked, offline)")
+	}
+
+	validStatuses := map[string]bool{"idle": true, "working": true, "blocked": true, "offline": true}
+	if !validStatuses[status] {
+		return nil, invalidArgument("Invalid status. Must be one of: idle, working, blocked, offline")
+	}
+
+	agentID, err := uuid.Parse(ctx.AgentID)
+	if err != nil {
+		return nil, invalidArgument("agent_id must be a valid UUID")
+	}
+	err = h.presence.Set(context.Background(), agentID, status)
+	if errors.Is(err, presence.ErrAgentNotFound) {
+		return nil, notFound("Agent not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Retrieve updated agent information
+	var agent models.Agent
+	err = h.db.QueryRow(`
+		SELECT id, project_id, name, role, team, status, last_seen, created_at
+		FROM agents
+		WHERE id = $1
+	`, ctx.AgentID).Scan(&agent.ID, &agent.ProjectID, &agent.Name, &agent.Role, &agent.Team, &agent.Status, &agent.LastSeen, &agent.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve updated agent: %w", err)
+	}
+
+	// Broadcast agent update to project subscribers via WebSocket
+	if h.hub != nil {
+		h.hub.BroadcastToProject(agent.ProjectID, "agent_update", agent)
+	}
+
+	// A status update counts as a heartbeat for task leases
+	if h.leases != nil {
+		if _, err := h.leases.Renew(context.Background(), agent.ID); err != nil {
+			log.Printf("Failed to renew leases for agent %s: %v", agent.ID, err)
+		}
+	}
+
+	return map[string]interface{}{
+		"success":  true,
+		"agent_id": ctx.AgentID,
+		"status":   status,
+		"message":  "Agent status updated and broadcasted to project",
+	}, nil
+}
+
+func (h *MCPHandler) executeHeartbeat(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil || h.leases == nil {
+		return nil, errNoDatabase
+	}
+
+	agentID, err := uuid.Parse(ctx.AgentID)
+	if err != nil {
+		return nil, invalidArgument("agent_id must be a valid UUID")
+	}
+
+	status, _ := args["status"].(string)
+	if status == "" {
+		status = "active"
+	}
+
+	_, err = h.db.Exec(`
+		INSERT INTO agent_heartbeats (id, agent_id, heartbeat_time, status)
+		VALUES ($1, $2, NOW(), $3)
+	`, uuid.New(), agentID, status)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to record heartbeat: %w", err)
+	}
+	if err := h.presence.Seen(context.Background(), agentID, status); err != nil {
+		log.Printf("Failed to update presence of agent %s: %v", agentID, err)
+	}
+
+	renewed, err := h.leases.Renew(context.Background(), agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"success":        true,
+		"agent_id":       ctx.AgentID,
+		"leases_renewed": renewed,
+		"lease_duration": h.leases.Duration().String(),
+	}, nil
+}
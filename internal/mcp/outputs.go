@@ -0,0 +1,32 @@
+package mcp
+
+// Output schemas describe the structuredContent each tool returns on success.
+// They list the top-level fields clients can rely on; nested entities are
+// described loosely so the models can grow without breaking the schemas.
+
+func outputSchema(properties map[string]interface{}, required ...string) *OutputSchema {
+	return &OutputSchema{Type: "object", Properties: properties, Required: required}
+}
+
+func outputField(typ, description string) map[string]interface{} {
+	return map[string]interface{}{"type": typ, "description": description}
+}
+
+func outputList(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"description": description,
+		"items":       map[string]interface{}{"type": "object"},
+	}
+}
+
+// outputNullable is a field that may be null, such as an unassigned task's assignee
+func outputNullable(typ, description string) map[string]interface{} {
+	return map[string]interface{}{"type": []string{typ, "null"}, "description": description}
+}
+
+var (
+	successField = outputField("boolean", "Always true on success")
+	messageField = outputField("string", "Human readable summary")
+	countField   = outputField("integer", "Number of items returned")
+)
@@ -0,0 +1,174 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultPageSize is how many items a list tool returns without a limit
+	defaultPageSize = 50
+	// maxPageSize bounds the limit argument of list tools
+	maxPageSize = 200
+	// resourcePageSize is how many resources one resources/list page holds
+	resourcePageSize = 100
+)
+
+// Input schema properties shared by the list tools
+var (
+	cursorArgument = map[string]interface{}{
+		"type":        "string",
+		"description": "nextCursor from the previous page, to continue the list",
+	}
+	limitArgument = map[string]interface{}{
+		"type":        "integer",
+		"description": fmt.Sprintf("Maximum number of items to return (default %d, max %d)", defaultPageSize, maxPageSize),
+	}
+	nextCursorField = outputField("string", "Cursor for the next page, absent on the last page")
+)
+
+// fieldsArgument is the input schema of a fields argument choosing which of
+// the given item fields a list tool returns
+func fieldsArgument(names ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string", "enum": names},
+		"description": "Only return these fields of each item, for compact results (id is always included)",
+	}
+}
+
+// cursor is the position a page ends at. It is sent to clients base64
+// encoded and must be treated by them as opaque.
+type cursor struct {
+	// Kind is the list the cursor belongs to
+	Kind string `json:"k"`
+	// CreatedAt and ID are the last item of a list ordered newest first
+	CreatedAt time.Time `json:"t,omitempty"`
+	ID        string    `json:"id,omitempty"`
+	// Offset is the position in lists that are not kept in the database
+	Offset int `json:"o,omitempty"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(kind, s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalidArgument("cursor is invalid")
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Kind != kind {
+		return nil, invalidArgument("cursor is invalid")
+	}
+	return &c, nil
+}
+
+// page is one page of a list tool whose rows are ordered newest first by
+// created_at and then id. Pages continue after the last row of the previous
+// one, so rows added in the meantime do not shift them.
+type page struct {
+	kind  string
+	limit int
+	after *cursor
+	// kept counts rows on the page; more is set when a row did not fit
+	kept int
+	more bool
+	last cursor
+}
+
+// parsePage reads the cursor and limit arguments of a list tool. Callers
+// inside the server, such as resource reads, pass nil args and get every row.
+func parsePage(kind string, args map[string]interface{}) (*page, error) {
+	p := &page{kind: kind}
+	if args == nil {
+		return p, nil
+	}
+
+	p.limit = defaultPageSize
+	if limit, ok := args["limit"].(float64); ok {
+		if limit < 1 || limit > maxPageSize {
+			return nil, invalidArgument("limit must be between 1 and %d", maxPageSize)
+		}
+		p.limit = int(limit)
+	}
+	if s, ok := args["cursor"].(string); ok && s != "" {
+		c, err := decodeCursor(kind, s)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := uuid.Parse(c.ID); err != nil {
+			return nil, invalidArgument("cursor is invalid")
+		}
+		p.after = c
+	}
+	return p, nil
+}
+
+// clause continues a query whose rows are in table alias prefix ("" or "c.")
+// with the page condition, the newest-first order and the limit
+func (p *page) clause(prefix string, queryArgs *[]interface{}) string {
+	var sql string
+	if p.after != nil {
+		*queryArgs = append(*queryArgs, p.after.CreatedAt, p.after.ID)
+		sql = fmt.Sprintf(" AND (%[1]screated_at, %[1]sid) < ($%[2]d, $%[3]d)", prefix, len(*queryArgs)-1, len(*queryArgs))
+	}
+	sql += fmt.Sprintf(" ORDER BY %[1]screated_at DESC, %[1]sid DESC", prefix)
+	if p.limit > 0 {
+		// One row more than the page tells whether another page follows
+		sql += fmt.Sprintf(" LIMIT %d", p.limit+1)
+	}
+	return sql
+}
+
+// keep records a row and reports whether it belongs on the page
+func (p *page) keep(createdAt time.Time, id string) bool {
+	if p.limit > 0 && p.kept == p.limit {
+		p.more = true
+		return false
+	}
+	p.kept++
+	p.last = cursor{Kind: p.kind, CreatedAt: createdAt, ID: id}
+	return true
+}
+
+// result finishes a list tool result with the page's nextCursor
+func (p *page) result(result map[string]interface{}) map[string]interface{} {
+	if p.more {
+		result["nextCursor"] = p.last.encode()
+	}
+	return result
+}
+
+// fieldSelection reads the fields argument of a list tool
+func fieldSelection(args map[string]interface{}) map[string]bool {
+	names := stringList(args["fields"])
+	if len(names) == 0 {
+		return nil
+	}
+	fields := map[string]bool{"id": true}
+	for _, name := range names {
+		fields[name] = true
+	}
+	return fields
+}
+
+// selectFields drops the fields of an item that were not asked for. A nil
+// selection keeps them all.
+func selectFields(item map[string]interface{}, fields map[string]bool) map[string]interface{} {
+	if fields == nil {
+		return item
+	}
+	for name := range item {
+		if !fields[name] {
+			delete(item, name)
+		}
+	}
+	return item
+}
@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParsePage(t *testing.T) {
+	tasksCursor := cursor{Kind: "tasks", CreatedAt: time.Now(), ID: uuid.New().String()}.encode()
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantErr   bool
+		wantLimit int
+		wantAfter bool
+	}{
+		{"internal callers get every row", nil, false, 0, false},
+		{"default limit", map[string]interface{}{}, false, defaultPageSize, false},
+		{"explicit limit", map[string]interface{}{"limit": 10.0}, false, 10, false},
+		{"limit too small", map[string]interface{}{"limit": 0.0}, true, 0, false},
+		{"limit too large", map[string]interface{}{"limit": float64(maxPageSize + 1)}, true, 0, false},
+		{"cursor", map[string]interface{}{"cursor": tasksCursor}, false, defaultPageSize, true},
+		{"garbage cursor", map[string]interface{}{"cursor": "%%%"}, true, 0, false},
+		{"cursor without a row ID", map[string]interface{}{"cursor": cursor{Kind: "tasks", ID: "x"}.encode()}, true, 0, false},
+		{"cursor of another list", map[string]interface{}{"cursor": cursor{Kind: "agents"}.encode()}, true, 0, false},
+	}
+
+	for _, tt := range tests {
+		p, err := parsePage("tasks", tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: parsePage() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if asToolError(err).Code != CodeInvalidArgument {
+				t.Errorf("%s: expected invalid_argument, got %v", tt.name, asToolError(err).Code)
+			}
+			continue
+		}
+		if p.limit != tt.wantLimit || (p.after != nil) != tt.wantAfter {
+			t.Errorf("%s: got limit %d, after %v", tt.name, p.limit, p.after)
+		}
+	}
+}
+
+func TestPageClause(t *testing.T) {
+	p, _ := parsePage("contexts", map[string]interface{}{"limit": 2.0})
+	args := []interface{}{"project"}
+	if got := p.clause("c.", &args); got != " ORDER BY c.created_at DESC, c.id DESC LIMIT 3" {
+		t.Errorf("Unexpected first page clause %q", got)
+	}
+
+	now := time.Now().UTC()
+	p.after = &cursor{Kind: "contexts", CreatedAt: now, ID: "x"}
+	got := p.clause("c.", &args)
+	if got != " AND (c.created_at, c.id) < ($2, $3) ORDER BY c.created_at DESC, c.id DESC LIMIT 3" {
+		t.Errorf("Unexpected next page clause %q", got)
+	}
+	if len(args) != 3 || args[2] != "x" {
+		t.Errorf("Expected the cursor position to be appended to the query arguments, got %v", args)
+	}
+}
+
+func TestPageNextCursor(t *testing.T) {
+	p, _ := parsePage("tasks", map[string]interface{}{"limit": 2.0})
+	base := time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC)
+
+	kept := 0
+	for i, id := range []string{"c", "b", "a"} {
+		if !p.keep(base.Add(-time.Duration(i)*time.Minute), id) {
+			break
+		}
+		kept++
+	}
+	if kept != 2 {
+		t.Fatalf("Expected 2 rows on the page, got %d", kept)
+	}
+
+	result := p.result(map[string]interface{}{})
+	next, ok := result["nextCursor"].(string)
+	if !ok {
+		t.Fatal("Expected a nextCursor when a row did not fit")
+	}
+	c, err := decodeCursor("tasks", next)
+	if err != nil || c.ID != "b" || !c.CreatedAt.Equal(base.Add(-time.Minute)) {
+		t.Errorf("Expected the cursor to point after b, got %+v (%v)", c, err)
+	}
+
+	last, _ := parsePage("tasks", map[string]interface{}{"limit": 2.0})
+	last.keep(base, "a")
+	if _, ok := last.result(map[string]interface{}{})["nextCursor"]; ok {
+		t.Error("Expected no nextCursor on the last page")
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	fields := fieldSelection(map[string]interface{}{"fields": []interface{}{"title"}})
+	item := selectFields(map[string]interface{}{"id": "1", "title": "t", "content": "long"}, fields)
+	if len(item) != 2 || item["id"] != "1" || item["title"] != "t" {
+		t.Errorf("Expected only id and title, got %v", item)
+	}
+
+	if item := selectFields(map[string]interface{}{"id": "1", "content": "long"}, fieldSelection(map[string]interface{}{})); len(item) != 2 {
+		t.Errorf("Expected every field without a selection, got %v", item)
+	}
+
+	def, _ := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil).Tools().Lookup("list_contexts")
+	if err := validateArguments(def.InputSchema, map[string]interface{}{"fields": []interface{}{"secret"}}); err == nil {
+		t.Error("Expected an unknown field to be rejected")
+	}
+}
+
+func TestResourcesListPagination(t *testing.T) {
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	result, rpcErr := h.handleResourcesList(nil, MCPContext{})
+	if rpcErr != nil {
+		t.Fatalf("resources/list failed: %v", rpcErr.Message)
+	}
+	if list := result.(ResourcesListResult); len(list.Resources) != 4 || list.NextCursor != "" {
+		t.Errorf("Expected the 4 global resources on a single page, got %+v", list)
+	}
+
+	params, _ := json.Marshal(map[string]string{"cursor": cursor{Kind: "resources", Offset: 3}.encode()})
+	result, _ = h.handleResourcesList(params, MCPContext{})
+	if list := result.(ResourcesListResult); len(list.Resources) != 1 || list.Resources[0].URI != "agent-shaker://dashboard" {
+		t.Errorf("Expected the page after offset 3 to hold the dashboard, got %+v", list)
+	}
+
+	params, _ = json.Marshal(map[string]string{"cursor": cursor{Kind: "tasks"}.encode()})
+	if _, rpcErr := h.handleResourcesList(params, MCPContext{}); rpcErr == nil || rpcErr.Code != -32602 {
+		t.Errorf("Expected a cursor of another list to be rejected with -32602, got %+v", rpcErr)
+	}
+}
@@ -0,0 +1,224 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+// projectTools read projects and the dashboard
+var projectTools = []ToolDefinition{
+	{
+		Name:        "list_projects",
+		Description: "List projects in the system, newest first, a page at a time",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"status": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional project status filter",
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Only return projects whose name contains this text",
+				},
+				"fields": fieldsArgument("name", "description", "status", "created_at", "updated_at"),
+				"cursor": cursorArgument,
+				"limit":  limitArgument,
+			},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"projects":   outputList("Projects, newest first"),
+			"count":      countField,
+			"nextCursor": nextCursorField,
+		}, "projects", "count"),
+		Access:  ToolAccess{Global: true},
+		Handler: (*MCPHandler).executeListProjects,
+	},
+	{
+		Name:        "get_project",
+		Description: "Get details of a specific project",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"project_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The project ID (UUID)",
+				},
+			},
+			Required: []string{"project_id"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"id":          outputField("string", "Project ID"),
+			"name":        outputField("string", "Project name"),
+			"description": outputField("string", "Project description"),
+			"status":      outputField("string", "Project status"),
+			"created_at":  outputField("string", "Creation time"),
+			"updated_at":  outputField("string", "Last update time"),
+		}, "id", "name", "status"),
+		Access:  ToolAccess{Action: auth.ActionRead, Project: argProject("project_id", "")},
+		Handler: (*MCPHandler).executeGetProject,
+	},
+	{
+		Name:        "get_dashboard",
+		Description: "Get dashboard statistics and overview",
+		InputSchema: InputSchema{
+			Type:       "object",
+			Properties: map[string]interface{}{},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"projects":          outputField("integer", "Number of projects"),
+			"agents":            outputField("integer", "Number of agents"),
+			"tasks":             outputField("integer", "Number of tasks"),
+			"contexts":          outputField("integer", "Number of contexts"),
+			"pending_tasks":     outputField("integer", "Tasks pending"),
+			"in_progress_tasks": outputField("integer", "Tasks in progress"),
+			"done_tasks":        outputField("integer", "Tasks done"),
+			"blocked_tasks":     outputField("integer", "Tasks blocked"),
+			"failed_tasks":      outputField("integer", "Tasks failed"),
+			"cancelled_tasks":   outputField("integer", "Tasks cancelled"),
+			"overdue_tasks":     outputField("integer", "Open tasks past their due date"),
+		}, "projects", "agents", "tasks", "contexts"),
+		Access:  ToolAccess{Global: true},
+		Handler: (*MCPHandler).executeGetDashboard,
+	},
+}
+
+// Tool execution methods
+func (h *MCPHandler) executeListProjects(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	pg, err := parsePage("projects", args)
+	if err != nil {
+		return nil, err
+	}
+	fields := fieldSelection(args)
+
+	scope, err := h.visibleProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, name, description, status, created_at, updated_at FROM projects WHERE 1=1`
+	var queryArgs []interface{}
+	if status, ok := args["status"].(string); ok && status != "" {
+		queryArgs = append(queryArgs, status)
+		query += fmt.Sprintf(" AND status = $%d", len(queryArgs))
+	}
+	if text, ok := args["query"].(string); ok && text != "" {
+		queryArgs = append(queryArgs, "%"+text+"%")
+		query += fmt.Sprintf(" AND name ILIKE $%d", len(queryArgs))
+	}
+	query += scope.filter("id", &queryArgs)
+	query += pg.clause("", &queryArgs)
+
+	rows, err := h.db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []map[string]interface{}{}
+	for rows.Next() {
+		var id, name, description, status string
+		var createdAt time.Time
+		var updatedAt interface{}
+		if err := rows.Scan(&id, &name, &description, &status, &createdAt, &updatedAt); err != nil {
+			continue
+		}
+		if !pg.keep(createdAt, id) {
+			break
+		}
+		projects = append(projects, selectFields(map[string]interface{}{
+			"id":          id,
+			"name":        name,
+			"description": description,
+			"status":      status,
+			"created_at":  createdAt,
+			"updated_at":  updatedAt,
+		}, fields))
+	}
+
+	return pg.result(map[string]interface{}{
+		"projects": projects,
+		"count":    len(projects),
+	}), nil
+}
+
+func (h *MCPHandler) executeGetProject(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	projectID, ok := args["project_id"].(string)
+	if !ok {
+		return nil, invalidArgument("project_id is required")
+	}
+
+	var id, name, description, status string
+	var createdAt, updatedAt interface{}
+	err := h.db.QueryRow(`
+		SELECT id, name, description, status, created_at, updated_at 
+		FROM projects WHERE id = $1
+	`, projectID).Scan(&id, &name, &description, &status, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"id":          id,
+		"name":        name,
+		"description": description,
+		"status":      status,
+		"created_at":  createdAt,
+		"updated_at":  updatedAt,
+	}, nil
+}
+
+func (h *MCPHandler) executeGetDashboard(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	scope, err := h.visibleProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Counts only cover the projects the caller may read
+	var projectArgs, queryArgs []interface{}
+	inProjects := scope.filter("id", &projectArgs)
+	inProject := scope.filter("project_id", &queryArgs)
+
+	var projectCount, agentCount, taskCount, contextCount int
+	var pendingTasks, inProgressTasks, doneTasks, blockedTasks, failedTasks, cancelledTasks, overdueTasks int
+
+	h.db.QueryRow("SELECT COUNT(*) FROM projects WHERE 1=1"+inProjects, projectArgs...).Scan(&projectCount)
+	h.db.QueryRow("SELECT COUNT(*) FROM agents WHERE 1=1"+inProject, queryArgs...).Scan(&agentCount)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE 1=1"+inProject, queryArgs...).Scan(&taskCount)
+	h.db.QueryRow("SELECT COUNT(*) FROM contexts WHERE 1=1"+inProject, queryArgs...).Scan(&contextCount)
+
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE status = 'pending'"+inProject, queryArgs...).Scan(&pendingTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE status = 'in_progress'"+inProject, queryArgs...).Scan(&inProgressTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE status = 'done'"+inProject, queryArgs...).Scan(&doneTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE status = 'blocked'"+inProject, queryArgs...).Scan(&blockedTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE status = 'failed'"+inProject, queryArgs...).Scan(&failedTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE status = 'cancelled'"+inProject, queryArgs...).Scan(&cancelledTasks)
+	h.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE overdue_at IS NOT NULL AND status IN ('pending', 'in_progress', 'blocked')"+inProject, queryArgs...).Scan(&overdueTasks)
+
+	return map[string]interface{}{
+		"projects":          projectCount,
+		"agents":            agentCount,
+		"tasks":             taskCount,
+		"contexts":          contextCount,
+		"pending_tasks":     pendingTasks,
+		"in_progress_tasks": inProgressTasks,
+		"done_tasks":        doneTasks,
+		"blocked_tasks":     blockedTasks,
+		"failed_tasks":      failedTasks,
+		"cancelled_tasks":   cancelledTasks,
+		"overdue_tasks":     overdueTasks,
+	}, nil
+}
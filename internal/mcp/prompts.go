@@ -0,0 +1,673 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+type Prompt struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description,omitempty"`
+	Arguments   []models.PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptsListResult struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+type PromptMessage struct {
+	Role    string            `json:"role"`
+	Content ToolResultContent `json:"content"`
+}
+
+type PromptsGetResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// maxPromptContent caps how much of each context is quoted into a prompt
+const maxPromptContent = 2000
+
+// builtinPrompts are offered in every project and filled from the database
+var builtinPrompts = []Prompt{
+	{
+		Name:        models.PromptStartMyDay,
+		Description: "Plan the day from my open tasks, what blocks them and my last standup",
+	},
+	{
+		Name:        models.PromptWriteStandup,
+		Description: "Draft today's standup from the tasks I worked on since my last one",
+	},
+	{
+		Name:        models.PromptHandOffTask,
+		Description: "Write a hand-off note for a task from its history, comments and linked contexts",
+		Arguments: []models.PromptArgument{
+			{Name: "task_id", Description: "The task to hand off", Required: true},
+			{Name: "to_agent_id", Description: "The agent taking the task over"},
+		},
+	},
+	{
+		Name:        models.PromptSummarizeContext,
+		Description: "Summarize the documentation and notes shared in the project",
+		Arguments: []models.PromptArgument{
+			{Name: "tags", Description: "Comma-separated tags to restrict the contexts to"},
+			{Name: "limit", Description: "How many recent contexts to include (default 20, max 100)"},
+		},
+	},
+}
+
+// promptAgent is the caller a prompt is filled in for
+type promptAgent struct {
+	ID                 uuid.UUID
+	ProjectID          uuid.UUID
+	Name               string
+	Role               string
+	ProjectName        string
+	ProjectDescription string
+}
+
+func (h *MCPHandler) handlePromptsList(ctx MCPContext) (interface{}, *JSONRPCError) {
+	prompts := append([]Prompt(nil), builtinPrompts...)
+
+	// Project templates are only listed to callers that may read the project
+	projectID := h.promptProject(ctx)
+	if h.db != nil && projectID != uuid.Nil && h.promptDenied(ctx, projectID) == nil {
+		templates, err := h.loadPromptTemplates(projectID, "")
+		if err != nil {
+			return nil, &JSONRPCError{Code: -32603, Message: "Internal error", Data: err.Error()}
+		}
+		for _, t := range templates {
+			prompts = append(prompts, Prompt{Name: t.Name, Description: t.Description, Arguments: t.Arguments})
+		}
+	}
+
+	return PromptsListResult{Prompts: prompts}, nil
+}
+
+func (h *MCPHandler) handlePromptsGet(params json.RawMessage, ctx MCPContext) (interface{}, *JSONRPCError) {
+	var getParams struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &getParams); err != nil {
+		return nil, &JSONRPCError{Code: -32602, Message: "Invalid params", Data: err.Error()}
+	}
+	if h.db == nil {
+		return nil, &JSONRPCError{Code: -32000, Message: "Prompt failed", Data: "Database not connected"}
+	}
+	args := getParams.Arguments
+
+	var description, text string
+	var rpcErr *JSONRPCError
+	switch getParams.Name {
+	case models.PromptStartMyDay:
+		description = "Start my day"
+		text, rpcErr = h.startMyDayPrompt(ctx)
+	case models.PromptWriteStandup:
+		description = "Write my standup"
+		text, rpcErr = h.writeStandupPrompt(ctx)
+	case models.PromptHandOffTask:
+		description = "Hand off a task"
+		text, rpcErr = h.handOffTaskPrompt(ctx, args["task_id"], args["to_agent_id"])
+	case models.PromptSummarizeContext:
+		description = "Summarize project context"
+		text, rpcErr = h.summarizeContextPrompt(ctx, args["tags"], args["limit"])
+	default:
+		description, text, rpcErr = h.customPrompt(ctx, getParams.Name, args)
+	}
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	return PromptsGetResult{
+		Description: description,
+		Messages: []PromptMessage{
+			{Role: "user", Content: ToolResultContent{Type: "text", Text: text}},
+		},
+	}, nil
+}
+
+// customPrompt renders a project's prompt template with the caller's arguments
+func (h *MCPHandler) customPrompt(ctx MCPContext, name string, args map[string]string) (string, string, *JSONRPCError) {
+	projectID := h.promptProject(ctx)
+	if projectID == uuid.Nil {
+		return "", "", &JSONRPCError{Code: -32602, Message: "Unknown prompt", Data: fmt.Sprintf("Prompt not found: %s", name)}
+	}
+	if rpcErr := h.promptDenied(ctx, projectID); rpcErr != nil {
+		return "", "", rpcErr
+	}
+
+	templates, err := h.loadPromptTemplates(projectID, name)
+	if err != nil {
+		return "", "", &JSONRPCError{Code: -32603, Message: "Internal error", Data: err.Error()}
+	}
+	if len(templates) == 0 {
+		return "", "", &JSONRPCError{Code: -32602, Message: "Unknown prompt", Data: fmt.Sprintf("Prompt not found: %s", name)}
+	}
+	t := templates[0]
+
+	values := map[string]string{
+		"project_id": projectID.String(),
+		"today":      time.Now().Format("2006-01-02"),
+	}
+	if agent, err := h.loadPromptAgent(ctx); err == nil {
+		values["agent_id"] = agent.ID.String()
+		values["agent_name"] = agent.Name
+		values["agent_role"] = agent.Role
+		values["project_name"] = agent.ProjectName
+		values["project_description"] = agent.ProjectDescription
+	} else {
+		var name, description string
+		h.db.QueryRow("SELECT name, COALESCE(description, '') FROM projects WHERE id = $1", projectID).Scan(&name, &description)
+		values["project_name"] = name
+		values["project_description"] = description
+	}
+	for _, arg := range t.Arguments {
+		value, ok := args[arg.Name]
+		if !ok && arg.Required {
+			return "", "", &JSONRPCError{Code: -32602, Message: "Invalid params", Data: fmt.Sprintf("Missing required argument: %s", arg.Name)}
+		}
+		values[arg.Name] = value
+	}
+
+	return t.Description, models.RenderPrompt(t.Template, values), nil
+}
+
+func (h *MCPHandler) startMyDayPrompt(ctx MCPContext) (string, *JSONRPCError) {
+	agent, rpcErr := h.requirePromptAgent(ctx)
+	if rpcErr != nil {
+		return "", rpcErr
+	}
+
+	tasks, err := h.promptTasks(agent.ID, "status IN ('pending', 'in_progress', 'blocked')")
+	if err != nil {
+		return "", promptFailed(err)
+	}
+	blockers, err := h.promptBlockers(tasks)
+	if err != nil {
+		return "", promptFailed(err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "I am %s, a %s agent in the project %q. Help me plan my day.\n\n", agent.Name, agent.Role, agent.ProjectName)
+
+	b.WriteString("## My open tasks\n\n")
+	if len(tasks) == 0 {
+		b.WriteString("No open tasks are assigned to me.\n")
+	}
+	for _, t := range tasks {
+		writePromptTask(&b, t)
+		for _, blocker := range blockers[t.ID] {
+			fmt.Fprintf(&b, "  - waiting on %s\n", blocker)
+		}
+	}
+
+	b.WriteString("\n## My last standup\n\n")
+	if err := h.writeLastStandup(&b, agent.ID); err != nil {
+		return "", promptFailed(err)
+	}
+
+	b.WriteString("\nSuggest what I should work on first and in which order, call out anything blocked or overdue, " +
+		"and say who I need to talk to about the tasks I am waiting on.")
+	return b.String(), nil
+}
+
+func (h *MCPHandler) writeStandupPrompt(ctx MCPContext) (string, *JSONRPCError) {
+	agent, rpcErr := h.requirePromptAgent(ctx)
+	if rpcErr != nil {
+		return "", rpcErr
+	}
+
+	// Work since the last standup, or since yesterday when there is none
+	var since time.Time
+	err := h.db.QueryRow(`
+		SELECT COALESCE(MAX(standup_date)::timestamp, CURRENT_DATE - INTERVAL '1 day')
+		FROM daily_standups WHERE agent_id = $1 AND standup_date < CURRENT_DATE
+	`, agent.ID).Scan(&since)
+	if err != nil {
+		return "", promptFailed(err)
+	}
+
+	finished, err := h.promptTasks(agent.ID, "status IN ('done', 'failed') AND updated_at >= $2", since)
+	if err != nil {
+		return "", promptFailed(err)
+	}
+	open, err := h.promptTasks(agent.ID, "status IN ('pending', 'in_progress', 'blocked')")
+	if err != nil {
+		return "", promptFailed(err)
+	}
+	blockers, err := h.promptBlockers(open)
+	if err != nil {
+		return "", promptFailed(err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Write today's standup for %s in the project %q.\n\n", agent.Name, agent.ProjectName)
+
+	fmt.Fprintf(&b, "## Finished since %s\n\n", since.Format("2006-01-02"))
+	if len(finished) == 0 {
+		b.WriteString("Nothing.\n")
+	}
+	for _, t := range finished {
+		writePromptTask(&b, t)
+	}
+
+	b.WriteString("\n## Still open\n\n")
+	if len(open) == 0 {
+		b.WriteString("Nothing.\n")
+	}
+	for _, t := range open {
+		writePromptTask(&b, t)
+		for _, blocker := range blockers[t.ID] {
+			fmt.Fprintf(&b, "  - waiting on %s\n", blocker)
+		}
+	}
+
+	b.WriteString("\n## Previous standup\n\n")
+	if err := h.writeLastStandup(&b, agent.ID); err != nil {
+		return "", promptFailed(err)
+	}
+
+	b.WriteString("\nFill in the standup fields did, doing, done, blockers and challenges. Keep each to a few short bullet points " +
+		"and mention task titles. The standup can be submitted with POST /api/standups.")
+	return b.String(), nil
+}
+
+func (h *MCPHandler) handOffTaskPrompt(ctx MCPContext, taskID, toAgentID string) (string, *JSONRPCError) {
+	id, err := uuid.Parse(taskID)
+	if err != nil {
+		return "", &JSONRPCError{Code: -32602, Message: "Invalid params", Data: "task_id must be a valid task ID"}
+	}
+
+	var t models.Task
+	var assignee sql.NullString
+	err = h.db.QueryRow(`
+		SELECT t.id, t.project_id, t.title, COALESCE(t.description, ''), t.status, t.priority, COALESCE(t.output, ''), t.due_at, a.name
+		FROM tasks t
+		LEFT JOIN agents a ON a.id = t.assigned_to
+		WHERE t.id = $1
+	`, id).Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &t.Output, &t.DueAt, &assignee)
+	if err == sql.ErrNoRows {
+		return "", &JSONRPCError{Code: -32602, Message: "Invalid params", Data: "Task not found"}
+	} else if err != nil {
+		return "", promptFailed(err)
+	}
+	if rpcErr := h.promptDenied(ctx, t.ProjectID); rpcErr != nil {
+		return "", rpcErr
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Write a hand-off note for the task %q (%s).\n\n", t.Title, t.ID)
+	fmt.Fprintf(&b, "- Status: %s\n- Priority: %s\n", t.Status, t.Priority)
+	if assignee.Valid {
+		fmt.Fprintf(&b, "- Assigned to: %s\n", assignee.String)
+	}
+	if t.DueAt != nil {
+		fmt.Fprintf(&b, "- Due: %s\n", t.DueAt.Format(time.RFC3339))
+	}
+	if t.Description != "" {
+		fmt.Fprintf(&b, "\n## Description\n\n%s\n", t.Description)
+	}
+	if t.Output != "" {
+		fmt.Fprintf(&b, "\n## Output so far\n\n%s\n", t.Output)
+	}
+
+	events, err := history.List(context.Background(), h.db, t.ID)
+	if err != nil {
+		return "", promptFailed(err)
+	}
+	if len(events) > 0 {
+		b.WriteString("\n## History\n\n")
+		for _, e := range events {
+			fmt.Fprintf(&b, "- %s %s", e.CreatedAt.Format("2006-01-02 15:04"), e.Event)
+			if e.NewStatus != "" {
+				fmt.Fprintf(&b, " -> %s", e.NewStatus)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if h.comments != nil {
+		threads, err := h.comments.List(context.Background(), t.ID)
+		if err != nil {
+			return "", promptFailed(err)
+		}
+		if len(threads) > 0 {
+			b.WriteString("\n## Comments\n\n")
+			writePromptComments(&b, threads, "")
+		}
+	}
+
+	rows, err := h.db.Query("SELECT title, COALESCE(content, '') FROM contexts WHERE task_id = $1 ORDER BY created_at", t.ID)
+	if err != nil {
+		return "", promptFailed(err)
+	}
+	defer rows.Close()
+	heading := false
+	for rows.Next() {
+		var title, content string
+		if err := rows.Scan(&title, &content); err != nil {
+			return "", promptFailed(err)
+		}
+		if !heading {
+			b.WriteString("\n## Linked contexts\n")
+			heading = true
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n%s\n", title, truncatePrompt(content))
+	}
+	if err := rows.Err(); err != nil {
+		return "", promptFailed(err)
+	}
+
+	target := "the next agent"
+	if toAgentID != "" {
+		var name string
+		if err := h.db.QueryRow("SELECT name FROM agents WHERE id = $1 AND project_id = $2", parseID(toAgentID), t.ProjectID).Scan(&name); err == nil {
+			target = name
+		}
+	}
+	fmt.Fprintf(&b, "\nWrite the note for %s: what has been done, what is left, open questions and anything surprising. ", target)
+	b.WriteString("Post it with comment_on_task")
+	if toAgentID != "" {
+		fmt.Fprintf(&b, ", then call reassign_task with task_id %s and agent_id %s", t.ID, toAgentID)
+	}
+	b.WriteString(".")
+	return b.String(), nil
+}
+
+func (h *MCPHandler) summarizeContextPrompt(ctx MCPContext, tags, limit string) (string, *JSONRPCError) {
+	projectID := h.promptProject(ctx)
+	if projectID == uuid.Nil {
+		return "", &JSONRPCError{Code: -32602, Message: "Invalid params", Data: "No project configured; connect with a project_id"}
+	}
+	if rpcErr := h.promptDenied(ctx, projectID); rpcErr != nil {
+		return "", rpcErr
+	}
+
+	n := 20
+	if limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 {
+			return "", &JSONRPCError{Code: -32602, Message: "Invalid params", Data: "limit must be a positive integer"}
+		}
+		n = min(parsed, 100)
+	}
+
+	var projectName, projectDescription string
+	err := h.db.QueryRow("SELECT name, COALESCE(description, '') FROM projects WHERE id = $1", projectID).Scan(&projectName, &projectDescription)
+	if err == sql.ErrNoRows {
+		return "", &JSONRPCError{Code: -32602, Message: "Invalid params", Data: "Project not found"}
+	} else if err != nil {
+		return "", promptFailed(err)
+	}
+
+	query := `
+		SELECT c.title, COALESCE(c.content, ''), c.tags, COALESCE(a.name, ''), c.created_at
+		FROM contexts c
+		LEFT JOIN agents a ON a.id = c.agent_id
+		WHERE c.project_id = $1`
+	queryArgs := []interface{}{projectID}
+	if filter := models.NormalizeCapabilities(strings.Split(tags, ",")); len(filter) > 0 {
+		queryArgs = append(queryArgs, pq.Array(filter))
+		query += fmt.Sprintf(" AND c.tags && $%d::text[]", len(queryArgs))
+	}
+	queryArgs = append(queryArgs, n)
+	query += fmt.Sprintf(" ORDER BY c.created_at DESC LIMIT $%d", len(queryArgs))
+
+	rows, err := h.db.Query(query, queryArgs...)
+	if err != nil {
+		return "", promptFailed(err)
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Summarize what the team has documented in the project %q.\n", projectName)
+	if projectDescription != "" {
+		fmt.Fprintf(&b, "\n%s\n", projectDescription)
+	}
+
+	count := 0
+	for rows.Next() {
+		var title, content, author string
+		var contextTags pq.StringArray
+		var createdAt time.Time
+		if err := rows.Scan(&title, &content, &contextTags, &author, &createdAt); err != nil {
+			return "", promptFailed(err)
+		}
+		count++
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		fmt.Fprintf(&b, "_%s", createdAt.Format("2006-01-02"))
+		if author != "" {
+			fmt.Fprintf(&b, " by %s", author)
+		}
+		if len(contextTags) > 0 {
+			fmt.Fprintf(&b, ", tags: %s", strings.Join(contextTags, ", "))
+		}
+		fmt.Fprintf(&b, "_\n\n%s\n", truncatePrompt(content))
+	}
+	if err := rows.Err(); err != nil {
+		return "", promptFailed(err)
+	}
+	if count == 0 {
+		b.WriteString("\nNo contexts have been shared yet.\n")
+	}
+
+	b.WriteString("\nGroup the summary by topic, keep the decisions and conventions agents must follow, " +
+		"and point out anything that looks outdated or contradictory.")
+	return b.String(), nil
+}
+
+// promptProject returns the caller's project, falling back to the project of
+// the caller's agent
+func (h *MCPHandler) promptProject(ctx MCPContext) uuid.UUID {
+	if id := parseID(ctx.ProjectID); id != uuid.Nil {
+		return id
+	}
+	var projectID uuid.UUID
+	if h.db != nil && ctx.AgentID != "" {
+		h.db.QueryRow("SELECT project_id FROM agents WHERE id = $1", parseID(ctx.AgentID)).Scan(&projectID)
+	}
+	return projectID
+}
+
+// promptDenied checks that the caller may read the project
+func (h *MCPHandler) promptDenied(ctx MCPContext, projectID uuid.UUID) *JSONRPCError {
+	if h.auth == nil {
+		return nil
+	}
+	if denied := h.check(ctx, projectID, auth.ActionRead); denied != nil {
+		return &JSONRPCError{Code: -32000, Message: "Prompt failed", Data: denied}
+	}
+	return nil
+}
+
+func (h *MCPHandler) loadPromptAgent(ctx MCPContext) (promptAgent, error) {
+	agent := promptAgent{ID: parseID(ctx.AgentID)}
+	err := h.db.QueryRow(`
+		SELECT a.project_id, a.name, COALESCE(a.role, ''), p.name, COALESCE(p.description, '')
+		FROM agents a
+		JOIN projects p ON p.id = a.project_id
+		WHERE a.id = $1
+	`, agent.ID).Scan(&agent.ProjectID, &agent.Name, &agent.Role, &agent.ProjectName, &agent.ProjectDescription)
+	return agent, err
+}
+
+// requirePromptAgent loads the caller's agent for prompts about "my" work
+func (h *MCPHandler) requirePromptAgent(ctx MCPContext) (promptAgent, *JSONRPCError) {
+	if ctx.AgentID == "" {
+		return promptAgent{}, &JSONRPCError{Code: -32602, Message: "Invalid params", Data: "No agent configured; connect with an agent_id"}
+	}
+	agent, err := h.loadPromptAgent(ctx)
+	if err == sql.ErrNoRows {
+		return agent, &JSONRPCError{Code: -32602, Message: "Invalid params", Data: "Agent not found"}
+	} else if err != nil {
+		return agent, promptFailed(err)
+	}
+	if rpcErr := h.promptDenied(ctx, agent.ProjectID); rpcErr != nil {
+		return agent, rpcErr
+	}
+	return agent, nil
+}
+
+// promptTasks returns the agent's tasks matching condition, most urgent first
+func (h *MCPHandler) promptTasks(agentID uuid.UUID, condition string, args ...interface{}) ([]models.Task, error) {
+	rows, err := h.db.Query(`
+		SELECT id, title, status, priority, due_at, overdue_at
+		FROM tasks
+		WHERE assigned_to = $1 AND `+condition+`
+		ORDER BY CASE status WHEN 'in_progress' THEN 0 WHEN 'blocked' THEN 1 ELSE 2 END,
+		         CASE priority WHEN 'high' THEN 0 WHEN 'medium' THEN 1 ELSE 2 END,
+		         due_at NULLS LAST, created_at
+		LIMIT 50
+	`, append([]interface{}{agentID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		var t models.Task
+		if err := rows.Scan(&t.ID, &t.Title, &t.Status, &t.Priority, &t.DueAt, &t.OverdueAt); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// promptBlockers describes the unfinished prerequisites of each task
+func (h *MCPHandler) promptBlockers(tasks []models.Task) (map[uuid.UUID][]string, error) {
+	blockers := make(map[uuid.UUID][]string)
+	if len(tasks) == 0 {
+		return blockers, nil
+	}
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID.String()
+	}
+
+	rows, err := h.db.Query(`
+		SELECT d.task_id, p.title, p.status, COALESCE(a.name, 'nobody')
+		FROM task_dependencies d
+		JOIN tasks p ON p.id = d.depends_on_id
+		LEFT JOIN agents a ON a.id = p.assigned_to
+		WHERE d.task_id = ANY($1::uuid[]) AND p.status <> 'done'
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID uuid.UUID
+		var title, status, assignee string
+		if err := rows.Scan(&taskID, &title, &status, &assignee); err != nil {
+			return nil, err
+		}
+		blockers[taskID] = append(blockers[taskID], fmt.Sprintf("%q (%s, assigned to %s)", title, status, assignee))
+	}
+	return blockers, rows.Err()
+}
+
+// writeLastStandup writes the agent's most recent standup before today
+func (h *MCPHandler) writeLastStandup(b *strings.Builder, agentID uuid.UUID) error {
+	var date time.Time
+	var did, doing, done string
+	var blockers sql.NullString
+	err := h.db.QueryRow(`
+		SELECT standup_date, did, doing, done, blockers
+		FROM daily_standups
+		WHERE agent_id = $1 AND standup_date < CURRENT_DATE
+		ORDER BY standup_date DESC
+		LIMIT 1
+	`, agentID).Scan(&date, &did, &doing, &done, &blockers)
+	if err == sql.ErrNoRows {
+		b.WriteString("No earlier standup.\n")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b, "From %s:\n\n- Did: %s\n- Doing: %s\n- Done: %s\n", date.Format("2006-01-02"), did, doing, done)
+	if blockers.Valid && blockers.String != "" {
+		fmt.Fprintf(b, "- Blockers: %s\n", blockers.String)
+	}
+	return nil
+}
+
+func writePromptTask(b *strings.Builder, t models.Task) {
+	fmt.Fprintf(b, "- [%s] %s (%s priority, id %s)", t.Status, t.Title, t.Priority, t.ID)
+	if t.OverdueAt != nil {
+		b.WriteString(", OVERDUE")
+	} else if t.DueAt != nil {
+		fmt.Fprintf(b, ", due %s", t.DueAt.Format(time.RFC3339))
+	}
+	b.WriteString("\n")
+}
+
+func writePromptComments(b *strings.Builder, comments []*models.TaskComment, indent string) {
+	for _, c := range comments {
+		author := c.AgentName
+		if author == "" {
+			author = c.AgentID.String()
+		}
+		fmt.Fprintf(b, "%s- %s: %s\n", indent, author, strings.ReplaceAll(c.Body, "\n", " "))
+		writePromptComments(b, c.Replies, indent+"  ")
+	}
+}
+
+// truncatePrompt shortens long content quoted into a prompt
+func truncatePrompt(s string) string {
+	if len(s) <= maxPromptContent {
+		return s
+	}
+	cut := maxPromptContent
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "\n\n[truncated]"
+}
+
+func (h *MCPHandler) loadPromptTemplates(projectID uuid.UUID, name string) ([]models.PromptTemplate, error) {
+	query := `
+		SELECT id, project_id, name, description, arguments, template, created_at, updated_at
+		FROM prompt_templates
+		WHERE project_id = $1`
+	args := []interface{}{projectID}
+	if name != "" {
+		query += " AND name = $2"
+		args = append(args, name)
+	}
+	rows, err := h.db.Query(query+" ORDER BY name", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompts: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []models.PromptTemplate
+	for rows.Next() {
+		var t models.PromptTemplate
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Name, &t.Description, &t.Arguments, &t.Template, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func promptFailed(err error) *JSONRPCError {
+	return &JSONRPCError{Code: -32000, Message: "Prompt failed", Data: err.Error()}
+}
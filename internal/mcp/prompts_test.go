@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+func TestBuiltinPromptsAreReserved(t *testing.T) {
+	if len(builtinPrompts) != len(models.BuiltinPrompts) {
+		t.Fatalf("Expected %d built-in prompts, got %d", len(models.BuiltinPrompts), len(builtinPrompts))
+	}
+	for i, p := range builtinPrompts {
+		if p.Name != models.BuiltinPrompts[i] {
+			t.Errorf("Expected built-in prompt %q, got %q", models.BuiltinPrompts[i], p.Name)
+		}
+	}
+
+	h := &MCPHandler{}
+	result, rpcErr := h.handlePromptsList(MCPContext{})
+	if rpcErr != nil {
+		t.Fatalf("Unexpected error: %v", rpcErr)
+	}
+	if got := result.(PromptsListResult).Prompts; len(got) != len(builtinPrompts) {
+		t.Errorf("Expected only the built-in prompts without a database, got %d", len(got))
+	}
+}
+
+func TestTruncatePrompt(t *testing.T) {
+	if got := truncatePrompt("short"); got != "short" {
+		t.Errorf("Expected short content unchanged, got %q", got)
+	}
+
+	// A multi-byte rune straddling the limit must not be split
+	long := strings.Repeat("a", maxPromptContent-1) + "é" + "tail"
+	got := truncatePrompt(long)
+	if !strings.HasSuffix(got, "[truncated]") {
+		t.Errorf("Expected a truncation marker, got %q", got[len(got)-20:])
+	}
+	if strings.Contains(got, "é") || !strings.HasPrefix(got, strings.Repeat("a", maxPromptContent-1)+"\n") {
+		t.Error("Expected the cut to fall before the multi-byte rune")
+	}
+}
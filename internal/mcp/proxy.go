@@ -0,0 +1,258 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/mcp/upstream"
+)
+
+// rawToolResult is a tools/call result passed on from a downstream server
+// unchanged
+type rawToolResult json.RawMessage
+
+// upstreamServer is a downstream MCP server whose tools are re-exported
+type upstreamServer struct {
+	client *upstream.Client
+	// tools are the registered names of its tools
+	tools []string
+}
+
+// ConnectUpstreams connects to the downstream MCP servers in the background
+// and re-exports their tools as they come up. Servers that cannot be reached
+// are logged and skipped.
+func (h *MCPHandler) ConnectUpstreams(ctx context.Context, servers map[string]upstream.Config) {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		go func(name string, config upstream.Config) {
+			c, err := upstream.Connect(ctx, name, config)
+			if err != nil {
+				log.Printf("Failed to connect to MCP server %s: %v", name, err)
+				return
+			}
+			if err := h.AddUpstream(ctx, c); err != nil {
+				log.Printf("Failed to add MCP server %s: %v", name, err)
+				c.Close()
+			}
+		}(name, servers[name])
+	}
+}
+
+// AddUpstream re-exports the tools of a connected downstream server as
+// "<server>.<tool>". The tools follow the server's list_changed
+// notifications and are removed when its connection ends.
+func (h *MCPHandler) AddUpstream(ctx context.Context, c *upstream.Client) error {
+	h.upstreamMu.Lock()
+	if h.upstreams == nil {
+		h.upstreams = make(map[string]*upstreamServer)
+	}
+	if _, exists := h.upstreams[c.Name()]; exists {
+		h.upstreamMu.Unlock()
+		return fmt.Errorf("MCP server %s is already connected", c.Name())
+	}
+	h.upstreams[c.Name()] = &upstreamServer{client: c}
+	h.upstreamMu.Unlock()
+
+	if err := h.syncUpstream(ctx, c); err != nil {
+		h.RemoveUpstream(c.Name())
+		return err
+	}
+
+	c.OnToolsChanged(func() {
+		if err := h.syncUpstream(context.Background(), c); err != nil {
+			log.Printf("Failed to refresh the tools of MCP server %s: %v", c.Name(), err)
+		}
+	})
+	c.OnClose(func(err error) {
+		log.Printf("MCP server %s disconnected: %v", c.Name(), err)
+		h.RemoveUpstream(c.Name())
+		c.Close()
+	})
+	return nil
+}
+
+// RemoveUpstream stops re-exporting a downstream server's tools and reports
+// whether it was connected. The connection itself is left to the caller.
+func (h *MCPHandler) RemoveUpstream(name string) bool {
+	h.upstreamMu.Lock()
+	defer h.upstreamMu.Unlock()
+
+	server, ok := h.upstreams[name]
+	if !ok {
+		return false
+	}
+	delete(h.upstreams, name)
+	if err := h.tools.Replace(server.tools, nil); err != nil {
+		log.Printf("Failed to remove the tools of MCP server %s: %v", name, err)
+	}
+	return true
+}
+
+// syncUpstream registers the server's current tools in place of the ones
+// registered before
+func (h *MCPHandler) syncUpstream(ctx context.Context, c *upstream.Client) error {
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	defs := make([]ToolDefinition, 0, len(tools))
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		def, err := proxiedTool(c, tool)
+		if err != nil {
+			// Without its schema the tool's arguments could not be checked
+			log.Printf("Skipping tool %s of MCP server %s: %v", tool.Name, c.Name(), err)
+			continue
+		}
+		defs = append(defs, def)
+		names = append(names, def.Name)
+	}
+
+	h.upstreamMu.Lock()
+	defer h.upstreamMu.Unlock()
+	server, ok := h.upstreams[c.Name()]
+	if !ok {
+		// Removed while its tools were being listed
+		return nil
+	}
+	if err := h.tools.Replace(server.tools, defs); err != nil {
+		return err
+	}
+	server.tools = names
+	log.Printf("MCP server %s provides %d tools", c.Name(), len(defs))
+	return nil
+}
+
+// proxiedTool is the definition a downstream tool is re-exported under. It
+// fails when the tool's schemas are not valid JSON schema objects.
+func proxiedTool(c *upstream.Client, tool upstream.Tool) (ToolDefinition, error) {
+	def := ToolDefinition{
+		Name:        c.Name() + "." + tool.Name,
+		Description: fmt.Sprintf("[%s] %s", c.Name(), tool.Description),
+		Access:      upstreamAccess(c.Name(), c.Config()),
+		Handler: func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+			return h.callUpstream(c, tool.Name, args, ctx)
+		},
+	}
+	// Schemas keep the keywords the server's arguments are validated with;
+	// servers that send none accept any object
+	if len(tool.InputSchema) > 0 {
+		if err := json.Unmarshal(tool.InputSchema, &def.InputSchema); err != nil {
+			return ToolDefinition{}, fmt.Errorf("invalid input schema: %w", err)
+		}
+	}
+	if len(tool.OutputSchema) > 0 {
+		var output OutputSchema
+		if err := json.Unmarshal(tool.OutputSchema, &output); err != nil {
+			return ToolDefinition{}, fmt.Errorf("invalid output schema: %w", err)
+		}
+		def.OutputSchema = &output
+	}
+	return def, nil
+}
+
+// upstreamAccess requires the server's configured role for its tools.
+// Downstream tools reach outside Agent Shaker, so they are reserved to admins
+// when the server sets no role. The role is checked in the server's project,
+// or the connection's project when it has none; admins need neither.
+func upstreamAccess(server string, config upstream.Config) ToolAccess {
+	role := config.Role
+	if role == auth.RoleNone {
+		role = auth.RoleAdmin
+	}
+	return ToolAccess{
+		Role: role,
+		Project: func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (*ToolScope, *ToolError) {
+			projectID := config.ProjectID
+			if projectID == "" {
+				projectID = ctx.ProjectID
+			}
+			if parseID(projectID) == uuid.Nil {
+				if ctx.Principal.IsAdmin() {
+					return &ToolScope{}, nil
+				}
+				return nil, invalidArgument("a project is required to use the tools of MCP server %s", server)
+			}
+			return &ToolScope{ProjectID: parseID(projectID)}, nil
+		},
+	}
+}
+
+// callUpstream calls a tool of a downstream server and records the call
+// against the calling project and agent
+func (h *MCPHandler) callUpstream(c *upstream.Client, tool string, args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	start := time.Now()
+	result, err := c.CallTool(ctx.Context(), tool, args)
+
+	var outcome struct {
+		IsError bool `json:"isError"`
+	}
+	if err == nil {
+		json.Unmarshal(result, &outcome)
+	}
+	h.recordUpstreamCall(c.Name(), tool, args, ctx, time.Since(start), outcome.IsError || err != nil, err)
+
+	if err != nil {
+		return nil, upstreamError(c.Name(), tool, err)
+	}
+	return rawToolResult(result), nil
+}
+
+// upstreamError classifies why a downstream server failed a call
+func upstreamError(server, tool string, err error) error {
+	var rpcErr *upstream.RPCError
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case -32602:
+			return invalidArgument("%s", rpcErr.Message)
+		case -32601:
+			return notFound("MCP server %s has no tool %s", server, tool)
+		}
+		return &ToolError{Code: CodeInternal, Message: fmt.Sprintf("MCP server %s failed: %s", server, rpcErr.Message)}
+	}
+	return unavailable("MCP server %s is unavailable: %v", server, err)
+}
+
+// recordUpstreamCall logs a proxied call and appends it to the audit log
+func (h *MCPHandler) recordUpstreamCall(server, tool string, args map[string]interface{}, ctx MCPContext, took time.Duration, failed bool, callErr error) {
+	log.Printf("MCP upstream call %s.%s (project=%s, agent=%s) took %s, error=%v", server, tool, ctx.ProjectID, ctx.AgentID, took.Round(time.Millisecond), failed)
+	if h.db == nil {
+		return
+	}
+
+	arguments, _ := json.Marshal(args)
+	var errMsg *string
+	if callErr != nil {
+		msg := callErr.Error()
+		errMsg = &msg
+	}
+	_, err := h.db.Exec(`
+		INSERT INTO mcp_upstream_calls (id, project_id, agent_id, server, tool_name, arguments, is_error, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, uuid.New(), optionalID(ctx.ProjectID), optionalID(ctx.AgentID), server, tool, arguments, failed, errMsg, took.Milliseconds())
+	if err != nil {
+		log.Printf("Failed to record MCP upstream call: %v", err)
+	}
+}
+
+// optionalID is a UUID argument for a nullable column, nil when s is not a UUID
+func optionalID(s string) *uuid.UUID {
+	id := parseID(s)
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,158 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/mcp/upstream"
+)
+
+// downstreamServer is another Agent Shaker MCP server offering an echo tool
+func downstreamServer(t *testing.T) *httptest.Server {
+	down := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	down.Tools().Register(ToolDefinition{
+		Name:        "echo",
+		Description: "Echo the arguments",
+		Access:      ToolAccess{Global: true},
+		InputSchema: InputSchema{
+			Properties: map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+			Required:   []string{"text"},
+		},
+		Handler: func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+			return args, nil
+		},
+	})
+	srv := httptest.NewServer(http.HandlerFunc(down.HandleMCP))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProxyUpstreamTools(t *testing.T) {
+	srv := downstreamServer(t)
+	c, err := upstream.Connect(context.Background(), "down", upstream.Config{URL: srv.URL, Role: auth.RoleAgent})
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer c.Close()
+
+	h := NewMCPHandler(nil, nil, auth.NewService(nil, auth.Config{}), nil, nil, nil, nil, nil, nil)
+	projectID := uuid.New()
+	agent := MCPContext{ProjectID: projectID.String(), Principal: &auth.Principal{Kind: auth.PrincipalAgent, AgentID: uuid.New(), ProjectID: projectID}}
+	changes := 0
+	h.Tools().OnChange(func() { changes++ })
+	if err := h.AddUpstream(context.Background(), c); err != nil {
+		t.Fatalf("AddUpstream failed: %v", err)
+	}
+	if err := h.AddUpstream(context.Background(), c); err == nil {
+		t.Error("Expected adding the same server twice to fail")
+	}
+	if changes != 1 {
+		t.Errorf("Expected one change notification for the whole tool list, got %d", changes)
+	}
+
+	def, ok := h.Tools().Lookup("down.echo")
+	if !ok {
+		t.Fatal("Expected echo to be re-exported as down.echo")
+	}
+	if def.Description != "[down] Echo the arguments" || len(def.InputSchema.Required) != 1 {
+		t.Errorf("Expected the description and schema to be kept, got %+v", def)
+	}
+
+	params, _ := json.Marshal(ToolCallParams{Name: "down.echo", Arguments: map[string]interface{}{"text": "hi"}})
+	result, rpcErr := h.handleToolsCall(params, agent)
+	if rpcErr != nil {
+		t.Fatalf("tools/call failed: %v", rpcErr.Message)
+	}
+	raw, ok := result.(json.RawMessage)
+	if !ok {
+		t.Fatalf("Expected the downstream result to be passed on as is, got %T", result)
+	}
+	var res struct {
+		IsError           bool              `json:"isError"`
+		StructuredContent map[string]string `json:"structuredContent"`
+	}
+	json.Unmarshal(raw, &res)
+	if res.IsError || res.StructuredContent["text"] != "hi" {
+		t.Errorf("Expected the echoed arguments, got %s", raw)
+	}
+
+	params, _ = json.Marshal(ToolCallParams{Name: "down.echo"})
+	result, _ = h.handleToolsCall(params, agent)
+	if err, ok := result.(ToolResult).StructuredContent.(*ToolError); !ok || err.Code != CodeInvalidArgument {
+		t.Errorf("Expected missing arguments to be rejected by the downstream schema, got %+v", result)
+	}
+
+	if !h.RemoveUpstream("down") || h.RemoveUpstream("down") {
+		t.Error("Expected the server to be removed exactly once")
+	}
+	if _, ok := h.Tools().Lookup("down.echo"); ok {
+		t.Error("Expected the tools of a removed server to be gone")
+	}
+}
+
+func TestUpstreamAccess(t *testing.T) {
+	h := NewMCPHandler(nil, nil, auth.NewService(nil, auth.Config{}), nil, nil, nil, nil, nil, nil)
+	home, other := uuid.New(), uuid.New()
+	agent := &auth.Principal{Kind: auth.PrincipalAgent, AgentID: uuid.New(), ProjectID: home}
+	admin := &auth.Principal{Kind: auth.PrincipalAdmin}
+
+	tests := []struct {
+		name   string
+		config upstream.Config
+		ctx    MCPContext
+		want   ToolErrorCode
+	}{
+		{"agent in its project", upstream.Config{Role: auth.RoleAgent}, MCPContext{ProjectID: home.String(), Principal: agent}, ""},
+		{"anonymous", upstream.Config{Role: auth.RoleAgent}, MCPContext{ProjectID: home.String()}, CodePermissionDenied},
+		{"agent in another project", upstream.Config{Role: auth.RoleAgent, ProjectID: other.String()}, MCPContext{ProjectID: home.String(), Principal: agent}, CodePermissionDenied},
+		{"role too low", upstream.Config{Role: auth.RoleMaintainer}, MCPContext{ProjectID: home.String(), Principal: agent}, CodePermissionDenied},
+		{"no project", upstream.Config{Role: auth.RoleAgent}, MCPContext{Principal: &auth.Principal{Kind: auth.PrincipalUser, UserID: uuid.New()}}, CodeInvalidArgument},
+		{"no role configured", upstream.Config{}, MCPContext{ProjectID: home.String(), Principal: agent}, CodePermissionDenied},
+		{"admin without a role configured", upstream.Config{}, MCPContext{Principal: admin}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := &ToolDefinition{Name: "down.echo", Access: upstreamAccess("down", tt.config)}
+			var got ToolErrorCode
+			if err := h.authorizeTool(def, map[string]interface{}{}, tt.ctx); err != nil {
+				got = err.Code
+			}
+			if got != tt.want {
+				t.Errorf("authorizeTool() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxiedToolRejectsInvalidSchema(t *testing.T) {
+	c := &upstream.Client{}
+	if _, err := proxiedTool(c, upstream.Tool{Name: "bad", InputSchema: json.RawMessage(`"object"`)}); err == nil {
+		t.Error("Expected a tool with an invalid input schema to be rejected")
+	}
+	if _, err := proxiedTool(c, upstream.Tool{Name: "good", InputSchema: json.RawMessage(`{"type":"object"}`)}); err != nil {
+		t.Errorf("Expected a valid schema to be accepted, got %v", err)
+	}
+}
+
+func TestUpstreamError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ToolErrorCode
+	}{
+		{&upstream.RPCError{Code: -32602, Message: "bad"}, CodeInvalidArgument},
+		{&upstream.RPCError{Code: -32601, Message: "missing"}, CodeNotFound},
+		{&upstream.RPCError{Code: -32603, Message: "boom"}, CodeInternal},
+		{upstream.ErrClosed, CodeUnavailable},
+	}
+	for _, tt := range tests {
+		err := upstreamError("down", "echo", tt.err).(*ToolError)
+		if err.Code != tt.want {
+			t.Errorf("upstreamError(%v) = %s, want %s", tt.err, err.Code, tt.want)
+		}
+	}
+}
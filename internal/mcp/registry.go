@@ -0,0 +1,470 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+// toolSettingsMessage is broadcast when a project's tool settings change
+const toolSettingsMessage = "mcp_tools_update"
+
+// ToolRequirement is the connection context a tool needs before it can run
+type ToolRequirement int
+
+const (
+	// RequiresAgent means the connection must name an agent (agent_id or an agent API key)
+	RequiresAgent ToolRequirement = 1 << iota
+	// RequiresProject means the connection must name a project
+	RequiresProject
+)
+
+// ToolHandler runs a tool call whose arguments already match the input schema.
+// It returns the structured result or an error, ideally a *ToolError.
+type ToolHandler func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (interface{}, error)
+
+// ToolDefinition is a tool served by the MCP endpoint
+type ToolDefinition struct {
+	Name         string
+	Description  string
+	InputSchema  InputSchema
+	OutputSchema *OutputSchema
+	Requires     ToolRequirement
+	// Access declares who may call the tool; tools without it are rejected
+	Access  ToolAccess
+	Handler ToolHandler
+	// Approval, when set, picks the calls that wait for a human's approval
+	Approval ApprovalPolicy
+}
+
+// tool is the definition as advertised by tools/list
+func (d *ToolDefinition) tool() Tool {
+	return Tool{Name: d.Name, Description: d.Description, InputSchema: d.InputSchema, OutputSchema: d.OutputSchema}
+}
+
+// ToolRegistry holds the tools of an MCP handler in registration order.
+// Tools can be added and removed while the server runs; every change is
+// reported to the registered listeners.
+type ToolRegistry struct {
+	mu        sync.RWMutex
+	tools     map[string]*ToolDefinition
+	order     []string
+	listeners []func()
+}
+
+// NewToolRegistry returns an empty registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]*ToolDefinition)}
+}
+
+// checkDefinition rejects tools that cannot be served
+func checkDefinition(def *ToolDefinition) error {
+	if def.Name == "" || def.Handler == nil {
+		return fmt.Errorf("tool needs a name and a handler")
+	}
+	if !def.Access.declared() {
+		return fmt.Errorf("tool %s declares no access rules", def.Name)
+	}
+	return nil
+}
+
+// Register adds a tool. Names must be unique and every tool must declare
+// its access rules.
+func (r *ToolRegistry) Register(def ToolDefinition) error {
+	if err := checkDefinition(&def); err != nil {
+		return err
+	}
+	if def.InputSchema.Type == "" {
+		def.InputSchema.Type = "object"
+	}
+
+	r.mu.Lock()
+	if _, exists := r.tools[def.Name]; exists {
+		r.mu.Unlock()
+		return fmt.Errorf("tool %s is already registered", def.Name)
+	}
+	r.tools[def.Name] = &def
+	r.order = append(r.order, def.Name)
+	r.mu.Unlock()
+
+	r.changed()
+	return nil
+}
+
+// Unregister removes a tool and reports whether it was registered
+func (r *ToolRegistry) Unregister(name string) bool {
+	r.mu.Lock()
+	if _, exists := r.tools[name]; !exists {
+		r.mu.Unlock()
+		return false
+	}
+	delete(r.tools, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i:i], r.order[i+1:]...)
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	r.changed()
+	return true
+}
+
+// Replace unregisters the named tools and registers defs in their place as
+// one change, so listeners hear about it once. Nothing changes when one of
+// defs clashes with a tool that is kept.
+func (r *ToolRegistry) Replace(remove []string, defs []ToolDefinition) error {
+	removed := make(map[string]bool, len(remove))
+	for _, name := range remove {
+		removed[name] = true
+	}
+
+	r.mu.Lock()
+	seen := make(map[string]bool, len(defs))
+	for i := range defs {
+		def := &defs[i]
+		if err := checkDefinition(def); err != nil {
+			r.mu.Unlock()
+			return err
+		}
+		if _, exists := r.tools[def.Name]; (exists && !removed[def.Name]) || seen[def.Name] {
+			r.mu.Unlock()
+			return fmt.Errorf("tool %s is already registered", def.Name)
+		}
+		seen[def.Name] = true
+		if def.InputSchema.Type == "" {
+			def.InputSchema.Type = "object"
+		}
+	}
+
+	order := r.order[:0:0]
+	for _, name := range r.order {
+		if removed[name] {
+			delete(r.tools, name)
+		} else {
+			order = append(order, name)
+		}
+	}
+	for i := range defs {
+		def := defs[i]
+		r.tools[def.Name] = &def
+		order = append(order, def.Name)
+	}
+	r.order = order
+	r.mu.Unlock()
+
+	r.changed()
+	return nil
+}
+
+// Lookup returns the tool with the given name
+func (r *ToolRegistry) Lookup(name string) (*ToolDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.tools[name]
+	return def, ok
+}
+
+// List returns the registered tools in registration order
+func (r *ToolRegistry) List() []*ToolDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defs := make([]*ToolDefinition, 0, len(r.order))
+	for _, name := range r.order {
+		defs = append(defs, r.tools[name])
+	}
+	return defs
+}
+
+// OnChange registers fn to be called after a tool is added or removed
+func (r *ToolRegistry) OnChange(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+func (r *ToolRegistry) changed() {
+	r.mu.RLock()
+	listeners := append([]func(){}, r.listeners...)
+	r.mu.RUnlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// builtinTools are the tools every handler starts with
+func builtinTools() []ToolDefinition {
+	var defs []ToolDefinition
+	for _, group := range [][]ToolDefinition{
+		identityTools, taskTools, projectTools, agentTools, roleTools,
+		dependencyTools, historyTools, subtaskTools, commentTools,
+		contextTools, a2aTools,
+	} {
+		defs = append(defs, group...)
+	}
+	return defs
+}
+
+// Tools returns the handler's tool registry, to add or remove tools
+func (h *MCPHandler) Tools() *ToolRegistry {
+	return h.tools
+}
+
+func (h *MCPHandler) handleToolsList(ctx MCPContext) (interface{}, *JSONRPCError) {
+	disabled := h.disabledTools(settingsFor(ctx, nil))
+
+	tools := []Tool{}
+	for _, def := range h.tools.List() {
+		if !disabled[def.Name] {
+			tools = append(tools, def.tool())
+		}
+	}
+
+	return ToolsListResult{Tools: tools}, nil
+}
+
+func (h *MCPHandler) handleToolsCall(params json.RawMessage, ctx MCPContext) (interface{}, *JSONRPCError) {
+	var callParams ToolCallParams
+	if err := json.Unmarshal(params, &callParams); err != nil {
+		return nil, &JSONRPCError{
+			Code:    -32602,
+			Message: "Invalid params",
+			Data:    err.Error(),
+		}
+	}
+
+	log.Printf("MCP Tool Call: %s with args %v (project=%s, agent=%s)", callParams.Name, callParams.Arguments, ctx.ProjectID, ctx.AgentID)
+
+	def, ok := h.tools.Lookup(callParams.Name)
+	if !ok {
+		return nil, &JSONRPCError{
+			Code:    -32601,
+			Message: "Unknown tool",
+			Data:    fmt.Sprintf("Tool not found: %s", callParams.Name),
+		}
+	}
+	if def.Requires&RequiresAgent != 0 && ctx.AgentID == "" {
+		return toolResult(nil, errNoAgent), nil
+	}
+	if def.Requires&RequiresProject != 0 && ctx.ProjectID == "" {
+		return toolResult(nil, errNoProject), nil
+	}
+	if err := validateArguments(def.InputSchema, callParams.Arguments); err != nil {
+		return toolResult(nil, err), nil
+	}
+
+	args := callParams.Arguments
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	// The project the call acts on decides both the tool settings that apply
+	// and the role it needs
+	scope, denied := h.toolScope(def, args, ctx)
+	if denied != nil {
+		return toolResult(nil, denied), nil
+	}
+	if h.disabledTools(settingsFor(ctx, scope))[def.Name] {
+		return toolResult(nil, &ToolError{Code: CodePermissionDenied, Message: fmt.Sprintf("Tool %s is disabled for this project or agent", def.Name)}), nil
+	}
+
+	// Enforce project roles before running the tool
+	if denied := h.authorizeScope(def, scope, ctx); denied != nil {
+		return toolResult(nil, denied), nil
+	}
+
+	if callParams.Meta != nil {
+		ctx.progressToken = callParams.Meta.ProgressToken
+	}
+
+	// Hold calls that need a human's approval until it is given
+	if denied := h.approve(def, args, ctx); denied != nil {
+		return toolResult(nil, denied), nil
+	}
+
+	value, err := def.Handler(h, args, ctx)
+	if raw, ok := value.(rawToolResult); ok && err == nil {
+		// Results of proxied tools are passed on as the downstream server sent them
+		return json.RawMessage(raw), nil
+	}
+	return toolResult(value, err), nil
+}
+
+// settingsFor returns the project and agent whose tool settings apply to a
+// call: the project the call acts on when it has a scope, and otherwise the
+// connection's. Agent keys are held to their own agent and home project, so
+// query parameters and headers cannot pick other settings.
+func settingsFor(ctx MCPContext, scope *ToolScope) (projectID, agentID uuid.UUID) {
+	projectID, agentID = parseID(ctx.ProjectID), parseID(ctx.AgentID)
+	if p := ctx.Principal; p != nil && p.Kind == auth.PrincipalAgent {
+		projectID, agentID = p.ProjectID, p.AgentID
+	}
+	if scope != nil && scope.ProjectID != uuid.Nil {
+		projectID = scope.ProjectID
+	}
+	return projectID, agentID
+}
+
+// disabledTools returns the tools switched off for a project and agent.
+// A setting for the agent overrides the one for its project.
+func (h *MCPHandler) disabledTools(projectID, agentID uuid.UUID) map[string]bool {
+	if h.db == nil || (projectID == uuid.Nil && agentID == uuid.Nil) {
+		return nil
+	}
+
+	rows, err := h.db.Query(`
+		SELECT tool_name, enabled, agent_id IS NOT NULL
+		FROM mcp_tool_settings
+		WHERE (project_id = $1 AND agent_id IS NULL) OR agent_id = $2
+		ORDER BY agent_id NULLS FIRST
+	`, projectID, agentID)
+	if err != nil {
+		log.Printf("Failed to load MCP tool settings: %v", err)
+		return nil
+	}
+	defer rows.Close()
+
+	disabled := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var enabled, forAgent bool
+		if err := rows.Scan(&name, &enabled, &forAgent); err != nil {
+			log.Printf("Failed to scan MCP tool setting: %v", err)
+			return nil
+		}
+		disabled[name] = !enabled
+	}
+	return disabled
+}
+
+// notifyToolsChanged tells every session whose tool list may have changed to
+// fetch it again. A nil project reaches every session.
+func (h *MCPHandler) notifyToolsChanged(projectID, agentID uuid.UUID) {
+	h.sessions.Range(func(_, value interface{}) bool {
+		s := value.(*Session)
+		if projectID == uuid.Nil || parseID(s.ProjectID) == projectID || (agentID != uuid.Nil && parseID(s.AgentID) == agentID) {
+			s.Notify("notifications/tools/list_changed", nil)
+		}
+		return true
+	})
+}
+
+// toolSettingsChanged is registered with the hub and forwards tool settings
+// changed through the REST API to the sessions they affect
+func (h *MCPHandler) toolSettingsChanged(projectID uuid.UUID, messageType string, payload interface{}) {
+	if messageType != toolSettingsMessage || projectID == uuid.Nil {
+		return
+	}
+	h.notifyToolsChanged(projectID, fieldID(payloadFields(payload), "agent_id"))
+}
+
+// validateArguments checks tool arguments against the tool's input schema:
+// required arguments must be present, and every known argument must have the
+// declared type and, for enums, one of the allowed values
+func validateArguments(schema InputSchema, args map[string]interface{}) error {
+	for _, name := range schema.Required {
+		if v, ok := args[name]; !ok || v == nil {
+			return invalidArgument("%s is required", name)
+		}
+	}
+	for name, value := range args {
+		prop, ok := schema.Properties[name].(map[string]interface{})
+		if !ok || value == nil {
+			continue
+		}
+		if err := validateValue(name, prop, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateValue(path string, schema map[string]interface{}, value interface{}) error {
+	typ, _ := schema["type"].(string)
+	if typ != "" && !hasType(typ, value) {
+		return invalidArgument("%s must be of type %s", path, typ)
+	}
+
+	if enum := schemaStrings(schema["enum"]); len(enum) > 0 {
+		s, _ := value.(string)
+		if !contains(enum, s) {
+			return invalidArgument("%s must be one of: %v", path, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateValue(fmt.Sprintf("%s[%d]", path, i), items, item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				return invalidArgument("%s.%s is required", path, name)
+			}
+		}
+		for name, field := range v {
+			if prop, ok := props[name].(map[string]interface{}); ok && field != nil {
+				if err := validateValue(path+"."+name, prop, field); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hasType reports whether a decoded JSON value has the JSON Schema type typ
+func hasType(typ string, value interface{}) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == float64(int64(n))
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+// schemaStrings reads a list of strings from a schema keyword
+func schemaStrings(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		return stringList(list)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
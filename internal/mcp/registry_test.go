@@ -0,0 +1,242 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+func TestValidateArguments(t *testing.T) {
+	schema := InputSchema{
+		Type: "object",
+		Properties: map[string]interface{}{
+			"title":    map[string]interface{}{"type": "string"},
+			"priority": map[string]interface{}{"type": "string", "enum": []string{"low", "medium", "high"}},
+			"timeout":  map[string]interface{}{"type": "integer"},
+			"tags":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"auto_assign": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"role": map[string]interface{}{"type": "string"}},
+			},
+		},
+		Required: []string{"title"},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+	}{
+		{"valid", map[string]interface{}{"title": "x", "priority": "high", "timeout": 30.0, "tags": []interface{}{"a"}}, false},
+		{"unknown arguments are ignored", map[string]interface{}{"title": "x", "extra": 1.0}, false},
+		{"null optional argument", map[string]interface{}{"title": "x", "priority": nil}, false},
+		{"missing required", map[string]interface{}{}, true},
+		{"null required", map[string]interface{}{"title": nil}, true},
+		{"wrong type", map[string]interface{}{"title": 5.0}, true},
+		{"not in enum", map[string]interface{}{"title": "x", "priority": "urgent"}, true},
+		{"fractional integer", map[string]interface{}{"title": "x", "timeout": 1.5}, true},
+		{"wrong item type", map[string]interface{}{"title": "x", "tags": []interface{}{"a", 2.0}}, true},
+		{"wrong nested type", map[string]interface{}{"title": "x", "auto_assign": map[string]interface{}{"role": true}}, true},
+	}
+
+	for _, tt := range tests {
+		err := validateArguments(schema, tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateArguments() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && asToolError(err).Code != CodeInvalidArgument {
+			t.Errorf("%s: expected an invalid_argument error, got %v", tt.name, asToolError(err).Code)
+		}
+	}
+}
+
+func TestToolRegistry(t *testing.T) {
+	r := NewToolRegistry()
+	changes := 0
+	r.OnChange(func() { changes++ })
+
+	echo := func(h *MCPHandler, args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+		return args, nil
+	}
+	global := ToolAccess{Global: true}
+	if err := r.Register(ToolDefinition{Name: "echo", Access: global, Handler: echo}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := r.Register(ToolDefinition{Name: "echo", Access: global, Handler: echo}); err == nil {
+		t.Error("Expected registering a duplicate name to fail")
+	}
+	if err := r.Register(ToolDefinition{Name: "no_handler", Access: global}); err == nil {
+		t.Error("Expected registering a tool without a handler to fail")
+	}
+	if err := r.Register(ToolDefinition{Name: "no_access", Handler: echo}); err == nil {
+		t.Error("Expected registering a tool without access rules to fail")
+	}
+	if err := r.Register(ToolDefinition{Name: "no_project", Access: ToolAccess{Action: auth.ActionRead}, Handler: echo}); err == nil {
+		t.Error("Expected registering a tool with an action but no project to fail")
+	}
+	r.Register(ToolDefinition{Name: "second", Access: global, Handler: echo})
+
+	if defs := r.List(); len(defs) != 2 || defs[0].Name != "echo" || defs[1].Name != "second" {
+		t.Errorf("Expected echo and second in registration order, got %v", defs)
+	}
+	if def, _ := r.Lookup("echo"); def.InputSchema.Type != "object" {
+		t.Errorf("Expected the input schema type to default to object, got %q", def.InputSchema.Type)
+	}
+
+	if !r.Unregister("echo") || r.Unregister("echo") {
+		t.Error("Expected echo to be removed exactly once")
+	}
+	if _, ok := r.Lookup("echo"); ok {
+		t.Error("Expected echo to be gone after Unregister")
+	}
+	if changes != 3 {
+		t.Errorf("Expected 3 change notifications, got %d", changes)
+	}
+}
+
+func TestToolsCallRequiresContext(t *testing.T) {
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	params, _ := json.Marshal(ToolCallParams{Name: "heartbeat"})
+	result, rpcErr := h.handleToolsCall(params, MCPContext{})
+	if rpcErr != nil {
+		t.Fatalf("tools/call failed: %v", rpcErr.Message)
+	}
+	res := result.(ToolResult)
+	if !res.IsError || res.StructuredContent.(*ToolError).Code != CodeNotConfigured {
+		t.Errorf("Expected a not_configured error without an agent, got %+v", res.StructuredContent)
+	}
+
+	params, _ = json.Marshal(ToolCallParams{Name: "update_task_status", Arguments: map[string]interface{}{"task_id": "x", "status": "nope"}})
+	result, _ = h.handleToolsCall(params, MCPContext{})
+	if err := result.(ToolResult).StructuredContent.(*ToolError); err.Code != CodeInvalidArgument {
+		t.Errorf("Expected an invalid status to be rejected before the tool runs, got %+v", err)
+	}
+}
+
+func TestToolsListChanged(t *testing.T) {
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	projectID, agentID := uuid.New(), uuid.New()
+
+	inProject := newSession(MCPContext{ProjectID: projectID.String()})
+	asAgent := newSession(MCPContext{AgentID: agentID.String()})
+	other := newSession(MCPContext{ProjectID: uuid.New().String()})
+	for _, s := range []*Session{inProject, asAgent, other} {
+		h.sessions.Store(s.ID, s)
+	}
+
+	h.toolSettingsChanged(projectID, toolSettingsMessage, map[string]interface{}{"agent_id": agentID.String()})
+	for s, want := range map[*Session]int{inProject: 1, asAgent: 1, other: 0} {
+		if events, _ := s.pending(nil, 0); len(events) != want {
+			t.Errorf("Expected %d notifications for session %s, got %d", want, s.ID, len(events))
+		}
+	}
+
+	h.Tools().Register(ToolDefinition{Name: "extra", Access: ToolAccess{Global: true}, Handler: (*MCPHandler).executeGetMyIdentity})
+	events, _ := other.pending(nil, 0)
+	if len(events) != 1 {
+		t.Fatalf("Expected registering a tool to notify every session, got %d", len(events))
+	}
+	var msg JSONRPCNotification
+	json.Unmarshal(events[0].Data, &msg)
+	if msg.Method != "notifications/tools/list_changed" {
+		t.Errorf("Expected a tools/list_changed notification, got %s", msg.Method)
+	}
+}
+
+func TestAuthorizeTool(t *testing.T) {
+	h := NewMCPHandler(nil, nil, auth.NewService(nil, auth.Config{}), nil, nil, nil, nil, nil, nil)
+	home, other := uuid.New(), uuid.New()
+	agent := &auth.Principal{Kind: auth.PrincipalAgent, AgentID: uuid.New(), ProjectID: home}
+
+	tests := []struct {
+		name string
+		tool string
+		args map[string]interface{}
+		ctx  MCPContext
+		want ToolErrorCode
+	}{
+		{"own project", "get_my_project", nil, MCPContext{ProjectID: home.String(), Principal: agent}, ""},
+		{"another project", "get_my_project", nil, MCPContext{ProjectID: other.String(), Principal: agent}, CodePermissionDenied},
+		{"named project", "get_task_graph", map[string]interface{}{"project_id": other.String()}, MCPContext{ProjectID: home.String(), Principal: agent}, CodePermissionDenied},
+		{"listing without a project", "list_tasks", nil, MCPContext{Principal: agent}, CodeInvalidArgument},
+		{"global tool", "get_my_identity", nil, MCPContext{Principal: agent}, ""},
+		{"task without a database", "complete_task", map[string]interface{}{"task_id": uuid.NewString()}, MCPContext{Principal: agent}, CodeUnavailable},
+		{"history without a database", "get_task_history", map[string]interface{}{"task_id": uuid.NewString()}, MCPContext{Principal: agent}, CodeUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, _ := h.Tools().Lookup(tt.tool)
+			args := tt.args
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+			var got ToolErrorCode
+			if err := h.authorizeTool(def, args, tt.ctx); err != nil {
+				got = err.Code
+			}
+			if got != tt.want {
+				t.Errorf("authorizeTool(%s) = %q, want %q", tt.tool, got, tt.want)
+			}
+		})
+	}
+
+	// Listings are scoped to the connection's project
+	args := map[string]interface{}{}
+	if err := h.authorizeTool(&ToolDefinition{Name: "list_tasks", Access: ToolAccess{Action: auth.ActionRead, Project: listProject}}, args, MCPContext{ProjectID: home.String(), Principal: agent}); err != nil || args["project_id"] != home.String() {
+		t.Errorf("Expected list_tasks to be scoped to the home project, got %v and %v", err, args)
+	}
+
+	if err := h.authorizeTool(&ToolDefinition{Name: "undeclared"}, args, MCPContext{}); err == nil || err.Code != CodeInternal {
+		t.Errorf("Expected a tool without access rules to be refused, got %v", err)
+	}
+
+	// A call is never allowed without a scope
+	unscoped := &ToolDefinition{Name: "unscoped", Access: ToolAccess{Action: auth.ActionRead, Project: func(*MCPHandler, map[string]interface{}, MCPContext) (*ToolScope, *ToolError) {
+		return nil, nil
+	}}}
+	if err := h.authorizeTool(unscoped, args, MCPContext{Principal: agent}); err == nil {
+		t.Error("Expected a call without a scope to be refused")
+	}
+
+	// Listing every project is left to admins, and to anonymous callers on open servers
+	listAll := &ToolDefinition{Name: "list_tasks", Access: ToolAccess{Action: auth.ActionRead, Project: listProject}}
+	if err := h.authorizeTool(listAll, map[string]interface{}{}, MCPContext{Principal: &auth.Principal{Kind: auth.PrincipalAdmin}}); err != nil {
+		t.Errorf("Expected an admin to list every project, got %v", err)
+	}
+	if err := h.authorizeTool(listAll, map[string]interface{}{}, MCPContext{}); err != nil {
+		t.Errorf("Expected anonymous callers to list every project on an open server, got %v", err)
+	}
+	required := NewMCPHandler(nil, nil, auth.NewService(nil, auth.Config{Required: true}), nil, nil, nil, nil, nil, nil)
+	if err := required.authorizeTool(listAll, map[string]interface{}{}, MCPContext{}); err == nil || err.Code != CodePermissionDenied {
+		t.Errorf("Expected anonymous callers to be refused when authentication is required, got %v", err)
+	}
+}
+
+func TestSettingsFor(t *testing.T) {
+	home, other, spoofed := uuid.New(), uuid.New(), uuid.New()
+	agent := &auth.Principal{Kind: auth.PrincipalAgent, AgentID: uuid.New(), ProjectID: home}
+
+	tests := []struct {
+		name        string
+		ctx         MCPContext
+		scope       *ToolScope
+		wantProject uuid.UUID
+		wantAgent   uuid.UUID
+	}{
+		{"connection", MCPContext{ProjectID: other.String(), AgentID: spoofed.String()}, nil, other, spoofed},
+		{"agent key ignores the connection", MCPContext{ProjectID: spoofed.String(), AgentID: spoofed.String(), Principal: agent}, nil, home, agent.AgentID},
+		{"scope of the call", MCPContext{ProjectID: spoofed.String(), Principal: agent}, &ToolScope{ProjectID: other}, other, agent.AgentID},
+		{"listing every project", MCPContext{Principal: agent}, &ToolScope{}, home, agent.AgentID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projectID, agentID := settingsFor(tt.ctx, tt.scope)
+			if projectID != tt.wantProject || agentID != tt.wantAgent {
+				t.Errorf("settingsFor() = %s, %s, want %s, %s", projectID, agentID, tt.wantProject, tt.wantAgent)
+			}
+		})
+	}
+}
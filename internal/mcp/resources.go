@@ -0,0 +1,402 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+const (
+	resourceScheme  = "agent-shaker://"
+	markdownMime    = "text/markdown"
+	projectResource = "projects"
+	taskResource    = "tasks"
+	contextResource = "contexts"
+)
+
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ResourceTemplatesListResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+// errResourceNotFound is returned by the markdown renderers for missing rows
+var errResourceNotFound = errors.New("resource not found")
+
+func (h *MCPHandler) handleResourceTemplatesList() (interface{}, *JSONRPCError) {
+	templates := []ResourceTemplate{
+		{
+			URITemplate: resourceScheme + projectResource + "/{id}",
+			Name:        "Project",
+			Description: "A project with its agents, task counts and recent contexts",
+			MimeType:    markdownMime,
+		},
+		{
+			URITemplate: resourceScheme + taskResource + "/{id}",
+			Name:        "Task",
+			Description: "A task with its dependencies, subtasks, comments and linked contexts",
+			MimeType:    markdownMime,
+		},
+		{
+			URITemplate: resourceScheme + contextResource + "/{id}",
+			Name:        "Context",
+			Description: "A shared context document",
+			MimeType:    markdownMime,
+		},
+	}
+
+	return ResourceTemplatesListResult{ResourceTemplates: templates}, nil
+}
+
+// parseResourceURI splits a per-entity URI such as agent-shaker://tasks/{id}
+func parseResourceURI(uri string) (string, uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(uri, resourceScheme)
+	if !ok {
+		return "", uuid.Nil, false
+	}
+	kind, rawID, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", uuid.Nil, false
+	}
+	switch kind {
+	case projectResource, taskResource, contextResource:
+	default:
+		return "", uuid.Nil, false
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return "", uuid.Nil, false
+	}
+	return kind, id, true
+}
+
+func resourceURI(kind string, id uuid.UUID) string {
+	return resourceScheme + kind + "/" + id.String()
+}
+
+// projectResource describes the caller's project, or returns nil when the
+// connection names no project the caller may read
+func (h *MCPHandler) projectResource(ctx MCPContext) (*Resource, error) {
+	projectID := parseID(ctx.ProjectID)
+	if h.db == nil || projectID == uuid.Nil {
+		return nil, nil
+	}
+	if h.auth != nil && h.check(ctx, projectID, auth.ActionRead) != nil {
+		return nil, nil
+	}
+
+	var name string
+	err := h.db.QueryRow("SELECT name FROM projects WHERE id = $1", projectID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &Resource{
+		URI:         resourceURI(projectResource, projectID),
+		Name:        name,
+		Description: "The current project",
+		MimeType:    markdownMime,
+	}, nil
+}
+
+// contextResources lists one page of a project's contexts, newest first
+func (h *MCPHandler) contextResources(projectID uuid.UUID, pg *page) ([]Resource, error) {
+	queryArgs := []interface{}{projectID}
+	rows, err := h.db.Query("SELECT id, title, created_at FROM contexts WHERE project_id = $1"+pg.clause("", &queryArgs), queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var resources []Resource
+	for rows.Next() {
+		var id uuid.UUID
+		var title string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &title, &createdAt); err != nil {
+			return nil, err
+		}
+		if !pg.keep(createdAt, id.String()) {
+			break
+		}
+		resources = append(resources, Resource{URI: resourceURI(contextResource, id), Name: title, MimeType: markdownMime})
+	}
+	return resources, rows.Err()
+}
+
+// readEntityResource renders a per-entity resource as markdown after
+// checking that the caller may read its project
+func (h *MCPHandler) readEntityResource(ctx MCPContext, uri, kind string, id uuid.UUID) (interface{}, *JSONRPCError) {
+	if rpcErr := h.authorizeResource(ctx, uri, kind, id); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var text string
+	var err error
+	switch kind {
+	case projectResource:
+		text, err = h.projectMarkdown(id)
+	case taskResource:
+		text, err = h.taskMarkdown(id)
+	case contextResource:
+		text, err = h.contextMarkdown(id)
+	}
+	if err == errResourceNotFound {
+		return nil, &JSONRPCError{Code: -32602, Message: "Unknown resource", Data: fmt.Sprintf("Resource not found: %s", uri)}
+	} else if err != nil {
+		return nil, &JSONRPCError{Code: -32000, Message: "Resource read failed", Data: err.Error()}
+	}
+
+	return ResourcesReadResult{
+		Contents: []ResourceContent{{URI: uri, MimeType: markdownMime, Text: text}},
+	}, nil
+}
+
+func (h *MCPHandler) projectMarkdown(id uuid.UUID) (string, error) {
+	var name, description, status string
+	err := h.db.QueryRow("SELECT name, COALESCE(description, ''), COALESCE(status, '') FROM projects WHERE id = $1", id).Scan(&name, &description, &status)
+	if err == sql.ErrNoRows {
+		return "", errResourceNotFound
+	} else if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", name)
+	fmt.Fprintf(&b, "- Status: %s\n- ID: %s\n", status, id)
+	if description != "" {
+		fmt.Fprintf(&b, "\n%s\n", description)
+	}
+
+	b.WriteString("\n## Tasks\n\n")
+	rows, err := h.db.Query("SELECT status, COUNT(*) FROM tasks WHERE project_id = $1 GROUP BY status ORDER BY status", id)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	total := 0
+	for rows.Next() {
+		var taskStatus string
+		var count int
+		if err := rows.Scan(&taskStatus, &count); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "- %s: %d\n", taskStatus, count)
+		total += count
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if total == 0 {
+		b.WriteString("No tasks yet.\n")
+	}
+
+	b.WriteString("\n## Agents\n\n")
+	agents, err := h.db.Query("SELECT name, COALESCE(role, ''), COALESCE(status, '') FROM agents WHERE project_id = $1 ORDER BY name", id)
+	if err != nil {
+		return "", err
+	}
+	defer agents.Close()
+	count := 0
+	for agents.Next() {
+		var agentName, role, agentStatus string
+		if err := agents.Scan(&agentName, &role, &agentStatus); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "- %s (%s, %s)\n", agentName, role, agentStatus)
+		count++
+	}
+	if err := agents.Err(); err != nil {
+		return "", err
+	}
+	if count == 0 {
+		b.WriteString("No agents yet.\n")
+	}
+
+	contexts, err := h.db.Query("SELECT id, title FROM contexts WHERE project_id = $1 ORDER BY created_at DESC LIMIT 20", id)
+	if err != nil {
+		return "", err
+	}
+	defer contexts.Close()
+	heading := false
+	for contexts.Next() {
+		var contextID uuid.UUID
+		var title string
+		if err := contexts.Scan(&contextID, &title); err != nil {
+			return "", err
+		}
+		if !heading {
+			b.WriteString("\n## Recent contexts\n\n")
+			heading = true
+		}
+		fmt.Fprintf(&b, "- [%s](%s)\n", title, resourceURI(contextResource, contextID))
+	}
+	return b.String(), contexts.Err()
+}
+
+func (h *MCPHandler) taskMarkdown(id uuid.UUID) (string, error) {
+	var t models.Task
+	var assignee sql.NullString
+	err := h.db.QueryRow(`
+		SELECT t.title, COALESCE(t.description, ''), t.status, t.priority, COALESCE(t.output, ''), t.parent_id, t.due_at, t.overdue_at,
+		       t.project_id, t.created_at, t.updated_at, a.name
+		FROM tasks t
+		LEFT JOIN agents a ON a.id = t.assigned_to
+		WHERE t.id = $1
+	`, id).Scan(&t.Title, &t.Description, &t.Status, &t.Priority, &t.Output, &t.ParentID, &t.DueAt, &t.OverdueAt,
+		&t.ProjectID, &t.CreatedAt, &t.UpdatedAt, &assignee)
+	if err == sql.ErrNoRows {
+		return "", errResourceNotFound
+	} else if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", t.Title)
+	fmt.Fprintf(&b, "- Status: %s\n- Priority: %s\n", t.Status, t.Priority)
+	if assignee.Valid {
+		fmt.Fprintf(&b, "- Assigned to: %s\n", assignee.String)
+	} else {
+		b.WriteString("- Assigned to: nobody\n")
+	}
+	if t.DueAt != nil {
+		fmt.Fprintf(&b, "- Due: %s", t.DueAt.Format(time.RFC3339))
+		if t.OverdueAt != nil {
+			b.WriteString(" (overdue)")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "- Project: %s\n", resourceURI(projectResource, t.ProjectID))
+	if t.ParentID != nil {
+		fmt.Fprintf(&b, "- Parent task: %s\n", resourceURI(taskResource, *t.ParentID))
+	}
+	fmt.Fprintf(&b, "- Updated: %s\n", t.UpdatedAt.Format(time.RFC3339))
+
+	if t.Description != "" {
+		fmt.Fprintf(&b, "\n## Description\n\n%s\n", t.Description)
+	}
+	if t.Output != "" {
+		fmt.Fprintf(&b, "\n## Output\n\n%s\n", t.Output)
+	}
+
+	if h.graph != nil {
+		dependsOn, dependents, err := h.graph.Neighbours(context.Background(), id)
+		if err != nil {
+			return "", err
+		}
+		writeTaskLinks(&b, "Depends on", dependsOn)
+		writeTaskLinks(&b, "Blocks", dependents)
+	}
+
+	subtasks, err := h.db.Query("SELECT id, title, status FROM tasks WHERE parent_id = $1 ORDER BY created_at", id)
+	if err != nil {
+		return "", err
+	}
+	defer subtasks.Close()
+	var nodes []models.TaskGraphNode
+	for subtasks.Next() {
+		var n models.TaskGraphNode
+		if err := subtasks.Scan(&n.ID, &n.Title, &n.Status); err != nil {
+			return "", err
+		}
+		nodes = append(nodes, n)
+	}
+	if err := subtasks.Err(); err != nil {
+		return "", err
+	}
+	writeTaskLinks(&b, "Subtasks", nodes)
+
+	if h.comments != nil {
+		threads, err := h.comments.List(context.Background(), id)
+		if err != nil {
+			return "", err
+		}
+		if len(threads) > 0 {
+			b.WriteString("\n## Comments\n\n")
+			writePromptComments(&b, threads, "")
+		}
+	}
+
+	contexts, err := h.db.Query("SELECT id, title, tags FROM contexts WHERE task_id = $1 ORDER BY created_at", id)
+	if err != nil {
+		return "", err
+	}
+	defer contexts.Close()
+	heading := false
+	for contexts.Next() {
+		var contextID uuid.UUID
+		var title string
+		var tags pq.StringArray
+		if err := contexts.Scan(&contextID, &title, &tags); err != nil {
+			return "", err
+		}
+		if !heading {
+			b.WriteString("\n## Linked contexts\n\n")
+			heading = true
+		}
+		fmt.Fprintf(&b, "- [%s](%s)", title, resourceURI(contextResource, contextID))
+		if len(tags) > 0 {
+			fmt.Fprintf(&b, " - %s", strings.Join(tags, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), contexts.Err()
+}
+
+// contextMarkdown returns a context document with a short metadata header;
+// the content itself is already markdown
+func (h *MCPHandler) contextMarkdown(id uuid.UUID) (string, error) {
+	var c models.Context
+	var author string
+	var taskID uuid.NullUUID
+	err := h.db.QueryRow(`
+		SELECT c.title, COALESCE(c.content, ''), c.tags, c.task_id, c.created_at, c.updated_at, COALESCE(a.name, '')
+		FROM contexts c
+		LEFT JOIN agents a ON a.id = c.agent_id
+		WHERE c.id = $1
+	`, id).Scan(&c.Title, &c.Content, &c.Tags, &taskID, &c.CreatedAt, &c.UpdatedAt, &author)
+	if err == sql.ErrNoRows {
+		return "", errResourceNotFound
+	} else if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", c.Title)
+	if author != "" {
+		fmt.Fprintf(&b, "- Author: %s\n", author)
+	}
+	fmt.Fprintf(&b, "- Updated: %s\n", c.UpdatedAt.Format(time.RFC3339))
+	if len(c.Tags) > 0 {
+		fmt.Fprintf(&b, "- Tags: %s\n", strings.Join(c.Tags, ", "))
+	}
+	if taskID.Valid {
+		fmt.Fprintf(&b, "- Task: %s\n", resourceURI(taskResource, taskID.UUID))
+	}
+	fmt.Fprintf(&b, "\n---\n\n%s\n", c.Content)
+	return b.String(), nil
+}
+
+func writeTaskLinks(b *strings.Builder, heading string, nodes []models.TaskGraphNode) {
+	if len(nodes) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n", heading)
+	for _, n := range nodes {
+		fmt.Fprintf(b, "- [%s](%s) - %s\n", n.Title, resourceURI(taskResource, n.ID), n.Status)
+	}
+}
@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+func TestParseResourceURI(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		uri    string
+		kind   string
+		wantOK bool
+	}{
+		{"agent-shaker://tasks/" + id.String(), taskResource, true},
+		{"agent-shaker://contexts/" + id.String(), contextResource, true},
+		{"agent-shaker://projects/" + id.String(), projectResource, true},
+		{"agent-shaker://tasks", "", false},
+		{"agent-shaker://tasks/not-a-uuid", "", false},
+		{"agent-shaker://agents/" + id.String(), "", false},
+		{"file:///tasks/" + id.String(), "", false},
+	}
+
+	for _, tt := range tests {
+		kind, got, ok := parseResourceURI(tt.uri)
+		if ok != tt.wantOK || kind != tt.kind {
+			t.Errorf("parseResourceURI(%q) = %q, %v, want %q, %v", tt.uri, kind, ok, tt.kind, tt.wantOK)
+		}
+		if ok && got != id {
+			t.Errorf("parseResourceURI(%q) returned ID %s, want %s", tt.uri, got, id)
+		}
+		if ok && resourceURI(kind, got) != tt.uri {
+			t.Errorf("resourceURI(%q, %s) does not round-trip", kind, got)
+		}
+	}
+}
+
+func TestVisibleProjectsFilter(t *testing.T) {
+	open := NewMCPHandler(nil, nil, auth.NewService(nil, auth.Config{}), nil, nil, nil, nil, nil, nil)
+	scope, err := open.visibleProjects(MCPContext{})
+	if err != nil {
+		t.Fatalf("visibleProjects failed: %v", err)
+	}
+	var queryArgs []interface{}
+	if filter := scope.filter("project_id", &queryArgs); filter != "" || len(queryArgs) != 0 {
+		t.Errorf("Expected anonymous callers of an open server to see every project, got %q", filter)
+	}
+
+	required := NewMCPHandler(nil, nil, auth.NewService(nil, auth.Config{Required: true}), nil, nil, nil, nil, nil, nil)
+	scope, _ = required.visibleProjects(MCPContext{})
+	queryArgs = []interface{}{"x"}
+	if filter := scope.filter("c.project_id", &queryArgs); filter != " AND c.project_id = ANY($2::uuid[])" || len(queryArgs) != 2 {
+		t.Errorf("Expected anonymous callers to be limited to their (no) projects, got %q with %v", filter, queryArgs)
+	}
+}
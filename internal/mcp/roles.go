@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// roleTools read the project's agent role vocabulary
+var roleTools = []ToolDefinition{
+	{
+		Name:        "list_agent_roles",
+		Description: "List the agent roles defined for a project",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"project_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The project ID (optional if project_id in MCP connection URL)",
+				},
+			},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"project_id": outputField("string", "Project ID"),
+			"roles":      outputList("Roles defined for the project, by name"),
+		}, "project_id", "roles"),
+		Access:  ToolAccess{Action: auth.ActionRead, Project: argProject("project_id", "")},
+		Handler: (*MCPHandler).executeListAgentRoles,
+	},
+}
+
+func (h *MCPHandler) executeListAgentRoles(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil {
+		return nil, errNoDatabase
+	}
+
+	projectID := argOrDefault(args, "project_id", ctx.ProjectID)
+	if projectID == "" {
+		return nil, invalidArgument("project_id is required")
+	}
+
+	rows, err := h.db.Query(`
+		SELECT project_id, name, description, created_at
+		FROM agent_roles
+		WHERE project_id = $1
+		ORDER BY name
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.AgentRoleDefinition{}
+	for rows.Next() {
+		var role models.AgentRoleDefinition
+		if err := rows.Scan(&role.ProjectID, &role.Name, &role.Description, &role.CreatedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return map[string]interface{}{
+		"project_id": projectID,
+		"roles":      roles,
+	}, nil
+}
+
+// stringList reads a tool argument that is a JSON array of strings
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package mcp
+
+import (
+	"encoding/json"
+
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// autoAssignSchema describes the auto_assign argument shared by task-creating tools
+var autoAssignSchema = map[string]interface{}{
+	"type":        "object",
+	"description": "Let the server pick the assignee using the project's assign strategy instead of assigning yourself",
+	"properties": map[string]interface{}{
+		"role":         map[string]interface{}{"type": "string", "description": "Required agent role"},
+		"team":         map[string]interface{}{"type": "string", "description": "Required agent team"},
+		"capabilities": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Capabilities the task needs, such as go or postgres"},
+	},
+}
+
+// autoAssignArg reads the optional auto_assign tool argument
+func autoAssignArg(args map[string]interface{}) (*models.AutoAssign, error) {
+	raw, ok := args["auto_assign"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	data, _ := json.Marshal(raw)
+	var req models.AutoAssign
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, invalidArgument("auto_assign must be an object with optional role, team and capabilities")
+	}
+	return &req, nil
+}
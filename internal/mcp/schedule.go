@@ -0,0 +1,31 @@
+package mcp
+
+import (
+	"time"
+
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// scheduleArgs reads the optional due_at (RFC 3339) and sla (duration such
+// as "4h") tool arguments
+func scheduleArgs(args map[string]interface{}) (*time.Time, *models.Duration, error) {
+	var dueAt *time.Time
+	if s, _ := args["due_at"].(string); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, nil, invalidArgument("due_at must be an RFC 3339 timestamp")
+		}
+		dueAt = &t
+	}
+
+	var sla *models.Duration
+	if s, _ := args["sla"].(string); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < time.Second {
+			return nil, nil, invalidArgument("sla must be a duration of at least one second, such as 30m or 4h")
+		}
+		v := models.Duration(d)
+		sla = &v
+	}
+	return dueAt, sla, nil
+}
@@ -0,0 +1,378 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+const (
+	// SessionHeader carries the session ID of the Streamable HTTP transport
+	SessionHeader = "Mcp-Session-Id"
+
+	// standaloneStream is the stream opened by GET for messages that are not
+	// replies to a particular request
+	standaloneStream = "standalone"
+
+	// maxSessionEvents bounds the events kept per session for resumption
+	maxSessionEvents = 1000
+	// sessionIdleTimeout is how long a session without open streams is kept
+	sessionIdleTimeout = 30 * time.Minute
+	// pingInterval keeps idle SSE streams from being closed by proxies
+	pingInterval = 30 * time.Second
+)
+
+// Protocol versions the server speaks, newest first
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSONRPCNotification is a server-to-client message that expects no reply
+type JSONRPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// sessionEvent is one SSE event. IDs increase across all streams of a
+// session so a Last-Event-ID identifies both the stream and the position.
+type sessionEvent struct {
+	ID     int64
+	Stream string
+	Data   []byte
+	// Final marks the response that ends a request stream
+	Final bool
+}
+
+// Session is an MCP session created by initialize. Messages for the client
+// are kept in an event log that open SSE streams follow, so a client that
+// reconnects with Last-Event-ID receives what it missed.
+type Session struct {
+	ID                 string
+	CreatedAt          time.Time
+	ClientInfo         map[string]interface{}
+	ClientCapabilities map[string]interface{}
+	ProtocolVersion    string
+	ProjectID          string
+	AgentID            string
+
+	// owner is the API key that created the session; only it may use the session
+	owner uuid.UUID
+
+	mu       sync.Mutex
+	seq      int64
+	streams  int64
+	events   []sessionEvent
+	wake     chan struct{}
+	done     chan struct{}
+	open     int
+	lastUsed time.Time
+	// delivered is the last standalone event written to a GET stream
+	delivered int64
+	// subscriptions are the resource URIs the client wants update notifications for
+	subscriptions map[string]bool
+	// inflight are the running requests by request ID, for cancellation
+	inflight map[string]*inflightRequest
+	// calls numbers the server's requests to the client, and waiting holds
+	// those still expecting a response by request ID
+	calls   int64
+	waiting map[string]chan *rpcReply
+}
+
+func newSession(ctx MCPContext) *Session {
+	now := time.Now()
+	s := &Session{
+		ID:        uuid.New().String(),
+		CreatedAt: now,
+		ProjectID: ctx.ProjectID,
+		AgentID:   ctx.AgentID,
+		wake:      make(chan struct{}),
+		done:      make(chan struct{}),
+		lastUsed:  now,
+	}
+	if ctx.Principal != nil {
+		s.owner = ctx.Principal.KeyID
+	}
+	return s
+}
+
+// Notify sends a notification to the client on the session's standalone stream
+func (s *Session) Notify(method string, params interface{}) error {
+	return s.send(standaloneStream, JSONRPCNotification{JSONRPC: "2.0", Method: method, Params: params}, false)
+}
+
+// send appends a message to the event log and wakes the streams following it
+func (s *Session) send(stream string, msg interface{}, final bool) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	s.appendEvent(sessionEvent{Stream: stream, Data: data, Final: final})
+	return nil
+}
+
+// end closes a request stream without a response, for requests cancelled by
+// the client
+func (s *Session) end(stream string) {
+	s.appendEvent(sessionEvent{Stream: stream, Final: true})
+}
+
+func (s *Session) appendEvent(e sessionEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	e.ID = s.seq
+	s.events = append(s.events, e)
+	if len(s.events) > maxSessionEvents {
+		s.events = append([]sessionEvent(nil), s.events[len(s.events)-maxSessionEvents:]...)
+	}
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// pending returns the events on streams (all streams when nil) after the
+// given ID, and a channel that is closed when more events arrive
+func (s *Session) pending(streams map[string]bool, after int64) ([]sessionEvent, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []sessionEvent
+	for _, e := range s.events {
+		if e.ID > after && (streams == nil || streams[e.Stream]) {
+			out = append(out, e)
+		}
+	}
+	return out, s.wake
+}
+
+// streamOf returns the stream an event was sent on, or "" if it is no longer kept
+func (s *Session) streamOf(eventID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.events {
+		if e.ID == eventID {
+			return e.Stream
+		}
+	}
+	return ""
+}
+
+// newStream names a stream for the reply to one POST
+func (s *Session) newStream() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.streams++
+	return "request-" + strconv.FormatInt(s.streams, 10)
+}
+
+// attach and detach count open streams so idle sessions can expire
+func (s *Session) attach() {
+	s.mu.Lock()
+	s.open++
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *Session) detach() {
+	s.mu.Lock()
+	s.open--
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *Session) touch() {
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *Session) idle(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.open == 0 && now.Sub(s.lastUsed) > sessionIdleTimeout
+}
+
+// close ends the session and every stream following it
+func (s *Session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
+
+// context fills the project and agent of a request from the session when
+// the request does not carry its own
+func (s *Session) context(ctx MCPContext) MCPContext {
+	if ctx.ProjectID == "" {
+		ctx.ProjectID = s.ProjectID
+	}
+	if ctx.AgentID == "" {
+		ctx.AgentID = s.AgentID
+	}
+	ctx.Session = s
+	ctx.stream = standaloneStream
+	return ctx
+}
+
+// startSession registers a new session, dropping sessions that have been idle too long
+func (h *MCPHandler) startSession(ctx MCPContext) *Session {
+	now := time.Now()
+	h.sessions.Range(func(key, value interface{}) bool {
+		if s := value.(*Session); s.idle(now) {
+			s.close()
+			h.sessions.Delete(key)
+		}
+		return true
+	})
+
+	s := newSession(ctx)
+	h.sessions.Store(s.ID, s)
+	return s
+}
+
+// lookupSession returns the session with id if it belongs to the caller
+func (h *MCPHandler) lookupSession(id string, principal *auth.Principal) (*Session, bool) {
+	value, ok := h.sessions.Load(id)
+	if !ok {
+		return nil, false
+	}
+	s := value.(*Session)
+
+	var owner uuid.UUID
+	if principal != nil {
+		owner = principal.KeyID
+	}
+	if s.owner != owner {
+		return nil, false
+	}
+	s.touch()
+	return s, true
+}
+
+// endSession removes a session and closes its streams
+func (h *MCPHandler) endSession(s *Session) {
+	s.close()
+	h.sessions.Delete(s.ID)
+}
+
+// handleDeleteSession ends the session named by the Mcp-Session-Id header
+func (h *MCPHandler) handleDeleteSession(w http.ResponseWriter, r *http.Request, ctx MCPContext) {
+	id := r.Header.Get(SessionHeader)
+	if id == "" {
+		http.Error(w, "Mcp-Session-Id header is required", http.StatusBadRequest)
+		return
+	}
+
+	s, ok := h.lookupSession(id, ctx.Principal)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	h.endSession(s)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeStream writes the session's events on streams after the given ID to w
+// as SSE. It returns when the client goes away, the session ends, or, when
+// untilFinal is set, after the final response has been written.
+func (h *MCPHandler) writeStream(w http.ResponseWriter, r *http.Request, s *Session, streams map[string]bool, after int64, untilFinal bool) {
+	flusher := w.(http.Flusher)
+
+	s.attach()
+	defer s.detach()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		events, wake := s.pending(streams, after)
+		for _, e := range events {
+			// A final event without data ends a stream whose request was cancelled
+			if e.Data != nil {
+				fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", e.ID, e.Data)
+			}
+			after = e.ID
+			if e.Stream == standaloneStream {
+				s.mu.Lock()
+				if e.ID > s.delivered {
+					s.delivered = e.ID
+				}
+				s.mu.Unlock()
+			}
+		}
+		flusher.Flush()
+
+		for _, e := range events {
+			if untilFinal && e.Final {
+				return
+			}
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-ticker.C:
+			w.Write([]byte(": ping\n\n"))
+			flusher.Flush()
+		case <-wake:
+		}
+	}
+}
+
+// resumeFrom picks where a GET stream starts: after the Last-Event-ID sent by
+// the client, which also resumes the stream that event belonged to, or after
+// the last standalone event a previous GET stream delivered
+func (s *Session) resumeFrom(r *http.Request) (map[string]bool, int64) {
+	streams := map[string]bool{standaloneStream: true}
+
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		if id, err := strconv.ParseInt(last, 10, 64); err == nil {
+			if stream := s.streamOf(id); stream != "" {
+				streams[stream] = true
+			}
+			return streams, id
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return streams, s.delivered
+}
+
+// negotiateVersion returns the client's protocol version when the server
+// supports it, and the newest supported version otherwise
+func negotiateVersion(requested string) string {
+	for _, v := range supportedProtocolVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return supportedProtocolVersions[0]
+}
+
+// acceptsEventStream reports whether the client accepts SSE responses
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func setStreamHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+}
@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+func TestSessionEventLog(t *testing.T) {
+	s := newSession(MCPContext{ProjectID: "p1"})
+	request := s.newStream()
+
+	s.Notify("notifications/message", nil)
+	s.send(request, JSONRPCResponse{JSONRPC: "2.0", ID: 1}, true)
+	s.Notify("notifications/message", nil)
+
+	events, _ := s.pending(map[string]bool{standaloneStream: true}, 0)
+	if len(events) != 2 || events[0].ID != 1 || events[1].ID != 3 {
+		t.Fatalf("Expected standalone events 1 and 3, got %+v", events)
+	}
+
+	events, _ = s.pending(map[string]bool{request: true}, 0)
+	if len(events) != 1 || !events[0].Final {
+		t.Fatalf("Expected the final response on %s, got %+v", request, events)
+	}
+	if got := s.streamOf(2); got != request {
+		t.Errorf("Expected event 2 to belong to %s, got %q", request, got)
+	}
+
+	// Resuming after event 2 replays the request stream and later standalone events
+	r := httptest.NewRequest("GET", "/mcp", nil)
+	r.Header.Set("Last-Event-ID", "2")
+	streams, after := s.resumeFrom(r)
+	if !streams[request] || !streams[standaloneStream] || after != 2 {
+		t.Errorf("Expected to resume %s and the standalone stream after 2, got %v after %d", request, streams, after)
+	}
+}
+
+func TestSessionEventLogIsBounded(t *testing.T) {
+	s := newSession(MCPContext{})
+	for i := 0; i < maxSessionEvents+10; i++ {
+		s.Notify("notifications/message", nil)
+	}
+
+	events, _ := s.pending(map[string]bool{standaloneStream: true}, 0)
+	if len(events) != maxSessionEvents || events[0].ID != 11 {
+		t.Errorf("Expected the last %d events starting at 11, got %d starting at %d", maxSessionEvents, len(events), events[0].ID)
+	}
+	if s.streamOf(1) != "" {
+		t.Error("Expected evicted events to be forgotten")
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	if got := negotiateVersion("2025-03-26"); got != "2025-03-26" {
+		t.Errorf("Expected a supported version to be kept, got %s", got)
+	}
+	if got := negotiateVersion("1999-01-01"); got != supportedProtocolVersions[0] {
+		t.Errorf("Expected the newest version for an unknown one, got %s", got)
+	}
+}
+
+func TestServerInfoMatchesInitialize(t *testing.T) {
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	h.handleServerInfo(w, httptest.NewRequest("GET", "/mcp", nil), MCPContext{})
+	var info struct {
+		Capabilities json.RawMessage `json:"capabilities"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &info)
+
+	result, _ := h.handleInitialize(nil, MCPContext{})
+	initialized, _ := json.Marshal(result.(InitializeResult).Capabilities)
+	if string(info.Capabilities) != string(initialized) {
+		t.Errorf("Expected the server info to advertise %s, got %s", initialized, info.Capabilities)
+	}
+}
+
+func TestNewMCPContextBindsAgentKeys(t *testing.T) {
+	home, other := uuid.New(), uuid.New()
+	agent := &auth.Principal{Kind: auth.PrincipalAgent, AgentID: uuid.New(), ProjectID: home}
+
+	ctx := NewMCPContext(other.String(), uuid.NewString(), agent)
+	if ctx.ProjectID != home.String() || ctx.AgentID != agent.AgentID.String() {
+		t.Errorf("Expected an agent key to act as itself in its home project, got project %s and agent %s", ctx.ProjectID, ctx.AgentID)
+	}
+
+	user := &auth.Principal{Kind: auth.PrincipalUser, UserID: uuid.New()}
+	if ctx := NewMCPContext(other.String(), "", user); ctx.ProjectID != other.String() {
+		t.Errorf("Expected a user to choose the project, got %s", ctx.ProjectID)
+	}
+}
@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/taskstate"
+)
+
+// taskStatusNames lists the canonical task statuses for tool schemas and errors
+func taskStatusNames() []string {
+	names := make([]string, len(models.TaskStatuses))
+	for i, s := range models.TaskStatuses {
+		names[i] = string(s)
+	}
+	return names
+}
+
+// transitionTask applies a status change through the task state machine,
+// returning a tool error when the change is not allowed
+func (h *MCPHandler) transitionTask(taskID string, change taskstate.Change) error {
+	id, err := uuid.Parse(taskID)
+	if err != nil {
+		return invalidArgument("invalid task_id")
+	}
+
+	err = taskstate.Update(context.Background(), h.db, id, change)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, taskstate.ErrTaskNotFound):
+		return notFound("Task not found")
+	case errors.Is(err, taskstate.ErrBlocked):
+		return h.blockedConflict(id)
+	case errors.Is(err, taskstate.ErrInvalidStatus):
+		return invalidArgument("invalid status, must be one of: %s", strings.Join(taskStatusNames(), ", "))
+	default:
+		// Transition errors are classified as conflicts by asToolError
+		return err
+	}
+}
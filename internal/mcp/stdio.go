@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxStdioMessage bounds a single JSON-RPC message read from stdin
+const maxStdioMessage = 10 * 1024 * 1024
+
+// ServeStdio runs the MCP stdio transport for one client: newline-delimited
+// JSON-RPC messages or batches are read from in, and responses and
+// notifications are written to out one per line. Requests run concurrently,
+// so a slow tool does not hold up the rest, and notifications/cancelled stops
+// a request that is still running. It returns once in is exhausted and every request
+// has been answered, or when ctx is cancelled.
+func (h *MCPHandler) ServeStdio(ctx context.Context, in io.Reader, out io.Writer, mctx MCPContext) error {
+	session := newSession(mctx)
+	mctx = session.context(mctx)
+
+	// Register the session so it receives resource update notifications. The
+	// session counts as attached for as long as stdout is being written.
+	h.sessions.Store(session.ID, session)
+	defer h.sessions.Delete(session.ID)
+	session.attach()
+	defer session.detach()
+
+	written := make(chan error, 1)
+	go func() {
+		written <- copyEvents(ctx, session, out)
+	}()
+
+	var requests sync.WaitGroup
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxStdioMessage)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		payload, rpcErr := parsePayload(line)
+		if rpcErr != nil {
+			session.send(standaloneStream, JSONRPCResponse{JSONRPC: "2.0", Error: rpcErr}, false)
+			continue
+		}
+
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			if reply := h.run(ctx, payload, mctx); reply != nil {
+				session.send(standaloneStream, reply, false)
+			}
+		}()
+	}
+
+	requests.Wait()
+	session.close()
+	if err := <-written; err != nil {
+		return err
+	}
+	return scanner.Err()
+}
+
+// copyEvents writes every message sent on the session to out, one per line,
+// until the session is closed and all of its messages have been written
+func copyEvents(ctx context.Context, s *Session, out io.Writer) error {
+	var after int64
+	for {
+		events, wake := s.pending(nil, after)
+		for _, e := range events {
+			if _, err := fmt.Fprintf(out, "%s\n", e.Data); err != nil {
+				return fmt.Errorf("failed to write message: %w", err)
+			}
+			after = e.ID
+		}
+		if len(events) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			if events, _ := s.pending(nil, after); len(events) == 0 {
+				return nil
+			}
+		case <-wake:
+		}
+	}
+}
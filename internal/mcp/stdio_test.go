@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestServeStdio(t *testing.T) {
+	h := NewMCPHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	in := strings.NewReader(strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		``,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		`not json`,
+		`[{"jsonrpc":"2.0","id":3,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}]`,
+	}, "\n"))
+
+	var out bytes.Buffer
+	if err := h.ServeStdio(context.Background(), in, &out, MCPContext{ProjectID: "p1"}); err != nil {
+		t.Fatalf("ServeStdio() error = %v", err)
+	}
+
+	ids := map[string]bool{}
+	parseErrors := 0
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp struct {
+			ID    json.RawMessage `json:"id"`
+			Error *JSONRPCError   `json:"error"`
+		}
+		if strings.HasPrefix(line, "[") {
+			var batch []json.RawMessage
+			if err := json.Unmarshal([]byte(line), &batch); err != nil || len(batch) != 1 {
+				t.Fatalf("Expected a batch with one response, got %q", line)
+			}
+			line = string(batch[0])
+		}
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("Expected one JSON message per line, got %q", line)
+		}
+		if resp.Error != nil && resp.Error.Code == -32700 {
+			parseErrors++
+			continue
+		}
+		ids[string(resp.ID)] = true
+	}
+
+	if len(ids) != 3 || !ids["1"] || !ids["2"] || !ids["3"] {
+		t.Errorf("Expected responses to requests 1, 2 and 3 only, got %v", ids)
+	}
+	if parseErrors != 1 {
+		t.Errorf("Expected one parse error, got %d", parseErrors)
+	}
+}
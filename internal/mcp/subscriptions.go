@@ -0,0 +1,209 @@
+package mcp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+// globalResources are the project-independent JSON resources of resources/list
+var globalResources = map[string]bool{
+	resourceScheme + "projects":  true,
+	resourceScheme + "agents":    true,
+	resourceScheme + "tasks":     true,
+	resourceScheme + "dashboard": true,
+}
+
+func (h *MCPHandler) handleResourcesSubscribe(params json.RawMessage, ctx MCPContext, subscribe bool) (interface{}, *JSONRPCError) {
+	var subParams struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &subParams); err != nil {
+		return nil, &JSONRPCError{Code: -32602, Message: "Invalid params", Data: err.Error()}
+	}
+	if ctx.Session == nil {
+		return nil, &JSONRPCError{Code: -32600, Message: "Invalid request", Data: "Subscriptions need a session; send initialize first"}
+	}
+
+	if !subscribe {
+		ctx.Session.unsubscribe(subParams.URI)
+		return map[string]interface{}{}, nil
+	}
+
+	if kind, id, ok := parseResourceURI(subParams.URI); ok {
+		if rpcErr := h.authorizeResource(ctx, subParams.URI, kind, id); rpcErr != nil {
+			return nil, rpcErr
+		}
+	} else if !globalResources[subParams.URI] {
+		return nil, &JSONRPCError{Code: -32602, Message: "Unknown resource", Data: fmt.Sprintf("Resource not found: %s", subParams.URI)}
+	}
+
+	ctx.Session.subscribe(subParams.URI)
+	return map[string]interface{}{}, nil
+}
+
+// authorizeResource checks that a per-entity resource exists and that the
+// caller may read its project
+func (h *MCPHandler) authorizeResource(ctx MCPContext, uri, kind string, id uuid.UUID) *JSONRPCError {
+	if h.db == nil {
+		return &JSONRPCError{Code: -32000, Message: "Resource read failed", Data: "Database not connected"}
+	}
+
+	var projectID uuid.UUID
+	var err error
+	switch kind {
+	case projectResource:
+		err = h.db.QueryRow("SELECT id FROM projects WHERE id = $1", id).Scan(&projectID)
+	case taskResource:
+		err = h.db.QueryRow("SELECT project_id FROM tasks WHERE id = $1", id).Scan(&projectID)
+	case contextResource:
+		err = h.db.QueryRow("SELECT project_id FROM contexts WHERE id = $1", id).Scan(&projectID)
+	}
+	if err == sql.ErrNoRows {
+		return &JSONRPCError{Code: -32602, Message: "Unknown resource", Data: fmt.Sprintf("Resource not found: %s", uri)}
+	} else if err != nil {
+		return &JSONRPCError{Code: -32000, Message: "Resource read failed", Data: err.Error()}
+	}
+
+	if h.auth != nil {
+		if denied := h.check(ctx, projectID, auth.ActionRead); denied != nil {
+			return &JSONRPCError{Code: -32000, Message: "Resource read failed", Data: denied}
+		}
+	}
+	return nil
+}
+
+// notifySubscribers is registered with the hub and tells every session
+// subscribed to a resource touched by a broadcast that it was updated
+func (h *MCPHandler) notifySubscribers(projectID uuid.UUID, messageType string, payload interface{}) {
+	var sessions []*Session
+	h.sessions.Range(func(_, value interface{}) bool {
+		if s := value.(*Session); s.hasSubscriptions() {
+			sessions = append(sessions, s)
+		}
+		return true
+	})
+	if len(sessions) == 0 {
+		return
+	}
+
+	uris := resourcesForEvent(projectID, messageType, payloadFields(payload))
+	for _, s := range sessions {
+		for _, uri := range s.subscribed(uris) {
+			s.Notify("notifications/resources/updated", map[string]string{"uri": uri})
+		}
+	}
+}
+
+// resourcesForEvent returns the URIs of the resources a hub message changes
+func resourcesForEvent(projectID uuid.UUID, messageType string, fields map[string]interface{}) []string {
+	if messageType == toolSettingsMessage {
+		return nil
+	}
+
+	var uris []string
+	if projectID != uuid.Nil {
+		uris = append(uris, resourceURI(projectResource, projectID))
+	}
+
+	switch {
+	case strings.HasPrefix(messageType, "task_"):
+		uris = append(uris, resourceScheme+"tasks", resourceScheme+"dashboard")
+		taskID := fieldID(fields, "task_id")
+		if taskID == uuid.Nil {
+			taskID = fieldID(fields, "id")
+		}
+		if taskID != uuid.Nil {
+			uris = append(uris, resourceURI(taskResource, taskID))
+		}
+		// Subtask changes roll up into the parent's progress
+		if parentID := fieldID(fields, "parent_id"); parentID != uuid.Nil {
+			uris = append(uris, resourceURI(taskResource, parentID))
+		}
+
+	case strings.HasPrefix(messageType, "context_"):
+		uris = append(uris, resourceScheme+"dashboard")
+		if contextID := fieldID(fields, "id"); contextID != uuid.Nil {
+			uris = append(uris, resourceURI(contextResource, contextID))
+		}
+		// Tasks list their linked contexts
+		if taskID := fieldID(fields, "task_id"); taskID != uuid.Nil {
+			uris = append(uris, resourceURI(taskResource, taskID))
+		}
+
+	case strings.HasPrefix(messageType, "agent_"):
+		uris = append(uris, resourceScheme+"agents", resourceScheme+"dashboard")
+
+	case strings.HasPrefix(messageType, "project_"):
+		uris = append(uris, resourceScheme+"projects", resourceScheme+"dashboard")
+	}
+	return uris
+}
+
+// payloadFields reads the top-level fields of a broadcast payload
+func payloadFields(payload interface{}) map[string]interface{} {
+	if fields, ok := payload.(map[string]interface{}); ok {
+		return fields
+	}
+	var fields map[string]interface{}
+	if data, err := json.Marshal(payload); err == nil {
+		json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+// fieldID reads a UUID field that may hold a uuid.UUID or its string form
+func fieldID(fields map[string]interface{}, key string) uuid.UUID {
+	switch v := fields[key].(type) {
+	case uuid.UUID:
+		return v
+	case *uuid.UUID:
+		if v != nil {
+			return *v
+		}
+	case string:
+		return parseID(v)
+	}
+	return uuid.Nil
+}
+
+func (s *Session) subscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscriptions == nil {
+		s.subscriptions = make(map[string]bool)
+	}
+	s.subscriptions[uri] = true
+}
+
+func (s *Session) unsubscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, uri)
+}
+
+func (s *Session) hasSubscriptions() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscriptions) > 0
+}
+
+// subscribed returns the URIs the session is subscribed to, each once
+func (s *Session) subscribed(uris []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []string
+	seen := make(map[string]bool, len(uris))
+	for _, uri := range uris {
+		if s.subscriptions[uri] && !seen[uri] {
+			out = append(out, uri)
+			seen[uri] = true
+		}
+	}
+	return out
+}
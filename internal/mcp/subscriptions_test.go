@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+func TestResourcesForEvent(t *testing.T) {
+	projectID, taskID, parentID, contextID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	task := models.Task{ID: taskID, ProjectID: projectID, ParentID: &parentID}
+	uris := resourcesForEvent(projectID, "task_update", payloadFields(task))
+	for _, want := range []string{
+		resourceURI(projectResource, projectID),
+		resourceURI(taskResource, taskID),
+		resourceURI(taskResource, parentID),
+		"agent-shaker://tasks",
+	} {
+		if !contains(uris, want) {
+			t.Errorf("Expected task_update to touch %s, got %v", want, uris)
+		}
+	}
+
+	comment := map[string]interface{}{"task_id": taskID, "comment": map[string]interface{}{"id": uuid.New()}}
+	if uris := resourcesForEvent(projectID, "task_comment", payloadFields(comment)); !contains(uris, resourceURI(taskResource, taskID)) {
+		t.Errorf("Expected task_comment to touch the task, got %v", uris)
+	}
+
+	ctx := models.Context{ID: contextID, ProjectID: projectID, TaskID: &taskID}
+	uris = resourcesForEvent(projectID, "context_updated", payloadFields(ctx))
+	if !contains(uris, resourceURI(contextResource, contextID)) || !contains(uris, resourceURI(taskResource, taskID)) {
+		t.Errorf("Expected context_updated to touch the context and its task, got %v", uris)
+	}
+}
+
+func TestNotifySubscribers(t *testing.T) {
+	h := &MCPHandler{}
+	projectID, taskID := uuid.New(), uuid.New()
+
+	s := newSession(MCPContext{})
+	h.sessions.Store(s.ID, s)
+	s.subscribe(resourceURI(taskResource, taskID))
+	other := newSession(MCPContext{})
+	h.sessions.Store(other.ID, other)
+
+	h.notifySubscribers(projectID, "task_update", map[string]interface{}{"id": taskID.String()})
+	h.notifySubscribers(projectID, "task_update", map[string]interface{}{"id": uuid.New().String()})
+
+	events, _ := s.pending(nil, 0)
+	if len(events) != 1 {
+		t.Fatalf("Expected one notification, got %d", len(events))
+	}
+	var msg JSONRPCNotification
+	json.Unmarshal(events[0].Data, &msg)
+	if msg.Method != "notifications/resources/updated" {
+		t.Errorf("Expected a resources/updated notification, got %s", msg.Method)
+	}
+	if events, _ := other.pending(nil, 0); len(events) != 0 {
+		t.Errorf("Expected no notifications for a session without subscriptions, got %d", len(events))
+	}
+
+	s.unsubscribe(resourceURI(taskResource, taskID))
+	h.notifySubscribers(projectID, "task_update", map[string]interface{}{"id": taskID.String()})
+	if events, _ := s.pending(nil, 0); len(events) != 1 {
+		t.Errorf("Expected no notifications after unsubscribing, got %d", len(events)-1)
+	}
+}
@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/validator"
+)
+
+// subtaskTools break tasks down into subtasks
+var subtaskTools = []ToolDefinition{
+	{
+		Name:        "create_subtasks",
+		Description: "Break a task down into subtasks in one call. The parent's progress rolls up from its subtasks.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"parent_task_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The task to break down",
+				},
+				"subtasks": map[string]interface{}{
+					"type":        "array",
+					"description": "Subtasks to create, in order",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"title":       map[string]interface{}{"type": "string", "description": "Subtask title"},
+							"description": map[string]interface{}{"type": "string", "description": "Subtask description"},
+							"priority":    map[string]interface{}{"type": "string", "enum": []string{"low", "medium", "high"}},
+							"assigned_to": map[string]interface{}{"type": "string", "description": "Optional agent ID to assign the subtask to"},
+							"due_at":      map[string]interface{}{"type": "string", "description": "Optional RFC 3339 due date"},
+							"sla":         map[string]interface{}{"type": "string", "description": "Optional SLA such as 4h"},
+							"auto_assign": autoAssignSchema,
+						},
+						"required": []string{"title"},
+					},
+				},
+			},
+			Required: []string{"parent_task_id", "subtasks"},
+		},
+		OutputSchema: outputSchema(map[string]interface{}{
+			"success":        successField,
+			"parent_task_id": outputField("string", "Parent task ID"),
+			"created":        outputField("integer", "Number of subtasks created"),
+			"subtasks":       outputList("The created subtasks"),
+			"progress":       outputField("object", "Roll-up progress of the parent task"),
+		}, "success", "parent_task_id", "created", "subtasks"),
+		Requires: RequiresAgent,
+		Access:   ToolAccess{Action: auth.ActionCreateTask, Project: taskProject("parent_task_id")},
+		Handler:  (*MCPHandler).executeCreateSubtasks,
+	},
+}
+
+func (h *MCPHandler) executeCreateSubtasks(args map[string]interface{}, ctx MCPContext) (interface{}, error) {
+	if h.db == nil || h.subtasks == nil {
+		return nil, errNoDatabase
+	}
+
+	parentID, err := uuid.Parse(fmt.Sprint(args["parent_task_id"]))
+	if err != nil {
+		return nil, invalidArgument("parent_task_id must be a valid UUID")
+	}
+	createdBy, err := uuid.Parse(ctx.AgentID)
+	if err != nil {
+		return nil, invalidArgument("agent_id must be a valid UUID")
+	}
+
+	// Round-trip the arguments through JSON to reuse the REST request type
+	req := models.CreateSubtasksRequest{CreatedBy: createdBy}
+	raw, _ := json.Marshal(args["subtasks"])
+	if err := json.Unmarshal(raw, &req.Subtasks); err != nil {
+		return nil, invalidArgument("subtasks must be a list of objects with a title")
+	}
+
+	if err := validator.ValidateCreateSubtasksRequest(&req); err != nil {
+		return nil, invalidArgument("%s", err)
+	}
+
+	created, err := h.subtasks.Create(context.Background(), parentID, req, toolActor(ctx))
+	if err != nil {
+		return nil, err
+	}
+	progress, _ := h.subtasks.Progress(context.Background(), parentID)
+
+	return map[string]interface{}{
+		"success":        true,
+		"parent_task_id": parentID,
+		"created":        len(created),
+		"subtasks":       created,
+		"progress":       progress,
+	}, nil
+}
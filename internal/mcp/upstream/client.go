@@ -0,0 +1,217 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// protocolVersion is the MCP version the client asks downstream servers for
+const protocolVersion = "2025-06-18"
+
+// ErrClosed is returned by calls on a client whose server has gone away
+var ErrClosed = errors.New("MCP server connection closed")
+
+// RPCError is a JSON-RPC error returned by a downstream server
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// request is a JSON-RPC request or, without an ID, a notification
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// message is any JSON-RPC message received from a server
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *RPCError       `json:"error,omitempty"`
+}
+
+// transport carries JSON-RPC messages to one server
+type transport interface {
+	// call sends a request and returns the matching response
+	call(ctx context.Context, req request) (*message, error)
+	// notify sends a notification
+	notify(ctx context.Context, req request) error
+	close() error
+}
+
+// Tool is a tool offered by a downstream server. The schemas are kept as
+// the server sent them.
+type Tool struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	InputSchema  json.RawMessage `json:"inputSchema,omitempty"`
+	OutputSchema json.RawMessage `json:"outputSchema,omitempty"`
+}
+
+// Client is an initialized connection to a downstream MCP server
+type Client struct {
+	name   string
+	config Config
+	conn   transport
+	ids    atomic.Int64
+
+	mu sync.Mutex
+	// toolsChanged and closed are called when the server reports a new tool
+	// list and when its connection ends
+	toolsChanged []func()
+	closed       []func(error)
+}
+
+// Connect starts or dials the server described by config and initializes
+// an MCP session with it
+func Connect(ctx context.Context, name string, config Config) (*Client, error) {
+	if err := config.Validate(name); err != nil {
+		return nil, err
+	}
+
+	c := &Client{name: name, config: config}
+	var err error
+	if config.Command != "" {
+		c.conn, err = startProcess(name, config, c.handleNotification, c.handleClose)
+	} else {
+		c.conn = newHTTPTransport(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.initialize(ctx); err != nil {
+		c.conn.close()
+		return nil, fmt.Errorf("failed to initialize MCP server %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// Name returns the name the server was configured under
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Config returns the configuration the server was connected with
+func (c *Client) Config() Config {
+	return c.config
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	_, err := c.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "agent-shaker", "version": "1.0.0"},
+	})
+	if err != nil {
+		return err
+	}
+	return c.conn.notify(ctx, request{JSONRPC: "2.0", Method: "notifications/initialized"})
+}
+
+// call sends a request with the configured timeout and returns its result
+func (c *Client) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.timeout())
+	defer cancel()
+
+	resp, err := c.conn.call(ctx, request{JSONRPC: "2.0", ID: c.ids.Add(1), Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Result, nil
+}
+
+// ListTools returns every tool of the server, following nextCursor
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		result, err := c.call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(result, &page); err != nil {
+			return nil, fmt.Errorf("invalid tools/list result: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool calls a tool and returns the server's tools/call result as is
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (json.RawMessage, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return c.call(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": args})
+}
+
+// OnToolsChanged registers fn to be called when the server reports that its
+// tool list changed. Only stdio servers can report changes. Calls come one at
+// a time off the read loop, so fn may call the server.
+func (c *Client) OnToolsChanged(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.toolsChanged = append(c.toolsChanged, fn)
+}
+
+// OnClose registers fn to be called when the server's connection ends, with
+// the reason
+func (c *Client) OnClose(fn func(error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = append(c.closed, fn)
+}
+
+// Close ends the session and stops the server process
+func (c *Client) Close() error {
+	return c.conn.close()
+}
+
+func (c *Client) handleNotification(method string) {
+	if method != "notifications/tools/list_changed" {
+		return
+	}
+	c.mu.Lock()
+	listeners := append([]func(){}, c.toolsChanged...)
+	c.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+func (c *Client) handleClose(err error) {
+	c.mu.Lock()
+	listeners := append([]func(error){}, c.closed...)
+	c.mu.Unlock()
+	for _, fn := range listeners {
+		fn(err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package upstream is an MCP client for the downstream MCP servers whose
+// tools Agent Shaker re-exports. Servers are run as stdio subprocesses or
+// reached over the Streamable HTTP transport.
+package upstream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// DefaultTimeout bounds a call to a downstream server without its own timeout
+const DefaultTimeout = 60 * time.Second
+
+var (
+	ErrInvalidServerName = errors.New("server name must be 1-64 letters, digits, '_' or '-'")
+	ErrInvalidTransport  = errors.New("exactly one of command or url is required")
+	ErrInvalidRole       = errors.New("role must be observer, agent, maintainer or admin")
+	ErrInvalidProject    = errors.New("project_id must be a UUID")
+)
+
+var serverName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Config describes a downstream MCP server: a subprocess spoken to over
+// stdio when Command is set, or a Streamable HTTP endpoint at URL
+type Config struct {
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Timeout bounds each request to the server, DefaultTimeout when unset
+	Timeout models.Duration `json:"timeout,omitempty"`
+	// Role is the least project role a caller needs to use the server's
+	// tools. Only admins may use them when it is unset.
+	Role auth.Role `json:"role,omitempty"`
+	// ProjectID is the project the role is checked in. When unset it is the
+	// project of the calling connection.
+	ProjectID string `json:"project_id,omitempty"`
+}
+
+// Validate checks that a server is named and configured for one transport
+func (c Config) Validate(name string) error {
+	if !serverName.MatchString(name) {
+		return ErrInvalidServerName
+	}
+	if (c.Command == "") == (c.URL == "") {
+		return ErrInvalidTransport
+	}
+	if c.Role != auth.RoleNone && !auth.IsProjectRole(c.Role) && c.Role != auth.RoleAdmin {
+		return ErrInvalidRole
+	}
+	if c.ProjectID != "" {
+		if _, err := uuid.Parse(c.ProjectID); err != nil {
+			return ErrInvalidProject
+		}
+	}
+	return nil
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return c.Timeout.Std()
+}
+
+// LoadConfig reads the downstream servers from a JSON file in the format
+// desktop MCP clients use: {"mcpServers": {"name": {"command": ...}}}
+func LoadConfig(path string) (map[string]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MCP server config: %w", err)
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig decodes and validates a downstream server config
+func ParseConfig(data []byte) (map[string]Config, error) {
+	var file struct {
+		Servers map[string]Config `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid MCP server config: %w", err)
+	}
+	for name, c := range file.Servers {
+		if err := c.Validate(name); err != nil {
+			return nil, fmt.Errorf("MCP server %q: %w", name, err)
+		}
+	}
+	return file.Servers, nil
+}
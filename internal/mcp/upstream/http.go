@@ -0,0 +1,192 @@
+package upstream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// httpTransport speaks the Streamable HTTP transport: each message is a
+// POST, answered with JSON or with an SSE stream that ends in the response
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu      sync.Mutex
+	session string
+	version string
+}
+
+func newHTTPTransport(config Config) *httpTransport {
+	// Calls are bounded by their context rather than a client timeout, so
+	// long-running tools can stream their response
+	return &httpTransport{url: config.URL, headers: config.Headers, client: &http.Client{}}
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.session != "" {
+		req.Header.Set("Mcp-Session-Id", t.session)
+	}
+	if t.version != "" {
+		req.Header.Set("Mcp-Protocol-Version", t.version)
+	}
+	return req, nil
+}
+
+// post sends a message and returns the response, which the caller closes
+func (t *httpTransport) post(ctx context.Context, msg request) (*http.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	req, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode == http.StatusNotFound && t.sessionID() != "" {
+			return nil, fmt.Errorf("%w: session expired", ErrClosed)
+		}
+		return nil, fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.session = id
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) sessionID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.session
+}
+
+func (t *httpTransport) call(ctx context.Context, req request) (*message, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	id := strconv.FormatInt(req.ID, 10)
+	var msg *message
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		msg, err = readEventStream(resp.Body, id)
+	} else {
+		msg = &message{}
+		err = json.NewDecoder(resp.Body).Decode(msg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if req.Method == "initialize" && msg.Result != nil {
+		var result struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(msg.Result, &result)
+		t.mu.Lock()
+		t.version = result.ProtocolVersion
+		t.mu.Unlock()
+	}
+	return msg, nil
+}
+
+// readEventStream reads SSE events until the response with the given ID.
+// Notifications sent before it are skipped.
+func readEventStream(body io.Reader, id string) (*message, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxMessage)
+
+	var data []string
+	// response decodes the event collected so far, if it is the response
+	response := func() *message {
+		if len(data) == 0 {
+			return nil
+		}
+		var msg message
+		err := json.Unmarshal([]byte(strings.Join(data, "\n")), &msg)
+		data = nil
+		if err == nil && msg.Method == "" && string(msg.ID) == id {
+			return &msg
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" {
+			continue
+		}
+		if msg := response(); msg != nil {
+			return msg, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// The last event may end with the stream rather than a blank line
+	if msg := response(); msg != nil {
+		return msg, nil
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+func (t *httpTransport) notify(ctx context.Context, req request) error {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close ends the session on the server
+func (t *httpTransport) close() error {
+	if t.sessionID() == "" {
+		return nil
+	}
+	req, err := t.newRequest(context.Background(), http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,238 @@
+package upstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxMessage bounds a single message read from a server's stdout
+const maxMessage = 10 * 1024 * 1024
+
+// streamTransport speaks newline-delimited JSON-RPC over a pair of streams,
+// the stdin and stdout of a server process
+type streamTransport struct {
+	out   io.WriteCloser
+	outMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *message
+	err     error
+	done    chan struct{}
+
+	// notifications queues the methods of server notifications for
+	// dispatch, so listeners never hold up the read loop
+	notifyMu      sync.Mutex
+	notifications []string
+	wake          chan struct{}
+
+	// stop ends the process once its stdin is closed
+	stop func() error
+}
+
+// startProcess runs a server's command with its stdin and stdout attached to
+// a stream transport. The server's stderr is logged.
+func startProcess(name string, config Config, notified func(string), closed func(error)) (*streamTransport, error) {
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range config.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stderr = stderrLogger{name: name}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin of MCP server %s: %w", name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout of MCP server %s: %w", name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server %s: %w", name, err)
+	}
+
+	t := newStreamTransport(stdout, stdin, notified, closed)
+	t.stop = func() error {
+		// Servers that do not exit when stdin closes are killed; either way
+		// stdout is read to the end before the process is waited for
+		select {
+		case <-t.done:
+		case <-time.After(5 * time.Second):
+			cmd.Process.Kill()
+			<-t.done
+		}
+		return cmd.Wait()
+	}
+	return t, nil
+}
+
+// stderrLogger logs what a server process writes to stderr, line by line
+type stderrLogger struct {
+	name string
+}
+
+func (l stderrLogger) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		log.Printf("MCP server %s: %s", l.name, line)
+	}
+	return len(p), nil
+}
+
+// newStreamTransport reads messages from in until it ends and writes
+// messages to out
+func newStreamTransport(in io.Reader, out io.WriteCloser, notified func(string), closed func(error)) *streamTransport {
+	t := &streamTransport{
+		out:     out,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+	go t.read(in, closed)
+	go t.dispatch(notified)
+	return t
+}
+
+func (t *streamTransport) read(in io.Reader, closed func(error)) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxMessage)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Printf("Ignoring invalid message from MCP server: %v", err)
+			continue
+		}
+
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			// Requests from the server: only ping is supported. The reply is
+			// written aside, as the server may itself be blocked writing to us.
+			reply := map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID}
+			if msg.Method == "ping" {
+				reply["result"] = map[string]interface{}{}
+			} else {
+				reply["error"] = RPCError{Code: -32601, Message: "Method not found"}
+			}
+			go t.write(reply)
+		case msg.Method != "":
+			t.notifyMu.Lock()
+			t.notifications = append(t.notifications, msg.Method)
+			t.notifyMu.Unlock()
+			select {
+			case t.wake <- struct{}{}:
+			default:
+			}
+		default:
+			t.mu.Lock()
+			replies, ok := t.pending[string(msg.ID)]
+			delete(t.pending, string(msg.ID))
+			t.mu.Unlock()
+			if ok {
+				replies <- &msg
+			}
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	t.mu.Lock()
+	t.err = err
+	close(t.done)
+	t.mu.Unlock()
+	if closed != nil {
+		closed(err)
+	}
+}
+
+// dispatch passes queued notifications to notified in the order they
+// arrived, until the connection ends
+func (t *streamTransport) dispatch(notified func(string)) {
+	for {
+		select {
+		case <-t.wake:
+		case <-t.done:
+			return
+		}
+
+		t.notifyMu.Lock()
+		methods := t.notifications
+		t.notifications = nil
+		t.notifyMu.Unlock()
+		for _, method := range methods {
+			if notified != nil {
+				notified(method)
+			}
+		}
+	}
+}
+
+func (t *streamTransport) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	if _, err := t.out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+func (t *streamTransport) call(ctx context.Context, req request) (*message, error) {
+	key := strconv.FormatInt(req.ID, 10)
+	replies := make(chan *message, 1)
+
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, ErrClosed
+	}
+	t.pending[key] = replies
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case msg := <-replies:
+		return msg, nil
+	case <-t.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		// Tell the server to stop working on the request
+		t.write(request{JSONRPC: "2.0", Method: "notifications/cancelled", Params: map[string]interface{}{"requestId": req.ID, "reason": ctx.Err().Error()}})
+		return nil, ctx.Err()
+	}
+}
+
+func (t *streamTransport) notify(ctx context.Context, req request) error {
+	return t.write(req)
+}
+
+// close closes the server's stdin, which asks it to exit, and waits for it
+func (t *streamTransport) close() error {
+	err := t.out.Close()
+	if t.stop != nil {
+		return t.stop()
+	}
+	return err
+}
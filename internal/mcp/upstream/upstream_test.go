@@ -0,0 +1,120 @@
+package upstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr error
+	}{
+		{"stdio", `{"mcpServers":{"files":{"command":"mcp-files","args":["/tmp"],"timeout":"30s","role":"maintainer"}}}`, nil},
+		{"http", `{"mcpServers":{"search":{"url":"http://localhost:9000/mcp","timeout":30}}}`, nil},
+		{"both transports", `{"mcpServers":{"x":{"command":"a","url":"http://b"}}}`, ErrInvalidTransport},
+		{"no transport", `{"mcpServers":{"x":{}}}`, ErrInvalidTransport},
+		{"dotted name", `{"mcpServers":{"a.b":{"command":"a"}}}`, ErrInvalidServerName},
+		{"unknown role", `{"mcpServers":{"x":{"command":"a","role":"owner"}}}`, ErrInvalidRole},
+		{"bad project", `{"mcpServers":{"x":{"command":"a","project_id":"p1"}}}`, ErrInvalidProject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servers, err := ParseConfig([]byte(tt.config))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseConfig() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				for _, c := range servers {
+					if c.timeout() != 30*time.Second {
+						t.Errorf("Expected a 30s timeout, got %s", c.timeout())
+					}
+				}
+			}
+		})
+	}
+}
+
+// fakeServer answers tools/list on the other end of a stream transport.
+// Before answering it pings the client and reports a tool list change.
+func fakeServer(t *testing.T, in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Result json.RawMessage `json:"result"`
+		}
+		json.Unmarshal(scanner.Bytes(), &msg)
+		switch {
+		case msg.Method == "tools/list":
+			io.WriteString(out, `{"jsonrpc":"2.0","id":"ping-1","method":"ping"}`+"\n")
+			io.WriteString(out, `{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`+"\n")
+			io.WriteString(out, `{"jsonrpc":"2.0","id":`+string(msg.ID)+`,"result":{"tools":[{"name":"read"}]}}`+"\n")
+		case string(msg.ID) == `"ping-1"`:
+			if string(msg.Result) != "{}" {
+				t.Errorf("Expected an empty ping result, got %s", msg.Result)
+			}
+		}
+	}
+}
+
+func TestStreamTransport(t *testing.T) {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	go fakeServer(t, serverIn, serverOut)
+
+	notified := make(chan string, 1)
+	closed := make(chan error, 1)
+	conn := newStreamTransport(clientIn, clientOut, func(method string) { notified <- method }, func(err error) { closed <- err })
+	c := &Client{name: "fake", conn: conn}
+
+	tools, err := c.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "read" {
+		t.Errorf("Expected the read tool, got %+v", tools)
+	}
+	if method := <-notified; method != "notifications/tools/list_changed" {
+		t.Errorf("Expected a list_changed notification, got %s", method)
+	}
+
+	serverOut.Close()
+	if err := <-closed; err != io.EOF {
+		t.Errorf("Expected the connection to end with EOF, got %v", err)
+	}
+	if _, err := c.ListTools(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected calls after the server went away to fail with ErrClosed, got %v", err)
+	}
+	conn.close()
+}
+
+func TestReadEventStream(t *testing.T) {
+	body := strings.Join([]string{
+		`event: message`,
+		`data: {"jsonrpc":"2.0","method":"notifications/progress","params":{}}`,
+		``,
+		`data: {"jsonrpc":"2.0","id":7,`,
+		`data: "result":{"ok":true}}`,
+		``,
+	}, "\n")
+	msg, err := readEventStream(strings.NewReader(body), "7")
+	if err != nil {
+		t.Fatalf("readEventStream failed: %v", err)
+	}
+	if string(msg.Result) != `{"ok":true}` {
+		t.Errorf("Expected the response after the notification, got %s", msg.Result)
+	}
+
+	if _, err := readEventStream(strings.NewReader(body), "8"); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected a stream without the response to fail, got %v", err)
+	}
+}
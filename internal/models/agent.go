@@ -1,19 +1,69 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// AgentRole represents the role of an agent
+// AgentRole represents the role of an agent. Each project defines the
+// roles its agents may take; new projects start with DefaultAgentRoles.
 type AgentRole string
 
 const (
 	RoleBackend  AgentRole = "backend"
 	RoleFrontend AgentRole = "frontend"
+	RoleQA       AgentRole = "qa"
+	RoleDevOps   AgentRole = "devops"
+	RoleDocs     AgentRole = "docs"
+	RoleReviewer AgentRole = "reviewer"
 )
 
+// DefaultAgentRoles is the role vocabulary new projects start with
+var DefaultAgentRoles = []AgentRoleDefinition{
+	{Name: RoleBackend, Description: "Server-side development"},
+	{Name: RoleFrontend, Description: "User interface development"},
+	{Name: RoleQA, Description: "Testing and quality assurance"},
+	{Name: RoleDevOps, Description: "Infrastructure, CI/CD and operations"},
+	{Name: RoleDocs, Description: "Documentation"},
+	{Name: RoleReviewer, Description: "Code review"},
+}
+
+// AgentRoleDefinition is one entry in a project's role vocabulary
+type AgentRoleDefinition struct {
+	ProjectID   uuid.UUID `json:"project_id" db:"project_id"`
+	Name        AgentRole `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateAgentRoleRequest struct {
+	Name        AgentRole `json:"name"`
+	Description string    `json:"description"`
+}
+
+// NormalizeAgentRole trims and lowercases a role name
+func NormalizeAgentRole(role AgentRole) AgentRole {
+	return AgentRole(strings.ToLower(strings.TrimSpace(string(role))))
+}
+
+// NormalizeCapabilities trims and lowercases capability tags, dropping
+// empty and duplicate tags while keeping their order
+func NormalizeCapabilities(tags []string) []string {
+	out := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
 type Agent struct {
 	ID        uuid.UUID `json:"id" db:"id"`
 	ProjectID uuid.UUID `json:"project_id" db:"project_id"`
@@ -21,15 +71,23 @@ type Agent struct {
 	Role      AgentRole `json:"role" db:"role"`
 	Team      string    `json:"team" db:"team"`
 	Status    string    `json:"status" db:"status"`
-	LastSeen  time.Time `json:"last_seen" db:"last_seen"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Capabilities are free-form tags such as languages, frameworks and tools
+	Capabilities []string  `json:"capabilities" db:"capabilities"`
+	LastSeen     time.Time `json:"last_seen" db:"last_seen"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
 type CreateAgentRequest struct {
-	ProjectID uuid.UUID `json:"project_id"`
-	Name      string    `json:"name"`
-	Role      AgentRole `json:"role"`
-	Team      string    `json:"team"`
+	ProjectID    uuid.UUID `json:"project_id"`
+	Name         string    `json:"name"`
+	Role         AgentRole `json:"role"`
+	Team         string    `json:"team"`
+	Capabilities []string  `json:"capabilities"`
+}
+
+// UpdateAgentCapabilitiesRequest replaces an agent's capability tags
+type UpdateAgentCapabilitiesRequest struct {
+	Capabilities []string `json:"capabilities"`
 }
 
 type UpdateAgentStatusRequest struct {
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey represents an API key issued to an agent or a user. The plaintext key is never stored.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	AgentID    *uuid.UUID `json:"agent_id,omitempty" db:"agent_id"`
+	UserID     *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	KeyPrefix  string     `json:"key_prefix" db:"key_prefix"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+// CreateAPIKeyRequest represents a request to issue a new API key
+type CreateAPIKeyRequest struct {
+	Name          string `json:"name"`
+	ExpiresInDays int    `json:"expires_in_days"` // 0 means the key never expires
+}
+
+// CreateAPIKeyResponse is returned once when a key is issued or rotated
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"` // Plaintext key, only returned at creation time
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskComment is a markdown comment on a task. Replies point at the comment
+// they answer through ParentID and are nested under it in Replies.
+type TaskComment struct {
+	ID        uuid.UUID      `json:"id" db:"id"`
+	TaskID    uuid.UUID      `json:"task_id" db:"task_id"`
+	ParentID  *uuid.UUID     `json:"parent_id,omitempty" db:"parent_id"`
+	AgentID   uuid.UUID      `json:"agent_id" db:"agent_id"`
+	AgentName string         `json:"agent_name,omitempty"`
+	Body      string         `json:"body" db:"body"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
+	EditedAt  *time.Time     `json:"edited_at,omitempty" db:"edited_at"`
+	Replies   []*TaskComment `json:"replies,omitempty"`
+}
+
+type CreateCommentRequest struct {
+	AgentID  uuid.UUID  `json:"agent_id"`
+	ParentID *uuid.UUID `json:"parent_id"`
+	Body     string     `json:"body"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// CommentThreads nests replies under their parents, keeping the input order
+// (oldest first) at every level. Replies whose parent is missing are treated
+// as top-level comments.
+func CommentThreads(comments []TaskComment) []*TaskComment {
+	byID := make(map[uuid.UUID]*TaskComment, len(comments))
+	nodes := make([]*TaskComment, len(comments))
+	for i := range comments {
+		c := comments[i]
+		c.Replies = nil
+		nodes[i] = &c
+		byID[c.ID] = &c
+	}
+
+	threads := []*TaskComment{}
+	for _, c := range nodes {
+		if c.ParentID != nil {
+			if parent, ok := byID[*c.ParentID]; ok && parent != c {
+				parent.Replies = append(parent.Replies, c)
+				continue
+			}
+		}
+		threads = append(threads, c)
+	}
+	return threads
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskDependency is an edge in a project's task graph: TaskID waits on DependsOnID
+type TaskDependency struct {
+	TaskID      uuid.UUID `json:"task_id" db:"task_id"`
+	DependsOnID uuid.UUID `json:"depends_on_id" db:"depends_on_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+type AddDependencyRequest struct {
+	DependsOnID uuid.UUID `json:"depends_on_id"`
+}
+
+// TaskGraphNode is a task as it appears in a dependency graph
+type TaskGraphNode struct {
+	ID         uuid.UUID  `json:"id"`
+	Title      string     `json:"title"`
+	Status     TaskStatus `json:"status"`
+	AssignedTo *uuid.UUID `json:"assigned_to"`
+}
+
+// TaskGraph is the dependency DAG of a project
+type TaskGraph struct {
+	ProjectID uuid.UUID        `json:"project_id"`
+	Nodes     []TaskGraphNode  `json:"nodes"`
+	Edges     []TaskDependency `json:"edges"`
+}
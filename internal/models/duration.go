@@ -0,0 +1,58 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that is written to JSON as a string such as
+// "4h0m0s" and stored in the database as whole seconds. JSON input may be a
+// duration string or a number of seconds.
+type Duration time.Duration
+
+// Std returns d as a time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(time.Duration(value * float64(time.Second)))
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+// Scan reads a number of seconds from the database
+func (d *Duration) Scan(src interface{}) error {
+	seconds, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into Duration", src)
+	}
+	*d = Duration(time.Duration(seconds) * time.Second)
+	return nil
+}
+
+// Value stores the duration as whole seconds
+func (d Duration) Value() (driver.Value, error) {
+	return int64(time.Duration(d) / time.Second), nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskEventType names the kind of change a task event records
+type TaskEventType string
+
+const (
+	EventTaskCreated      TaskEventType = "created"
+	EventTaskUpdated      TaskEventType = "updated"
+	EventTaskStatus       TaskEventType = "status_changed"
+	EventTaskReassigned   TaskEventType = "reassigned"
+	EventTaskClaimed      TaskEventType = "claimed"
+	EventTaskCompleted    TaskEventType = "completed"
+	EventTaskLeaseExpired TaskEventType = "lease_expired"
+	EventTaskReleased     TaskEventType = "released"
+	EventTaskBlocked      TaskEventType = "blocked"
+	EventTaskUnblocked    TaskEventType = "unblocked"
+	EventTaskOverdue      TaskEventType = "overdue"
+	EventTaskRescheduled  TaskEventType = "rescheduled"
+	EventTaskDeleted      TaskEventType = "deleted"
+)
+
+// TaskEvent is one entry in a task's append-only history. Actor fields are
+// empty for changes made by the system, such as lease expiry.
+type TaskEvent struct {
+	ID            uuid.UUID     `json:"id" db:"id"`
+	TaskID        uuid.UUID     `json:"task_id" db:"task_id"`
+	ProjectID     uuid.UUID     `json:"project_id" db:"project_id"`
+	Event         TaskEventType `json:"event" db:"event"`
+	ActorAgentID  *uuid.UUID    `json:"actor_agent_id,omitempty" db:"actor_agent_id"`
+	ActorUserID   *uuid.UUID    `json:"actor_user_id,omitempty" db:"actor_user_id"`
+	OldStatus     TaskStatus    `json:"old_status,omitempty" db:"old_status"`
+	NewStatus     TaskStatus    `json:"new_status,omitempty" db:"new_status"`
+	OldAssignedTo *uuid.UUID    `json:"old_assigned_to,omitempty" db:"old_assigned_to"`
+	NewAssignedTo *uuid.UUID    `json:"new_assigned_to,omitempty" db:"new_assigned_to"`
+	Output        *string       `json:"output,omitempty" db:"output"`
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -169,3 +170,145 @@ func TestContextModel(t *testing.T) {
 		t.Errorf("Expected TaskID to match task UUID, got '%s'", ctx.TaskID.String())
 	}
 }
+
+func TestTaskStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		from TaskStatus
+		to   TaskStatus
+		want bool
+	}{
+		{name: "start pending task", from: StatusPending, to: StatusInProgress, want: true},
+		{name: "finish in-progress task", from: StatusInProgress, to: StatusDone, want: true},
+		{name: "fail in-progress task", from: StatusInProgress, to: StatusFailed, want: true},
+		{name: "release in-progress task", from: StatusInProgress, to: StatusPending, want: true},
+		{name: "unblock task", from: StatusBlocked, to: StatusPending, want: true},
+		{name: "reopen done task", from: StatusDone, to: StatusPending, want: true},
+		{name: "retry failed task", from: StatusFailed, to: StatusPending, want: true},
+		{name: "same status", from: StatusDone, to: StatusDone, want: true},
+		{name: "fail pending task", from: StatusPending, to: StatusFailed, want: false},
+		{name: "finish blocked task", from: StatusBlocked, to: StatusDone, want: false},
+		{name: "restart done task", from: StatusDone, to: StatusInProgress, want: false},
+		{name: "resume cancelled task", from: StatusCancelled, to: StatusInProgress, want: false},
+		{name: "legacy completed status", from: StatusInProgress, to: "completed", want: false},
+		{name: "unknown source status", from: "completed", to: StatusPending, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("%s -> %s: CanTransitionTo() = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentThreads(t *testing.T) {
+	root := TaskComment{ID: uuid.New(), Body: "root"}
+	reply := TaskComment{ID: uuid.New(), ParentID: &root.ID, Body: "reply"}
+	nested := TaskComment{ID: uuid.New(), ParentID: &reply.ID, Body: "nested"}
+	missing := uuid.New()
+	orphan := TaskComment{ID: uuid.New(), ParentID: &missing, Body: "orphan"}
+	second := TaskComment{ID: uuid.New(), Body: "second"}
+
+	threads := CommentThreads([]TaskComment{root, reply, nested, orphan, second})
+
+	if len(threads) != 3 {
+		t.Fatalf("Expected 3 top-level comments, got %d", len(threads))
+	}
+	if threads[0].Body != "root" || threads[1].Body != "orphan" || threads[2].Body != "second" {
+		t.Errorf("Unexpected top-level order: %s, %s, %s", threads[0].Body, threads[1].Body, threads[2].Body)
+	}
+	if len(threads[0].Replies) != 1 || threads[0].Replies[0].Body != "reply" {
+		t.Fatalf("Expected reply under root, got %+v", threads[0].Replies)
+	}
+	if len(threads[0].Replies[0].Replies) != 1 || threads[0].Replies[0].Replies[0].Body != "nested" {
+		t.Errorf("Expected nested reply under reply, got %+v", threads[0].Replies[0].Replies)
+	}
+}
+
+func TestNewTaskProgress(t *testing.T) {
+	tests := []struct {
+		total, done, want int
+	}{
+		{total: 0, done: 0, want: 0},
+		{total: 4, done: 1, want: 25},
+		{total: 3, done: 2, want: 66},
+		{total: 2, done: 2, want: 100},
+	}
+
+	for _, tt := range tests {
+		if got := NewTaskProgress(tt.total, tt.done); got.Percent != tt.want {
+			t.Errorf("NewTaskProgress(%d, %d).Percent = %d, want %d", tt.total, tt.done, got.Percent, tt.want)
+		}
+	}
+}
+
+func TestDurationJSON(t *testing.T) {
+	var d Duration
+	if err := json.Unmarshal([]byte(`"1h30m"`), &d); err != nil || d.Std() != 90*time.Minute {
+		t.Errorf("Expected 1h30m from a string, got %s (err %v)", d.Std(), err)
+	}
+	if err := json.Unmarshal([]byte(`45`), &d); err != nil || d.Std() != 45*time.Second {
+		t.Errorf("Expected 45s from a number, got %s (err %v)", d.Std(), err)
+	}
+	if err := json.Unmarshal([]byte(`"soon"`), &d); err == nil {
+		t.Error("Expected an error for an invalid duration")
+	}
+
+	data, err := json.Marshal(Duration(4 * time.Hour))
+	if err != nil || string(data) != `"4h0m0s"` {
+		t.Errorf("Expected \"4h0m0s\", got %s (err %v)", data, err)
+	}
+
+	var scanned Duration
+	if err := scanned.Scan(int64(120)); err != nil || scanned.Std() != 2*time.Minute {
+		t.Errorf("Expected 2m from 120 seconds, got %s (err %v)", scanned.Std(), err)
+	}
+}
+
+func TestNormalizeCapabilities(t *testing.T) {
+	got := NormalizeCapabilities([]string{" Go", "postgres", "go", "", "  ", "Docker "})
+	want := []string{"go", "postgres", "docker"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+			break
+		}
+	}
+
+	if got := NormalizeCapabilities(nil); got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty non-nil slice, got %#v", got)
+	}
+	if got := NormalizeAgentRole(" QA "); got != RoleQA {
+		t.Errorf("Expected role %q, got %q", RoleQA, got)
+	}
+}
+
+func TestRenderPrompt(t *testing.T) {
+	tests := []struct {
+		template string
+		values   map[string]string
+		want     string
+	}{
+		{"Hello {{name}}", map[string]string{"name": "qa-bot"}, "Hello qa-bot"},
+		{"{{ a }}-{{b}}", map[string]string{"a": "1", "b": "2"}, "1-2"},
+		{"Keep {{missing}}", map[string]string{}, "Keep {{missing}}"},
+		{"Empty {{value}}.", map[string]string{"value": ""}, "Empty ."},
+		{"No {placeholders}", nil, "No {placeholders}"},
+	}
+
+	for _, tt := range tests {
+		if got := RenderPrompt(tt.template, tt.values); got != tt.want {
+			t.Errorf("RenderPrompt(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	var args PromptArguments
+	if err := args.Scan([]byte(`[{"name":"pr","required":true}]`)); err != nil || len(args) != 1 || !args[0].Required {
+		t.Errorf("Expected one required argument, got %v (err %v)", args, err)
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Agent presence statuses. Agents may also report other statuses through
+// MCP, such as working or blocked; presence only moves them to idle or
+// offline when their heartbeats stop.
+const (
+	AgentActive  = "active"
+	AgentIdle    = "idle"
+	AgentOffline = "offline"
+)
+
+// PresenceReason tells what caused a presence transition
+type PresenceReason string
+
+const (
+	// PresenceHeartbeat means a heartbeat brought an idle or offline agent back
+	PresenceHeartbeat PresenceReason = "heartbeat"
+	// PresenceManual means the agent or a user set the status explicitly
+	PresenceManual PresenceReason = "manual"
+	// PresenceTimeout means heartbeats stopped for longer than a threshold
+	PresenceTimeout PresenceReason = "timeout"
+)
+
+// AgentPresence records one change of an agent's status
+type AgentPresence struct {
+	ID        uuid.UUID      `json:"id" db:"id"`
+	AgentID   uuid.UUID      `json:"agent_id" db:"agent_id"`
+	ProjectID uuid.UUID      `json:"project_id" db:"project_id"`
+	AgentName string         `json:"agent_name,omitempty"`
+	OldStatus string         `json:"old_status" db:"old_status"`
+	NewStatus string         `json:"new_status" db:"new_status"`
+	Reason    PresenceReason `json:"reason" db:"reason"`
+	LastSeen  *time.Time     `json:"last_seen,omitempty" db:"last_seen"`
+	// ReleasedTasks lists the tasks returned to pending when the agent went offline
+	ReleasedTasks []uuid.UUID `json:"released_tasks,omitempty"`
+	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
+}
@@ -11,11 +11,19 @@ type Project struct {
 	Name        string    `json:"name" db:"name"`
 	Description string    `json:"description" db:"description"`
 	Status      string    `json:"status" db:"status"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// AssignStrategy names the routing strategy used to auto-assign tasks
+	AssignStrategy string    `json:"assign_strategy" db:"assign_strategy"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type CreateProjectRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	AssignStrategy string `json:"assign_strategy"`
+}
+
+// UpdateAssignStrategyRequest selects a project's auto-assign strategy
+type UpdateAssignStrategyRequest struct {
+	Strategy string `json:"strategy"`
 }
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Built-in MCP prompts. Project templates cannot take these names.
+const (
+	PromptStartMyDay       = "start_my_day"
+	PromptWriteStandup     = "write_standup"
+	PromptHandOffTask      = "hand_off_task"
+	PromptSummarizeContext = "summarize_project_context"
+)
+
+// BuiltinPrompts lists the names of the built-in prompts
+var BuiltinPrompts = []string{PromptStartMyDay, PromptWriteStandup, PromptHandOffTask, PromptSummarizeContext}
+
+// PromptArgument is a value a prompt takes when it is requested
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptArguments is stored in the database as a JSON array
+type PromptArguments []PromptArgument
+
+// Scan reads a JSON array from the database
+func (a *PromptArguments) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*a = PromptArguments{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into PromptArguments", src)
+	}
+	return json.Unmarshal(data, a)
+}
+
+// Value stores the arguments as a JSON array
+func (a PromptArguments) Value() (driver.Value, error) {
+	if a == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(a)
+}
+
+// PromptTemplate is a project's own MCP prompt. The template refers to its
+// arguments, and to the variables filled in from the caller's project and
+// agent, as {{name}}.
+type PromptTemplate struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	ProjectID   uuid.UUID       `json:"project_id" db:"project_id"`
+	Name        string          `json:"name" db:"name"`
+	Description string          `json:"description" db:"description"`
+	Arguments   PromptArguments `json:"arguments" db:"arguments"`
+	Template    string          `json:"template" db:"template"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+type CreatePromptTemplateRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Arguments   PromptArguments `json:"arguments"`
+	Template    string          `json:"template"`
+}
+
+var promptPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// RenderPrompt replaces each {{name}} in template with values[name].
+// Placeholders without a value are left as they are.
+func RenderPrompt(template string, values map[string]string) string {
+	return promptPlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		name := promptPlaceholder.FindStringSubmatch(match)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return match
+	})
+}
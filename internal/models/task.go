@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,31 +13,136 @@ type TaskStatus string
 const (
 	StatusPending    TaskStatus = "pending"
 	StatusInProgress TaskStatus = "in_progress"
-	StatusCompleted  TaskStatus = "completed"
+	StatusBlocked    TaskStatus = "blocked"
+	StatusDone       TaskStatus = "done"
 	StatusFailed     TaskStatus = "failed"
+	StatusCancelled  TaskStatus = "cancelled"
 )
 
+// TaskStatuses lists every valid task status
+var TaskStatuses = []TaskStatus{
+	StatusPending, StatusInProgress, StatusBlocked, StatusDone, StatusFailed, StatusCancelled,
+}
+
+// taskTransitions is the task state machine: the statuses each status may move to.
+// Setting a task to the status it already has is always allowed.
+var taskTransitions = map[TaskStatus][]TaskStatus{
+	StatusPending:    {StatusInProgress, StatusBlocked, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusPending, StatusBlocked, StatusDone, StatusFailed, StatusCancelled},
+	StatusBlocked:    {StatusPending, StatusInProgress, StatusCancelled},
+	StatusDone:       {StatusPending},
+	StatusFailed:     {StatusPending, StatusCancelled},
+	StatusCancelled:  {StatusPending},
+}
+
+// IsValid reports whether s is one of the canonical task statuses
+func (s TaskStatus) IsValid() bool {
+	_, ok := taskTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a task in status s may be moved to next
+func (s TaskStatus) CanTransitionTo(next TaskStatus) bool {
+	if !s.IsValid() || !next.IsValid() {
+		return false
+	}
+	if s == next {
+		return true
+	}
+	for _, t := range taskTransitions[s] {
+		if t == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when a task cannot move between two statuses
+type TransitionError struct {
+	From TaskStatus
+	To   TaskStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change task status from %s to %s", e.From, e.To)
+}
+
 type Task struct {
-	ID          uuid.UUID  `json:"id" db:"id"`
-	ProjectID   uuid.UUID  `json:"project_id" db:"project_id"`
-	Title       string     `json:"title" db:"title"`
-	Description string     `json:"description" db:"description"`
-	Status      TaskStatus `json:"status" db:"status"`
-	Priority    string     `json:"priority" db:"priority"`
-	CreatedBy   uuid.UUID  `json:"created_by" db:"created_by"`
-	AssignedTo  *uuid.UUID `json:"assigned_to" db:"assigned_to"`
-	Output      string     `json:"output" db:"output"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID  `json:"id" db:"id"`
+	ProjectID      uuid.UUID  `json:"project_id" db:"project_id"`
+	Title          string     `json:"title" db:"title"`
+	Description    string     `json:"description" db:"description"`
+	Status         TaskStatus `json:"status" db:"status"`
+	Priority       string     `json:"priority" db:"priority"`
+	CreatedBy      uuid.UUID  `json:"created_by" db:"created_by"`
+	AssignedTo     *uuid.UUID `json:"assigned_to" db:"assigned_to"`
+	Output         string     `json:"output" db:"output"`
+	ParentID       *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty" db:"lease_expires_at"`
+	DueAt          *time.Time `json:"due_at,omitempty" db:"due_at"`
+	SLA            *Duration  `json:"sla,omitempty" db:"sla_seconds"`
+	StartedAt      *time.Time `json:"started_at,omitempty" db:"started_at"`
+	OverdueAt      *time.Time `json:"overdue_at,omitempty" db:"overdue_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	// Progress rolls up the task's subtasks; it is only set on tasks that have any
+	Progress *TaskProgress `json:"progress,omitempty" db:"-"`
+}
+
+// TaskProgress summarises how far a task's subtasks have got. Cancelled
+// subtasks are left out of the total.
+type TaskProgress struct {
+	Total   int `json:"total"`
+	Done    int `json:"done"`
+	Percent int `json:"percent"`
+}
+
+// NewTaskProgress computes the percentage of done subtasks, rounded down
+func NewTaskProgress(total, done int) *TaskProgress {
+	p := &TaskProgress{Total: total, Done: done}
+	if total > 0 {
+		p.Percent = done * 100 / total
+	}
+	return p
 }
 
 type CreateTaskRequest struct {
-	ProjectID   uuid.UUID  `json:"project_id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Priority    string     `json:"priority"`
-	CreatedBy   uuid.UUID  `json:"created_by"`
-	AssignedTo  *uuid.UUID `json:"assigned_to"`
+	ProjectID   uuid.UUID   `json:"project_id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Priority    string      `json:"priority"`
+	CreatedBy   uuid.UUID   `json:"created_by"`
+	AssignedTo  *uuid.UUID  `json:"assigned_to"`
+	ParentID    *uuid.UUID  `json:"parent_id"`
+	DueAt       *time.Time  `json:"due_at"`
+	SLA         *Duration   `json:"sla"`
+	AutoAssign  *AutoAssign `json:"auto_assign"`
+}
+
+// AutoAssign asks the server to pick a task's assignee using the project's
+// assign strategy. Candidates must match Role and Team when they are set;
+// Capabilities are the skills the task needs.
+type AutoAssign struct {
+	Role         AgentRole `json:"role,omitempty"`
+	Team         string    `json:"team,omitempty"`
+	Capabilities []string  `json:"capabilities,omitempty"`
+}
+
+// SubtaskRequest describes one subtask in a CreateSubtasksRequest
+type SubtaskRequest struct {
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Priority    string      `json:"priority"`
+	AssignedTo  *uuid.UUID  `json:"assigned_to"`
+	DueAt       *time.Time  `json:"due_at"`
+	SLA         *Duration   `json:"sla"`
+	AutoAssign  *AutoAssign `json:"auto_assign"`
+}
+
+// CreateSubtasksRequest breaks a task down into several subtasks at once
+type CreateSubtasksRequest struct {
+	CreatedBy uuid.UUID        `json:"created_by"`
+	Subtasks  []SubtaskRequest `json:"subtasks"`
 }
 
 type UpdateTaskRequest struct {
@@ -44,6 +150,16 @@ type UpdateTaskRequest struct {
 	Output string     `json:"output"`
 }
 
+// ScheduleTaskRequest replaces a task's due date and SLA; nil clears them
+type ScheduleTaskRequest struct {
+	DueAt *time.Time `json:"due_at"`
+	SLA   *Duration  `json:"sla"`
+}
+
+type ClaimTaskRequest struct {
+	AgentID uuid.UUID `json:"agent_id"`
+}
+
 type ReassignTaskRequest struct {
 	AssignedTo uuid.UUID `json:"assigned_to"`
 }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ToolApprovalStatus is where an approval request stands
+type ToolApprovalStatus string
+
+const (
+	ApprovalPending  ToolApprovalStatus = "pending"
+	ApprovalApproved ToolApprovalStatus = "approved"
+	ApprovalRejected ToolApprovalStatus = "rejected"
+	// ApprovalExpired means nobody decided before the tool call gave up waiting
+	ApprovalExpired ToolApprovalStatus = "expired"
+)
+
+// ToolApproval is an MCP tool call held until a human approves or rejects it
+type ToolApproval struct {
+	ID          uuid.UUID          `json:"id" db:"id"`
+	ProjectID   uuid.UUID          `json:"project_id" db:"project_id"`
+	AgentID     *uuid.UUID         `json:"agent_id,omitempty" db:"agent_id"`
+	ToolName    string             `json:"tool_name" db:"tool_name"`
+	Arguments   json.RawMessage    `json:"arguments" db:"arguments"`
+	Summary     string             `json:"summary" db:"summary"`
+	Status      ToolApprovalStatus `json:"status" db:"status"`
+	Reason      string             `json:"reason,omitempty" db:"reason"`
+	DecidedBy   *uuid.UUID         `json:"decided_by,omitempty" db:"decided_by"`
+	RequestedAt time.Time          `json:"requested_at" db:"requested_at"`
+	DecidedAt   *time.Time         `json:"decided_at,omitempty" db:"decided_at"`
+}
+
+type DecideToolApprovalRequest struct {
+	Approved *bool  `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
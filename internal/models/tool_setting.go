@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ToolSetting switches an MCP tool on or off for a project, or for one agent
+// of the project. Tools without a setting are enabled, and a setting for an
+// agent overrides the one for its project.
+type ToolSetting struct {
+	ProjectID uuid.UUID  `json:"project_id" db:"project_id"`
+	AgentID   *uuid.UUID `json:"agent_id,omitempty" db:"agent_id"`
+	ToolName  string     `json:"tool_name" db:"tool_name"`
+	Enabled   bool       `json:"enabled" db:"enabled"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type UpdateToolSettingRequest struct {
+	// ToolName comes from the URL
+	ToolName string     `json:"-"`
+	AgentID  *uuid.UUID `json:"agent_id,omitempty"`
+	Enabled  *bool      `json:"enabled"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UpstreamCall is an audit entry for a tool call proxied to a downstream MCP
+// server, attributed to the project and agent of the calling connection
+type UpstreamCall struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	ProjectID  *uuid.UUID      `json:"project_id,omitempty" db:"project_id"`
+	AgentID    *uuid.UUID      `json:"agent_id,omitempty" db:"agent_id"`
+	Server     string          `json:"server" db:"server"`
+	ToolName   string          `json:"tool_name" db:"tool_name"`
+	Arguments  json.RawMessage `json:"arguments" db:"arguments"`
+	IsError    bool            `json:"is_error" db:"is_error"`
+	Error      string          `json:"error,omitempty" db:"error"`
+	DurationMS int             `json:"duration_ms" db:"duration_ms"`
+	CalledAt   time.Time       `json:"called_at" db:"called_at"`
+}
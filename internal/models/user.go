@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User is a human operator who can hold roles in projects
+type User struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Email     string    `json:"email,omitempty" db:"email"`
+	IsAdmin   bool      `json:"is_admin" db:"is_admin"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateUserRequest struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+// ProjectMember is a role assignment for a user or agent within a project
+type ProjectMember struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	ProjectID uuid.UUID  `json:"project_id" db:"project_id"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	AgentID   *uuid.UUID `json:"agent_id,omitempty" db:"agent_id"`
+	Name      string     `json:"name"`
+	Role      string     `json:"role" db:"role"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// SetProjectMemberRequest assigns a role to exactly one of a user or an agent
+type SetProjectMemberRequest struct {
+	UserID  *uuid.UUID `json:"user_id,omitempty"`
+	AgentID *uuid.UUID `json:"agent_id,omitempty"`
+	Role    string     `json:"role"`
+}
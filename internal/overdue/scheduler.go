@@ -0,0 +1,161 @@
+// Package overdue escalates tasks that miss their due date or overrun their
+// SLA. A scheduler periodically marks such tasks, records the escalation in
+// their history and notifies each project through the hub.
+package overdue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/websocket"
+)
+
+const DefaultInterval = time.Minute
+
+// Reason tells why a task was marked overdue
+type Reason string
+
+const (
+	// ReasonDueDate means the task was still open when its due date passed
+	ReasonDueDate Reason = "due_date"
+	// ReasonSLA means the task stayed in progress longer than its SLA
+	ReasonSLA Reason = "sla"
+)
+
+// Config controls how often tasks are checked and the SLA applied to
+// in-progress tasks that have none of their own. A zero DefaultSLA only
+// checks tasks with an explicit SLA.
+type Config struct {
+	Interval   time.Duration
+	DefaultSLA time.Duration
+}
+
+// Overdue describes a task the scheduler has just marked overdue
+type Overdue struct {
+	TaskID     uuid.UUID         `json:"task_id"`
+	ProjectID  uuid.UUID         `json:"project_id"`
+	Title      string            `json:"title"`
+	Status     models.TaskStatus `json:"status"`
+	AssignedTo *uuid.UUID        `json:"assigned_to,omitempty"`
+	Reason     Reason            `json:"reason"`
+	DueAt      *time.Time        `json:"due_at,omitempty"`
+	StartedAt  *time.Time        `json:"started_at,omitempty"`
+	SLA        *models.Duration  `json:"sla,omitempty"`
+	OverdueAt  time.Time         `json:"overdue_at"`
+}
+
+// Scheduler finds and escalates overdue tasks
+type Scheduler struct {
+	db     *database.DB
+	hub    *websocket.Hub
+	config Config
+}
+
+// NewScheduler creates a scheduler, filling in defaults for zero config values
+func NewScheduler(db *database.DB, hub *websocket.Hub, config Config) *Scheduler {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.DefaultSLA < 0 {
+		config.DefaultSLA = 0
+	}
+	return &Scheduler{db: db, hub: hub, config: config}
+}
+
+// Run checks for overdue tasks every Interval until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.Check(ctx); err != nil {
+				log.Printf("Overdue scheduler: %v", err)
+			} else if n > 0 {
+				log.Printf("Overdue scheduler: marked %d task(s) overdue", n)
+			}
+		}
+	}
+}
+
+// Check marks open tasks that are past their due date, and in-progress tasks
+// that have overrun their SLA, as overdue. Each task is escalated once until
+// it is rescheduled or restarted.
+func (s *Scheduler) Check(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH late AS (
+			SELECT id,
+			       CASE WHEN due_at < NOW() THEN 'due_date' ELSE 'sla' END AS reason,
+			       COALESCE(sla_seconds, NULLIF($1, 0)) AS sla
+			FROM tasks
+			WHERE overdue_at IS NULL
+			  AND status IN ('pending', 'in_progress', 'blocked')
+			  AND (due_at < NOW()
+			       OR (status = 'in_progress'
+			           AND started_at + COALESCE(sla_seconds, NULLIF($1, 0)) * INTERVAL '1 second' < NOW()))
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE tasks t
+		SET overdue_at = NOW()
+		FROM late l
+		WHERE t.id = l.id
+		RETURNING t.id, t.project_id, t.title, t.status, t.assigned_to, t.due_at, t.started_at, t.overdue_at, l.reason, l.sla
+	`, int64(s.config.DefaultSLA/time.Second))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find overdue tasks: %w", err)
+	}
+
+	var marked []Overdue
+	for rows.Next() {
+		var o Overdue
+		if err := rows.Scan(&o.TaskID, &o.ProjectID, &o.Title, &o.Status, &o.AssignedTo, &o.DueAt, &o.StartedAt, &o.OverdueAt, &o.Reason, &o.SLA); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		marked = append(marked, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, o := range marked {
+		err := history.Record(ctx, tx, history.Actor{}, models.TaskEvent{
+			TaskID:        o.TaskID,
+			ProjectID:     o.ProjectID,
+			Event:         models.EventTaskOverdue,
+			OldStatus:     o.Status,
+			NewStatus:     o.Status,
+			OldAssignedTo: o.AssignedTo,
+			NewAssignedTo: o.AssignedTo,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit overdue tasks: %w", err)
+	}
+
+	if s.hub != nil {
+		for _, o := range marked {
+			s.hub.BroadcastToProject(o.ProjectID, "task_overdue", o)
+		}
+	}
+	return len(marked), nil
+}
@@ -0,0 +1,21 @@
+package overdue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSchedulerDefaults(t *testing.T) {
+	s := NewScheduler(nil, nil, Config{DefaultSLA: -time.Hour})
+	if s.config.Interval != DefaultInterval {
+		t.Errorf("Expected default interval %s, got %s", DefaultInterval, s.config.Interval)
+	}
+	if s.config.DefaultSLA != 0 {
+		t.Errorf("Expected a negative default SLA to disable it, got %s", s.config.DefaultSLA)
+	}
+
+	s = NewScheduler(nil, nil, Config{Interval: time.Second, DefaultSLA: time.Hour})
+	if s.config.Interval != time.Second || s.config.DefaultSLA != time.Hour {
+		t.Errorf("Expected configured values to be kept, got %+v", s.config)
+	}
+}
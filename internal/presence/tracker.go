@@ -0,0 +1,318 @@
+// Package presence derives agent status from heartbeats. A tracker
+// periodically moves agents that stopped sending heartbeats to idle and then
+// offline, records every transition and notifies each project through the hub.
+package presence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/taskgraph"
+	"github.com/techbuzzz/agent-shaker/internal/websocket"
+)
+
+const (
+	DefaultIdleAfter    = 2 * time.Minute
+	DefaultOfflineAfter = 10 * time.Minute
+	DefaultInterval     = 30 * time.Second
+)
+
+var ErrAgentNotFound = errors.New("agent not found")
+
+// Config controls the heartbeat age at which agents become idle and offline,
+// how often agents are checked, and whether in-progress tasks held by agents
+// that go offline are returned to pending
+type Config struct {
+	IdleAfter    time.Duration
+	OfflineAfter time.Duration
+	Interval     time.Duration
+	ReleaseTasks bool
+}
+
+// Tracker derives and records agent presence
+type Tracker struct {
+	db     *database.DB
+	hub    *websocket.Hub
+	graph  *taskgraph.Service
+	config Config
+}
+
+// NewTracker creates a presence tracker, filling in defaults for zero config
+// values. OfflineAfter is never shorter than IdleAfter.
+func NewTracker(db *database.DB, hub *websocket.Hub, graph *taskgraph.Service, config Config) *Tracker {
+	if config.IdleAfter <= 0 {
+		config.IdleAfter = DefaultIdleAfter
+	}
+	if config.OfflineAfter <= 0 {
+		config.OfflineAfter = DefaultOfflineAfter
+	}
+	if config.OfflineAfter < config.IdleAfter {
+		config.OfflineAfter = config.IdleAfter
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	return &Tracker{db: db, hub: hub, graph: graph, config: config}
+}
+
+// Derive returns the status an agent should have given its current status
+// and the time since its last heartbeat. Active and working agents become
+// idle after IdleAfter; any agent becomes offline after OfflineAfter. Other
+// self-reported statuses, such as blocked, are kept until the agent is offline.
+func (t *Tracker) Derive(status string, age time.Duration) string {
+	switch {
+	case age >= t.config.OfflineAfter:
+		return models.AgentOffline
+	case age >= t.config.IdleAfter && (status == models.AgentActive || status == "working"):
+		return models.AgentIdle
+	default:
+		return status
+	}
+}
+
+// Run checks agent presence every Interval until ctx is cancelled
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := t.Check(ctx); err != nil {
+				log.Printf("Presence tracker: %v", err)
+			} else if n > 0 {
+				log.Printf("Presence tracker: updated presence of %d agent(s)", n)
+			}
+		}
+	}
+}
+
+// Check moves agents whose heartbeats are older than the thresholds to idle
+// or offline and returns how many agents changed
+func (t *Tracker) Check(ctx context.Context) (int, error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Ages are computed by the database so they do not depend on clock skew
+	// between the server and Postgres
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, project_id, name, COALESCE(status, 'active'),
+		       EXTRACT(EPOCH FROM NOW() - COALESCE(last_seen, created_at))
+		FROM agents
+		WHERE status IS DISTINCT FROM 'offline'
+		  AND COALESCE(last_seen, created_at) < NOW() - $1 * INTERVAL '1 second'
+		FOR UPDATE SKIP LOCKED
+	`, t.config.IdleAfter.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to load agents: %w", err)
+	}
+
+	var changed []models.AgentPresence
+	for rows.Next() {
+		var p models.AgentPresence
+		var age float64
+		if err := rows.Scan(&p.AgentID, &p.ProjectID, &p.AgentName, &p.OldStatus, &age); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		p.NewStatus = t.Derive(p.OldStatus, time.Duration(age*float64(time.Second)))
+		if p.NewStatus != p.OldStatus {
+			p.Reason = models.PresenceTimeout
+			changed = append(changed, p)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i := range changed {
+		if err := t.record(ctx, tx, &changed[i]); err != nil {
+			return 0, err
+		}
+		if changed[i].NewStatus == models.AgentOffline && t.config.ReleaseTasks {
+			if err := t.release(ctx, tx, &changed[i]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit presence changes: %w", err)
+	}
+
+	for _, p := range changed {
+		t.broadcast(ctx, p)
+	}
+	return len(changed), nil
+}
+
+// Seen records a heartbeat from agentID. An idle or offline agent takes the
+// status it reported, which defaults to active.
+func (t *Tracker) Seen(ctx context.Context, agentID uuid.UUID, status string) error {
+	if status == "" {
+		status = models.AgentActive
+	}
+	return t.update(ctx, agentID, func(old string) (string, models.PresenceReason) {
+		if old == models.AgentIdle || old == models.AgentOffline {
+			return status, models.PresenceHeartbeat
+		}
+		return old, ""
+	})
+}
+
+// Set changes an agent's status on request of the agent or a user, which
+// also counts as a heartbeat
+func (t *Tracker) Set(ctx context.Context, agentID uuid.UUID, status string) error {
+	return t.update(ctx, agentID, func(string) (string, models.PresenceReason) {
+		return status, models.PresenceManual
+	})
+}
+
+// update touches last_seen and applies the status chosen by next, recording
+// a transition when the status changes
+func (t *Tracker) update(ctx context.Context, agentID uuid.UUID, next func(old string) (string, models.PresenceReason)) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	p := models.AgentPresence{AgentID: agentID}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE agents a SET last_seen = NOW()
+		FROM (SELECT id, status FROM agents WHERE id = $1 FOR UPDATE) old
+		WHERE a.id = old.id
+		RETURNING a.project_id, a.name, COALESCE(old.status, 'active')
+	`, agentID).Scan(&p.ProjectID, &p.AgentName, &p.OldStatus)
+	if err == sql.ErrNoRows {
+		return ErrAgentNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to update agent: %w", err)
+	}
+
+	p.NewStatus, p.Reason = next(p.OldStatus)
+	if p.NewStatus == p.OldStatus {
+		return tx.Commit()
+	}
+	if err := t.record(ctx, tx, &p); err != nil {
+		return err
+	}
+	// Going offline by hand releases tasks just like timing out does
+	if p.NewStatus == models.AgentOffline && t.config.ReleaseTasks {
+		if err := t.release(ctx, tx, &p); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit presence change: %w", err)
+	}
+
+	t.broadcast(ctx, p)
+	return nil
+}
+
+// record sets the agent's new status and stores the transition
+func (t *Tracker) record(ctx context.Context, tx *sql.Tx, p *models.AgentPresence) error {
+	err := tx.QueryRowContext(ctx, "UPDATE agents SET status = $2 WHERE id = $1 RETURNING last_seen", p.AgentID, p.NewStatus).Scan(&p.LastSeen)
+	if err != nil {
+		return fmt.Errorf("failed to update agent status: %w", err)
+	}
+
+	p.ID = uuid.New()
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO agent_presence_events (id, agent_id, project_id, old_status, new_status, reason, last_seen)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`, p.ID, p.AgentID, p.ProjectID, p.OldStatus, p.NewStatus, p.Reason, p.LastSeen).Scan(&p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record presence change: %w", err)
+	}
+	return nil
+}
+
+// release returns the in-progress tasks held by an offline agent to pending
+func (t *Tracker) release(ctx context.Context, tx *sql.Tx, p *models.AgentPresence) error {
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE tasks
+		SET status = 'pending', assigned_to = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE assigned_to = $1 AND status = 'in_progress'
+		RETURNING id, project_id
+	`, p.AgentID)
+	if err != nil {
+		return fmt.Errorf("failed to release tasks: %w", err)
+	}
+
+	var events []models.TaskEvent
+	for rows.Next() {
+		var e models.TaskEvent
+		if err := rows.Scan(&e.TaskID, &e.ProjectID); err != nil {
+			rows.Close()
+			return err
+		}
+		previous := p.AgentID
+		e.Event = models.EventTaskReleased
+		e.OldStatus = models.StatusInProgress
+		e.NewStatus = models.StatusPending
+		e.OldAssignedTo = &previous
+		events = append(events, e)
+		p.ReleasedTasks = append(p.ReleasedTasks, e.TaskID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		if err := history.Record(ctx, tx, history.Actor{}, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Tracker) broadcast(ctx context.Context, p models.AgentPresence) {
+	if t.hub != nil {
+		t.hub.BroadcastToProject(p.ProjectID, "agent_presence", p)
+	}
+	if t.graph != nil && len(p.ReleasedTasks) > 0 {
+		t.graph.BroadcastTasks(ctx, p.ReleasedTasks)
+	}
+}
+
+// History returns an agent's most recent presence transitions, newest first
+func History(ctx context.Context, db *database.DB, agentID uuid.UUID, limit int) ([]models.AgentPresence, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, agent_id, project_id, old_status, new_status, reason, last_seen, created_at
+		FROM agent_presence_events
+		WHERE agent_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, agentID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load presence history: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AgentPresence{}
+	for rows.Next() {
+		var p models.AgentPresence
+		if err := rows.Scan(&p.ID, &p.AgentID, &p.ProjectID, &p.OldStatus, &p.NewStatus, &p.Reason, &p.LastSeen, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, p)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,44 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+func TestNewTrackerDefaults(t *testing.T) {
+	tr := NewTracker(nil, nil, nil, Config{})
+	if tr.config.IdleAfter != DefaultIdleAfter || tr.config.OfflineAfter != DefaultOfflineAfter || tr.config.Interval != DefaultInterval {
+		t.Errorf("Expected defaults, got %+v", tr.config)
+	}
+
+	tr = NewTracker(nil, nil, nil, Config{IdleAfter: time.Hour, OfflineAfter: time.Minute})
+	if tr.config.OfflineAfter != time.Hour {
+		t.Errorf("Expected OfflineAfter to be raised to IdleAfter, got %s", tr.config.OfflineAfter)
+	}
+}
+
+func TestDerive(t *testing.T) {
+	tr := NewTracker(nil, nil, nil, Config{IdleAfter: time.Minute, OfflineAfter: 5 * time.Minute})
+
+	tests := []struct {
+		status string
+		age    time.Duration
+		want   string
+	}{
+		{status: models.AgentActive, age: 30 * time.Second, want: models.AgentActive},
+		{status: models.AgentActive, age: 2 * time.Minute, want: models.AgentIdle},
+		{status: "working", age: 2 * time.Minute, want: models.AgentIdle},
+		{status: "blocked", age: 2 * time.Minute, want: "blocked"},
+		{status: models.AgentIdle, age: 2 * time.Minute, want: models.AgentIdle},
+		{status: "blocked", age: 5 * time.Minute, want: models.AgentOffline},
+		{status: models.AgentActive, age: time.Hour, want: models.AgentOffline},
+	}
+
+	for _, tt := range tests {
+		if got := tr.Derive(tt.status, tt.age); got != tt.want {
+			t.Errorf("Derive(%q, %s) = %q, want %q", tt.status, tt.age, got, tt.want)
+		}
+	}
+}
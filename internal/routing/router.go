@@ -0,0 +1,93 @@
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+// DefaultStaleAfter is how recently an agent must have been seen to be assigned work
+const DefaultStaleAfter = 15 * time.Minute
+
+// Config controls which agents are considered available
+type Config struct {
+	StaleAfter time.Duration
+}
+
+// Router assigns tasks using each project's strategy
+type Router struct {
+	config Config
+}
+
+// NewRouter creates a router, filling in defaults for zero config values
+func NewRouter(config Config) *Router {
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = DefaultStaleAfter
+	}
+	return &Router{config: config}
+}
+
+// Assign picks an agent in projectID for a new task and marks it as just
+// assigned. It runs inside the caller's transaction and serializes with other
+// assignments in the project so round-robin and load counts stay accurate.
+// It returns nil when no agent is eligible.
+func (r *Router) Assign(ctx context.Context, tx *sql.Tx, projectID uuid.UUID, req models.AutoAssign) (*uuid.UUID, error) {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('routing:' || $1))", projectID.String()); err != nil {
+		return nil, fmt.Errorf("failed to lock project routing: %w", err)
+	}
+
+	var name string
+	err := tx.QueryRowContext(ctx, "SELECT assign_strategy FROM projects WHERE id = $1", projectID).Scan(&name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assign strategy: %w", err)
+	}
+	strategy, ok := Lookup(name)
+	if !ok {
+		strategy, _ = Lookup(DefaultStrategy)
+	}
+
+	candidates, err := loadCandidates(ctx, tx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	picked, ok := strategy.Pick(Eligible(candidates, req, time.Now().Add(-r.config.StaleAfter)), req)
+	if !ok {
+		return nil, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE agents SET last_assigned_at = NOW() WHERE id = $1", picked.ID); err != nil {
+		return nil, fmt.Errorf("failed to record assignment: %w", err)
+	}
+	return &picked.ID, nil
+}
+
+func loadCandidates(ctx context.Context, tx *sql.Tx, projectID uuid.UUID) ([]Candidate, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.id, a.name, COALESCE(a.role, ''), COALESCE(a.team, ''), a.capabilities, COALESCE(a.status, 'active'),
+		       COALESCE(a.last_seen, a.created_at), a.last_assigned_at,
+		       (SELECT COUNT(*) FROM tasks t
+		        WHERE t.assigned_to = a.id AND t.status IN ('pending', 'in_progress', 'blocked'))
+		FROM agents a
+		WHERE a.project_id = $1
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agents: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []Candidate
+	for rows.Next() {
+		var c Candidate
+		if err := rows.Scan(&c.ID, &c.Name, &c.Role, &c.Team, pq.Array(&c.Capabilities), &c.Status, &c.LastSeen, &c.LastAssignedAt, &c.OpenTasks); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
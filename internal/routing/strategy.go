@@ -0,0 +1,213 @@
+// Package routing picks an agent for tasks created with auto_assign. Each
+// project selects a Strategy by name; the built-in strategies are
+// round_robin, least_loaded and skill_match, and others can be registered.
+package routing
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+const (
+	RoundRobin  = "round_robin"
+	LeastLoaded = "least_loaded"
+	SkillMatch  = "skill_match"
+
+	// DefaultStrategy is used by projects that have not chosen one
+	DefaultStrategy = LeastLoaded
+)
+
+// Candidate is an agent that may be assigned a task
+type Candidate struct {
+	ID             uuid.UUID
+	Name           string
+	Role           models.AgentRole
+	Team           string
+	Capabilities   []string
+	Status         string
+	LastSeen       time.Time
+	LastAssignedAt *time.Time
+	// OpenTasks counts the agent's pending, in-progress and blocked tasks
+	OpenTasks int
+}
+
+// Strategy chooses one of the eligible candidates for a task. Candidates
+// already match the requested role and team and are not offline; how the
+// requested capabilities are weighed is up to the strategy.
+type Strategy interface {
+	Name() string
+	Pick(candidates []Candidate, req models.AutoAssign) (Candidate, bool)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Strategy{}
+)
+
+func init() {
+	Register(roundRobin{})
+	Register(leastLoaded{})
+	Register(skillMatch{})
+}
+
+// Register makes a strategy selectable by its name, replacing any strategy
+// registered under the same name
+func Register(s Strategy) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[s.Name()] = s
+}
+
+// Lookup returns the strategy registered under name
+func Lookup(name string) (Strategy, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	s, ok := registry[name]
+	return s, ok
+}
+
+// Names lists the registered strategies in alphabetical order
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eligible keeps the candidates that are not offline, were seen after
+// cutoff and match the requested role and team
+func Eligible(candidates []Candidate, req models.AutoAssign, cutoff time.Time) []Candidate {
+	var out []Candidate
+	for _, c := range candidates {
+		if c.Status == "offline" || c.LastSeen.Before(cutoff) {
+			continue
+		}
+		if req.Role != "" && !strings.EqualFold(string(c.Role), string(req.Role)) {
+			continue
+		}
+		if req.Team != "" && !strings.EqualFold(c.Team, req.Team) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// matches counts how many of the wanted capabilities c has
+func matches(c Candidate, wanted []string) int {
+	n := 0
+	for _, w := range wanted {
+		for _, have := range c.Capabilities {
+			if strings.EqualFold(have, w) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// capable keeps the candidates that have every wanted capability
+func capable(candidates []Candidate, wanted []string) []Candidate {
+	var out []Candidate
+	for _, c := range candidates {
+		if matches(c, wanted) == len(wanted) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// assignedBefore orders candidates by how long ago they were last given a
+// task, never-assigned agents first, falling back to name for stability
+func assignedBefore(a, b Candidate) bool {
+	switch {
+	case a.LastAssignedAt == nil && b.LastAssignedAt != nil:
+		return true
+	case a.LastAssignedAt != nil && b.LastAssignedAt == nil:
+		return false
+	case a.LastAssignedAt != nil && !a.LastAssignedAt.Equal(*b.LastAssignedAt):
+		return a.LastAssignedAt.Before(*b.LastAssignedAt)
+	}
+	return a.Name < b.Name
+}
+
+// lessLoaded orders candidates by open tasks, preferring active over idle
+// agents and then the least recently assigned
+func lessLoaded(a, b Candidate) bool {
+	if a.OpenTasks != b.OpenTasks {
+		return a.OpenTasks < b.OpenTasks
+	}
+	if (a.Status == "active") != (b.Status == "active") {
+		return a.Status == "active"
+	}
+	return assignedBefore(a, b)
+}
+
+func first(candidates []Candidate, less func(a, b Candidate) bool) (Candidate, bool) {
+	if len(candidates) == 0 {
+		return Candidate{}, false
+	}
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if less(c, best) {
+			best = c
+		}
+	}
+	return best, true
+}
+
+// roundRobin rotates through capable agents, picking the one that has gone
+// longest without an assignment
+type roundRobin struct{}
+
+func (roundRobin) Name() string { return RoundRobin }
+
+func (roundRobin) Pick(candidates []Candidate, req models.AutoAssign) (Candidate, bool) {
+	return first(capable(candidates, req.Capabilities), assignedBefore)
+}
+
+// leastLoaded picks the capable agent with the fewest open tasks
+type leastLoaded struct{}
+
+func (leastLoaded) Name() string { return LeastLoaded }
+
+func (leastLoaded) Pick(candidates []Candidate, req models.AutoAssign) (Candidate, bool) {
+	return first(capable(candidates, req.Capabilities), lessLoaded)
+}
+
+// skillMatch picks the agent covering the most requested capabilities, even
+// when nobody covers all of them, breaking ties by load
+type skillMatch struct{}
+
+func (skillMatch) Name() string { return SkillMatch }
+
+func (skillMatch) Pick(candidates []Candidate, req models.AutoAssign) (Candidate, bool) {
+	if len(req.Capabilities) == 0 {
+		return first(candidates, lessLoaded)
+	}
+
+	var best []Candidate
+	bestScore := 0
+	for _, c := range candidates {
+		score := matches(c, req.Capabilities)
+		switch {
+		case score == 0 || score < bestScore:
+		case score > bestScore:
+			bestScore = score
+			best = []Candidate{c}
+		default:
+			best = append(best, c)
+		}
+	}
+	return first(best, lessLoaded)
+}
@@ -0,0 +1,84 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+func candidate(name string, open int, caps ...string) Candidate {
+	return Candidate{ID: uuid.New(), Name: name, Role: "backend", Status: "active", LastSeen: time.Now(), OpenTasks: open, Capabilities: caps}
+}
+
+func TestEligible(t *testing.T) {
+	now := time.Now()
+	fresh := candidate("fresh", 0)
+	offline := candidate("offline", 0)
+	offline.Status = "offline"
+	stale := candidate("stale", 0)
+	stale.LastSeen = now.Add(-time.Hour)
+	frontend := candidate("frontend", 0)
+	frontend.Role = "frontend"
+	other := candidate("other-team", 0)
+	other.Team = "payments"
+
+	got := Eligible([]Candidate{fresh, offline, stale, frontend, other}, models.AutoAssign{Role: "Backend"}, now.Add(-time.Minute))
+	if len(got) != 2 || got[0].Name != "fresh" || got[1].Name != "other-team" {
+		t.Errorf("Expected fresh and other-team, got %+v", got)
+	}
+
+	got = Eligible([]Candidate{fresh, other}, models.AutoAssign{Team: "payments"}, now.Add(-time.Minute))
+	if len(got) != 1 || got[0].Name != "other-team" {
+		t.Errorf("Expected only other-team, got %+v", got)
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	earlier := time.Now().Add(-time.Hour)
+	later := time.Now()
+
+	busyGo := candidate("busy-go", 3, "go", "postgres")
+	busyGo.LastAssignedAt = &earlier
+	idleGo := candidate("idle-go", 1, "Go")
+	idleGo.LastAssignedAt = &later
+	vue := candidate("vue", 0, "vue")
+
+	candidates := []Candidate{busyGo, idleGo, vue}
+
+	tests := []struct {
+		strategy string
+		req      models.AutoAssign
+		want     string
+		wantOK   bool
+	}{
+		{strategy: LeastLoaded, want: "vue", wantOK: true},
+		{strategy: LeastLoaded, req: models.AutoAssign{Capabilities: []string{"go"}}, want: "idle-go", wantOK: true},
+		{strategy: LeastLoaded, req: models.AutoAssign{Capabilities: []string{"go", "postgres"}}, want: "busy-go", wantOK: true},
+		{strategy: LeastLoaded, req: models.AutoAssign{Capabilities: []string{"rust"}}, wantOK: false},
+		{strategy: RoundRobin, want: "vue", wantOK: true},
+		{strategy: RoundRobin, req: models.AutoAssign{Capabilities: []string{"go"}}, want: "busy-go", wantOK: true},
+		{strategy: SkillMatch, req: models.AutoAssign{Capabilities: []string{"go", "postgres", "redis"}}, want: "busy-go", wantOK: true},
+		{strategy: SkillMatch, req: models.AutoAssign{Capabilities: []string{"go", "redis"}}, want: "idle-go", wantOK: true},
+		{strategy: SkillMatch, req: models.AutoAssign{Capabilities: []string{"rust"}}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		s, ok := Lookup(tt.strategy)
+		if !ok {
+			t.Fatalf("Strategy %s is not registered", tt.strategy)
+		}
+		got, ok := s.Pick(candidates, tt.req)
+		if ok != tt.wantOK || (ok && got.Name != tt.want) {
+			t.Errorf("%s with %v: got %q (ok %v), want %q (ok %v)", tt.strategy, tt.req.Capabilities, got.Name, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := Names()
+	if len(names) < 3 || names[0] != LeastLoaded || names[1] != RoundRobin || names[2] != SkillMatch {
+		t.Errorf("Expected the built-in strategies in order, got %v", names)
+	}
+}
@@ -0,0 +1,424 @@
+// Package subtasks manages task hierarchies: breaking a task down into
+// subtasks, rolling their progress up to the parent and applying the
+// cascade rules when a task with subtasks is deleted.
+package subtasks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/routing"
+	"github.com/techbuzzz/agent-shaker/internal/websocket"
+)
+
+var (
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrParentNotFound    = errors.New("parent task not found")
+	ErrCrossProject      = errors.New("a subtask must belong to the same project as its parent")
+	ErrAgentNotFound     = errors.New("agent not found, or assignee outside the task's project")
+	ErrHasSubtasks       = errors.New("task has subtasks; delete with children=cascade or children=detach")
+	ErrInvalidDeleteMode = errors.New("children must be restrict, cascade or detach")
+)
+
+// DeleteMode decides what happens to the subtasks of a deleted task
+type DeleteMode string
+
+const (
+	// DeleteRestrict refuses to delete a task that still has subtasks
+	DeleteRestrict DeleteMode = "restrict"
+	// DeleteCascade deletes the whole subtree
+	DeleteCascade DeleteMode = "cascade"
+	// DeleteDetach moves the subtasks up to the deleted task's parent
+	DeleteDetach DeleteMode = "detach"
+)
+
+// ParseDeleteMode reads a delete mode, defaulting to restrict
+func ParseDeleteMode(s string) (DeleteMode, error) {
+	switch DeleteMode(s) {
+	case "", DeleteRestrict:
+		return DeleteRestrict, nil
+	case DeleteCascade, DeleteDetach:
+		return DeleteMode(s), nil
+	}
+	return "", ErrInvalidDeleteMode
+}
+
+// Service manages subtasks
+type Service struct {
+	db     *database.DB
+	hub    *websocket.Hub
+	router *routing.Router
+}
+
+// NewService creates a new subtask service
+func NewService(db *database.DB, hub *websocket.Hub, router *routing.Router) *Service {
+	return &Service{db: db, hub: hub, router: router}
+}
+
+// CheckParent verifies that parentID exists in projectID
+func (s *Service) CheckParent(ctx context.Context, parentID, projectID uuid.UUID) error {
+	var parentProject uuid.UUID
+	err := s.db.QueryRowContext(ctx, "SELECT project_id FROM tasks WHERE id = $1", parentID).Scan(&parentProject)
+	if err == sql.ErrNoRows {
+		return ErrParentNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to load parent task: %w", err)
+	}
+	if parentProject != projectID {
+		return ErrCrossProject
+	}
+	return nil
+}
+
+// Create adds subtasks under parentID in one transaction, recording each in
+// the task history, and returns them in request order
+func (s *Service) Create(ctx context.Context, parentID uuid.UUID, req models.CreateSubtasksRequest, actor history.Actor) ([]models.Task, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var projectID uuid.UUID
+	err = tx.QueryRowContext(ctx, "SELECT project_id FROM tasks WHERE id = $1", parentID).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		return nil, ErrParentNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load parent task: %w", err)
+	}
+
+	if err := checkAgents(ctx, tx, projectID, req); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]models.Task, 0, len(req.Subtasks))
+	for _, st := range req.Subtasks {
+		t := models.Task{
+			ID:          uuid.New(),
+			ProjectID:   projectID,
+			Title:       st.Title,
+			Description: st.Description,
+			Status:      models.StatusPending,
+			Priority:    st.Priority,
+			CreatedBy:   req.CreatedBy,
+			AssignedTo:  st.AssignedTo,
+			ParentID:    &parentID,
+			DueAt:       st.DueAt,
+			SLA:         st.SLA,
+		}
+		if t.Priority == "" {
+			t.Priority = "medium"
+		}
+		if st.AutoAssign != nil && t.AssignedTo == nil {
+			if t.AssignedTo, err = s.router.Assign(ctx, tx, projectID, *st.AutoAssign); err != nil {
+				return nil, err
+			}
+		}
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO tasks (id, project_id, title, description, status, priority, created_by, assigned_to, parent_id, due_at, sla_seconds, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+			RETURNING created_at, updated_at
+		`, t.ID, t.ProjectID, t.Title, t.Description, t.Status, t.Priority, t.CreatedBy, t.AssignedTo, t.ParentID, t.DueAt, t.SLA).Scan(&t.CreatedAt, &t.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create subtask: %w", err)
+		}
+
+		err = history.Record(ctx, tx, actor, models.TaskEvent{
+			TaskID:        t.ID,
+			ProjectID:     t.ProjectID,
+			Event:         models.EventTaskCreated,
+			NewStatus:     t.Status,
+			NewAssignedTo: t.AssignedTo,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, t := range tasks {
+		s.broadcast(t.ProjectID, "task_update", t)
+	}
+	return tasks, nil
+}
+
+// checkAgents verifies that the creator exists and every assignee belongs to
+// the project
+func checkAgents(ctx context.Context, tx *sql.Tx, projectID uuid.UUID, req models.CreateSubtasksRequest) error {
+	assignees := map[uuid.UUID]bool{}
+	for _, st := range req.Subtasks {
+		if st.AssignedTo != nil {
+			assignees[*st.AssignedTo] = true
+		}
+	}
+	list := make([]string, 0, len(assignees))
+	for id := range assignees {
+		list = append(list, id.String())
+	}
+
+	var creatorExists bool
+	var found int
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM agents WHERE id = $1),
+			(SELECT COUNT(*) FROM agents WHERE id = ANY($2::uuid[]) AND project_id = $3)
+	`, req.CreatedBy, pq.Array(list), projectID).Scan(&creatorExists, &found)
+	if err != nil {
+		return fmt.Errorf("failed to verify agents: %w", err)
+	}
+	if !creatorExists || found != len(assignees) {
+		return ErrAgentNotFound
+	}
+	return nil
+}
+
+// Children returns the direct subtasks of a task, oldest first
+func (s *Service) Children(ctx context.Context, parentID uuid.UUID) ([]models.Task, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, parent_id, due_at, sla_seconds, started_at, overdue_at, created_at, updated_at
+		FROM tasks
+		WHERE parent_id = $1
+		ORDER BY created_at, id
+	`, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subtasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var t models.Task
+		var output sql.NullString
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &t.CreatedBy, &t.AssignedTo, &output, &t.ParentID, &t.DueAt, &t.SLA, &t.StartedAt, &t.OverdueAt, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		t.Output = output.String
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Subtasks may be broken down further
+	for i := range tasks {
+		if tasks[i].Progress, err = s.Progress(ctx, tasks[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
+
+// Progress rolls up a task's direct subtasks. It returns nil for tasks
+// without subtasks.
+func (s *Service) Progress(ctx context.Context, taskID uuid.UUID) (*models.TaskProgress, error) {
+	var all, total, done int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE status <> 'cancelled'),
+		       COUNT(*) FILTER (WHERE status = 'done')
+		FROM tasks
+		WHERE parent_id = $1
+	`, taskID).Scan(&all, &total, &done)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute progress: %w", err)
+	}
+	if all == 0 {
+		return nil, nil
+	}
+	return models.NewTaskProgress(total, done), nil
+}
+
+// Deletion reports what a Delete removed
+type Deletion struct {
+	ProjectID uuid.UUID
+	// Deleted lists the removed tasks, the requested task first
+	Deleted []uuid.UUID
+	// Dependents are surviving tasks that waited on a deleted task and may
+	// now be unblocked
+	Dependents []uuid.UUID
+	// Detached are subtasks that were moved up to the deleted task's parent
+	Detached []uuid.UUID
+}
+
+// Authorizer decides whether the caller may delete a task owned by the given
+// agent. It returns an error (typically auth.ErrForbidden) to refuse.
+type Authorizer func(owner uuid.UUID) error
+
+// Delete removes a task according to mode, along with the contexts linked to
+// every deleted task. Each deletion is recorded in the task history.
+// A cascade also calls authorize for the creator and assignee of every
+// subtask it removes, so deleting a task can't take other agents' work with it.
+func (s *Service) Delete(ctx context.Context, taskID uuid.UUID, mode DeleteMode, actor history.Actor, authorize Authorizer) (*Deletion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var parentID uuid.NullUUID
+	d := &Deletion{}
+	err = tx.QueryRowContext(ctx, "SELECT project_id, parent_id FROM tasks WHERE id = $1 FOR UPDATE", taskID).Scan(&d.ProjectID, &parentID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load task: %w", err)
+	}
+
+	children, err := queryIDs(ctx, tx, "SELECT id FROM tasks WHERE parent_id = $1 FOR UPDATE", taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(children) == 0:
+		d.Deleted = []uuid.UUID{taskID}
+	case mode == DeleteCascade:
+		subtree, err := loadSubtree(ctx, tx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeSubtree(subtree[1:], authorize); err != nil {
+			return nil, err
+		}
+		for _, t := range subtree {
+			d.Deleted = append(d.Deleted, t.ID)
+		}
+	case mode == DeleteDetach:
+		if _, err := tx.ExecContext(ctx, "UPDATE tasks SET parent_id = $1, updated_at = NOW() WHERE parent_id = $2", parentID, taskID); err != nil {
+			return nil, fmt.Errorf("failed to detach subtasks: %w", err)
+		}
+		d.Deleted = []uuid.UUID{taskID}
+		d.Detached = children
+	default:
+		return nil, ErrHasSubtasks
+	}
+
+	deleted := pq.Array(uuidStrings(d.Deleted))
+	d.Dependents, err = queryIDs(ctx, tx, `
+		SELECT DISTINCT task_id FROM task_dependencies
+		WHERE depends_on_id = ANY($1::uuid[]) AND NOT (task_id = ANY($1::uuid[]))
+	`, deleted)
+	if err != nil {
+		return nil, err
+	}
+
+	// The history outlives the tasks
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO task_events (id, task_id, project_id, event, actor_agent_id, actor_user_id, old_status, old_assigned_to, created_at)
+		SELECT gen_random_uuid(), id, project_id, $2, $3, $4, status, assigned_to, NOW()
+		FROM tasks WHERE id = ANY($1::uuid[])
+	`, deleted, string(models.EventTaskDeleted), actor.AgentID, actor.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record task history: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM contexts WHERE task_id = ANY($1::uuid[])", deleted); err != nil {
+		return nil, fmt.Errorf("failed to delete related contexts: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tasks WHERE id = ANY($1::uuid[])", deleted); err != nil {
+		return nil, fmt.Errorf("failed to delete task: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return d, nil
+}
+
+// subtreeTask is a task removed by a cascade, with the agents that own it
+type subtreeTask struct {
+	ID         uuid.UUID
+	CreatedBy  uuid.UUID
+	AssignedTo uuid.NullUUID
+}
+
+// loadSubtree locks and returns a task and all of its descendants, the task first
+func loadSubtree(ctx context.Context, tx *sql.Tx, taskID uuid.UUID) ([]subtreeTask, error) {
+	rows, err := tx.QueryContext(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth FROM tasks WHERE id = $1
+			UNION ALL
+			SELECT t.id, s.depth + 1 FROM tasks t JOIN subtree s ON t.parent_id = s.id
+		)
+		SELECT t.id, t.created_by, t.assigned_to
+		FROM subtree s JOIN tasks t ON t.id = s.id
+		ORDER BY s.depth
+		FOR UPDATE OF t
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []subtreeTask
+	for rows.Next() {
+		var t subtreeTask
+		if err := rows.Scan(&t.ID, &t.CreatedBy, &t.AssignedTo); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// authorizeSubtree checks the caller may delete every task, which needs both
+// its creator's and its assignee's permission
+func authorizeSubtree(tasks []subtreeTask, authorize Authorizer) error {
+	if authorize == nil {
+		return nil
+	}
+	for _, t := range tasks {
+		if err := authorize(t.CreatedBy); err != nil {
+			return err
+		}
+		if t.AssignedTo.Valid && t.AssignedTo.UUID != t.CreatedBy {
+			if err := authorize(t.AssignedTo.UUID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.String()
+	}
+	return s
+}
+
+func (s *Service) broadcast(projectID uuid.UUID, messageType string, payload interface{}) {
+	if s.hub != nil {
+		s.hub.BroadcastToProject(projectID, messageType, payload)
+	}
+}
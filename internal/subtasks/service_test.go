@@ -0,0 +1,59 @@
+package subtasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/auth"
+)
+
+func TestAuthorizeSubtree(t *testing.T) {
+	projectID := uuid.New()
+	self := uuid.New()
+	other := uuid.New()
+
+	authz := auth.NewService(nil, auth.Config{Required: true})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Kind: auth.PrincipalAgent, AgentID: self, ProjectID: projectID})
+	authorize := func(owner uuid.UUID) error {
+		return authz.Authorize(ctx, projectID, auth.ActionDeleteTask, owner)
+	}
+
+	tests := []struct {
+		name     string
+		children []subtreeTask
+		want     error
+	}{
+		{
+			name:     "own subtasks",
+			children: []subtreeTask{{ID: uuid.New(), CreatedBy: self}, {ID: uuid.New(), CreatedBy: self, AssignedTo: uuid.NullUUID{UUID: self, Valid: true}}},
+		},
+		{
+			name:     "foreign subtask",
+			children: []subtreeTask{{ID: uuid.New(), CreatedBy: self}, {ID: uuid.New(), CreatedBy: other}},
+			want:     auth.ErrForbidden,
+		},
+		{
+			name:     "own subtask assigned to another agent",
+			children: []subtreeTask{{ID: uuid.New(), CreatedBy: self, AssignedTo: uuid.NullUUID{UUID: other, Valid: true}}},
+			want:     auth.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := authorizeSubtree(tt.children, authorize); !errors.Is(err, tt.want) {
+				t.Errorf("authorizeSubtree() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{Kind: auth.PrincipalAdmin})
+	err := authorizeSubtree([]subtreeTask{{ID: uuid.New(), CreatedBy: other}}, func(owner uuid.UUID) error {
+		return authz.Authorize(admin, projectID, auth.ActionDeleteTask, owner)
+	})
+	if err != nil {
+		t.Errorf("Expected an admin to cascade over other agents' subtasks, got %v", err)
+	}
+}
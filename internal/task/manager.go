@@ -18,7 +18,9 @@ type TaskUpdate struct {
 	IsFinal bool   `json:"is_final"`
 }
 
-// TaskExecutor defines the interface for task execution logic
+// TaskExecutor defines the interface for task execution logic. Artifacts of
+// the result are added to the task. The context carries the caller's
+// principal and is cancelled when the task is.
 type TaskExecutor interface {
 	Execute(ctx context.Context, task *models.Task) (*models.Result, error)
 }
@@ -30,6 +32,10 @@ type Manager struct {
 	subscribers map[string][]chan TaskUpdate
 	mu          sync.RWMutex
 	baseURL     string
+
+	// running holds the cancel functions of executing tasks
+	running   map[string]context.CancelFunc
+	runningMu sync.Mutex
 }
 
 // NewManager creates a new task manager
@@ -39,6 +45,7 @@ func NewManager(store Store, executor TaskExecutor, baseURL string) *Manager {
 		executor:    executor,
 		subscribers: make(map[string][]chan TaskUpdate),
 		baseURL:     baseURL,
+		running:     make(map[string]context.CancelFunc),
 	}
 }
 
@@ -49,6 +56,7 @@ func (m *Manager) CreateTask(ctx context.Context, req *models.SendMessageRequest
 		ID:        uuid.New().String(),
 		Status:    models.TaskStatusPending,
 		Message:   req.Message,
+		Metadata:  req.Metadata,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -57,8 +65,13 @@ func (m *Manager) CreateTask(ctx context.Context, req *models.SendMessageRequest
 		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
 
-	// Trigger async execution
-	go m.executeTask(task.ID)
+	// Trigger async execution. It outlives the request but keeps its values,
+	// such as the caller's principal.
+	execCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	m.runningMu.Lock()
+	m.running[task.ID] = cancel
+	m.runningMu.Unlock()
+	go m.executeTask(execCtx, task.ID)
 
 	return task, nil
 }
@@ -97,6 +110,13 @@ func (m *Manager) CancelTask(ctx context.Context, taskID string) error {
 		return err
 	}
 
+	// Stop the executor; its outcome no longer matters
+	m.runningMu.Lock()
+	if cancel, ok := m.running[taskID]; ok {
+		cancel()
+	}
+	m.runningMu.Unlock()
+
 	m.notifySubscribers(taskID, TaskUpdate{
 		Event:   "cancelled",
 		Data:    task,
@@ -153,8 +173,15 @@ func (m *Manager) notifySubscribers(taskID string, update TaskUpdate) {
 }
 
 // executeTask runs the task execution logic asynchronously
-func (m *Manager) executeTask(taskID string) {
-	ctx := context.Background()
+func (m *Manager) executeTask(ctx context.Context, taskID string) {
+	defer func() {
+		m.runningMu.Lock()
+		if cancel, ok := m.running[taskID]; ok {
+			cancel()
+			delete(m.running, taskID)
+		}
+		m.runningMu.Unlock()
+	}()
 
 	// Get the task
 	task, err := m.store.GetTask(ctx, taskID)
@@ -199,6 +226,11 @@ func (m *Manager) executeTask(taskID string) {
 		}
 	}
 
+	if ctx.Err() != nil {
+		// Cancelled while executing: CancelTask has recorded the outcome
+		return
+	}
+
 	// Update task with result
 	now := time.Now()
 	task.CompletedAt = &now
@@ -213,6 +245,7 @@ func (m *Manager) executeTask(taskID string) {
 	} else {
 		task.Status = models.TaskStatusCompleted
 		task.Result = result
+		task.Artifacts = append(task.Artifacts, result.Artifacts...)
 	}
 
 	if err := m.store.UpdateTask(ctx, task); err != nil {
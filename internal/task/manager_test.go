@@ -0,0 +1,86 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/techbuzzz/agent-shaker/internal/a2a/models"
+)
+
+// blockingExecutor returns an artifact when released, or stops when cancelled
+type blockingExecutor struct {
+	release chan struct{}
+	stopped chan error
+}
+
+func (e *blockingExecutor) Execute(ctx context.Context, task *models.Task) (*models.Result, error) {
+	select {
+	case <-e.release:
+		return &models.Result{Content: "done", Format: "text", Artifacts: []models.Artifact{{ID: "a1", Name: "notes"}}}, nil
+	case <-ctx.Done():
+		e.stopped <- ctx.Err()
+		return nil, ctx.Err()
+	}
+}
+
+// waitForStatus polls the store until the task reaches status
+func waitForStatus(t *testing.T, m *Manager, id string, status models.TaskStatus) *models.Task {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		task, err := m.GetTask(context.Background(), id)
+		if err == nil && task.Status == status {
+			return task
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected task %s to become %s", id, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManagerSavesExecutorArtifacts(t *testing.T) {
+	exec := &blockingExecutor{release: make(chan struct{}), stopped: make(chan error, 1)}
+	m := NewManager(NewMemoryStore(""), exec, "")
+
+	req := &models.SendMessageRequest{Message: models.Message{Content: "hi"}, Metadata: models.Metadata{Priority: "high"}}
+	created, err := m.CreateTask(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if created.Metadata.Priority != "high" {
+		t.Errorf("Expected the request metadata to be kept, got %+v", created.Metadata)
+	}
+
+	close(exec.release)
+	task := waitForStatus(t, m, created.ID, models.TaskStatusCompleted)
+	if task.Result.Content != "done" || len(task.Artifacts) != 1 {
+		t.Errorf("Expected the result and artifact, got %+v", task)
+	}
+}
+
+func TestManagerCancelStopsExecutor(t *testing.T) {
+	exec := &blockingExecutor{release: make(chan struct{}), stopped: make(chan error, 1)}
+	m := NewManager(NewMemoryStore(""), exec, "")
+
+	created, err := m.CreateTask(context.Background(), &models.SendMessageRequest{Message: models.Message{Content: "hi"}})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	waitForStatus(t, m, created.ID, models.TaskStatusRunning)
+
+	if err := m.CancelTask(context.Background(), created.ID); err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+	select {
+	case <-exec.stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected cancelling the task to stop its executor")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	task, _ := m.GetTask(context.Background(), created.ID)
+	if task.Status != models.TaskStatusFailed || task.Result.Content != "Task was cancelled" {
+		t.Errorf("Expected the cancellation to be kept, got %+v", task.Result)
+	}
+}
@@ -0,0 +1,33 @@
+// Package taskgraph maintains dependency edges between tasks and keeps the
+// blocked/pending status of dependent tasks in sync with their prerequisites.
+package taskgraph
+
+import "github.com/google/uuid"
+
+// Edges maps a task to the tasks it depends on
+type Edges map[uuid.UUID][]uuid.UUID
+
+// WouldCycle reports whether adding the edge taskID -> dependsOnID would
+// create a cycle, i.e. whether taskID is already reachable from dependsOnID.
+func (e Edges) WouldCycle(taskID, dependsOnID uuid.UUID) bool {
+	if taskID == dependsOnID {
+		return true
+	}
+
+	visited := map[uuid.UUID]bool{}
+	stack := []uuid.UUID{dependsOnID}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current == taskID {
+			return true
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		stack = append(stack, e[current]...)
+	}
+	return false
+}
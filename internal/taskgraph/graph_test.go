@@ -0,0 +1,39 @@
+package taskgraph
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestWouldCycle(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	// a -> b -> c, d is independent
+	edges := Edges{
+		a: {b},
+		b: {c},
+	}
+
+	tests := []struct {
+		name      string
+		task      uuid.UUID
+		dependsOn uuid.UUID
+		want      bool
+	}{
+		{name: "self dependency", task: a, dependsOn: a, want: true},
+		{name: "direct back edge", task: b, dependsOn: a, want: true},
+		{name: "transitive back edge", task: c, dependsOn: a, want: true},
+		{name: "forward shortcut", task: a, dependsOn: c, want: false},
+		{name: "independent task", task: d, dependsOn: a, want: false},
+		{name: "onto independent task", task: c, dependsOn: d, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := edges.WouldCycle(tt.task, tt.dependsOn); got != tt.want {
+				t.Errorf("WouldCycle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,455 @@
+package taskgraph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/websocket"
+)
+
+var (
+	ErrTaskNotFound       = errors.New("task not found")
+	ErrSelfDependency     = errors.New("a task cannot depend on itself")
+	ErrCrossProject       = errors.New("tasks must belong to the same project")
+	ErrCycle              = errors.New("dependency would create a cycle")
+	ErrDependencyNotFound = errors.New("dependency not found")
+)
+
+// openPrerequisite matches dependency rows whose prerequisite is not finished yet
+const openPrerequisite = `
+	SELECT 1 FROM task_dependencies d
+	JOIN tasks p ON p.id = d.depends_on_id
+	WHERE d.task_id = t.id AND p.status <> 'done'
+`
+
+// Service manages task dependency edges and the statuses they imply
+type Service struct {
+	db  *database.DB
+	hub *websocket.Hub
+}
+
+// NewService creates a new task graph service
+func NewService(db *database.DB, hub *websocket.Hub) *Service {
+	return &Service{db: db, hub: hub}
+}
+
+// AddDependency records that taskID waits on dependsOnID. Adding an existing
+// edge is a no-op. The dependent task is blocked if the prerequisite is open.
+func (s *Service) AddDependency(ctx context.Context, taskID, dependsOnID uuid.UUID) (*models.TaskDependency, error) {
+	if taskID == dependsOnID {
+		return nil, ErrSelfDependency
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Both tasks must exist and share a project
+	var projectID, otherProjectID uuid.UUID
+	err = tx.QueryRowContext(ctx, "SELECT project_id FROM tasks WHERE id = $1", taskID).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRowContext(ctx, "SELECT project_id FROM tasks WHERE id = $1", dependsOnID).Scan(&otherProjectID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if projectID != otherProjectID {
+		return nil, ErrCrossProject
+	}
+
+	// Serialize graph changes per project so concurrent inserts cannot form a cycle
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", projectID.String()); err != nil {
+		return nil, fmt.Errorf("failed to lock task graph: %w", err)
+	}
+
+	edges, err := loadEdges(ctx, tx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if edges.WouldCycle(taskID, dependsOnID) {
+		return nil, ErrCycle
+	}
+
+	dep := &models.TaskDependency{TaskID: taskID, DependsOnID: dependsOnID}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO task_dependencies (task_id, depends_on_id)
+		VALUES ($1, $2)
+		ON CONFLICT (task_id, depends_on_id) DO UPDATE SET task_id = EXCLUDED.task_id
+		RETURNING created_at
+	`, taskID, dependsOnID).Scan(&dep.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add dependency: %w", err)
+	}
+
+	// Block the dependent task while the prerequisite is open, as Block does
+	var blocked bool
+	var assignedTo uuid.NullUUID
+	var from models.TaskStatus
+	err = tx.QueryRowContext(ctx, `
+		WITH old AS (
+			SELECT id, status FROM tasks
+			WHERE id = $1 AND status IN ('pending', 'in_progress')
+			FOR UPDATE
+		)
+		UPDATE tasks t SET status = 'blocked', lease_expires_at = NULL, updated_at = NOW()
+		FROM old
+		WHERE t.id = old.id AND EXISTS (`+openPrerequisite+`)
+		RETURNING TRUE, t.assigned_to, old.status
+	`, taskID).Scan(&blocked, &assignedTo, &from)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to block task: %w", err)
+	}
+	if blocked {
+		err = history.Record(ctx, tx, history.ActorFromContext(ctx), statusEvent(taskID, projectID, assignedTo, models.EventTaskBlocked, from, models.StatusBlocked))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if blocked {
+		s.BroadcastTasks(ctx, []uuid.UUID{taskID})
+	}
+	return dep, nil
+}
+
+// RemoveDependency deletes an edge and unblocks the task if nothing else holds it
+func (s *Service) RemoveDependency(ctx context.Context, taskID, dependsOnID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM task_dependencies WHERE task_id = $1 AND depends_on_id = $2", taskID, dependsOnID)
+	if err != nil {
+		return fmt.Errorf("failed to remove dependency: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrDependencyNotFound
+	}
+
+	return s.Unblock(ctx, []uuid.UUID{taskID})
+}
+
+// TaskChanged must be called after a task's status changes. When the task is
+// finished, dependents whose prerequisites are now all done are unblocked;
+// when it leaves done, dependents that are waiting or running are blocked again.
+func (s *Service) TaskChanged(ctx context.Context, taskID uuid.UUID) error {
+	dependents, err := s.Dependents(ctx, taskID)
+	if err != nil || len(dependents) == 0 {
+		return err
+	}
+	if err := s.Unblock(ctx, dependents); err != nil {
+		return err
+	}
+	return s.Block(ctx, dependents)
+}
+
+// Dependents returns the IDs of tasks that wait on taskID
+func (s *Service) Dependents(ctx context.Context, taskID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT task_id FROM task_dependencies WHERE depends_on_id = $1", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dependents: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Unblock moves blocked tasks back to pending once none of their
+// prerequisites are open, broadcasting a task_update for each.
+func (s *Service) Unblock(ctx context.Context, taskIDs []uuid.UUID) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE tasks t SET status = 'pending', updated_at = NOW()
+		WHERE t.id = ANY($1::uuid[]) AND t.status = 'blocked' AND NOT EXISTS (`+openPrerequisite+`)
+		RETURNING t.id, t.project_id, t.assigned_to
+	`, pq.Array(uuidStrings(taskIDs)))
+	if err != nil {
+		return fmt.Errorf("failed to unblock tasks: %w", err)
+	}
+
+	var unblocked []uuid.UUID
+	var events []models.TaskEvent
+	for rows.Next() {
+		var id, projectID uuid.UUID
+		var assignedTo uuid.NullUUID
+		if err := rows.Scan(&id, &projectID, &assignedTo); err != nil {
+			rows.Close()
+			return err
+		}
+		unblocked = append(unblocked, id)
+		events = append(events, statusEvent(id, projectID, assignedTo, models.EventTaskUnblocked, models.StatusBlocked, models.StatusPending))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Unblocking follows from other changes, so it is attributed to the system
+	for _, e := range events {
+		if err := history.Record(ctx, tx, history.Actor{}, e); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.BroadcastTasks(ctx, unblocked)
+	return nil
+}
+
+// Block moves pending and in-progress tasks to blocked while any of their
+// prerequisites is open, dropping their leases and broadcasting a task_update
+// for each.
+func (s *Service) Block(ctx context.Context, taskIDs []uuid.UUID) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH old AS (
+			SELECT id, status FROM tasks
+			WHERE id = ANY($1::uuid[]) AND status IN ('pending', 'in_progress')
+			FOR UPDATE
+		)
+		UPDATE tasks t SET status = 'blocked', lease_expires_at = NULL, updated_at = NOW()
+		FROM old
+		WHERE t.id = old.id AND EXISTS (`+openPrerequisite+`)
+		RETURNING t.id, t.project_id, t.assigned_to, old.status
+	`, pq.Array(uuidStrings(taskIDs)))
+	if err != nil {
+		return fmt.Errorf("failed to block tasks: %w", err)
+	}
+
+	var blocked []uuid.UUID
+	var events []models.TaskEvent
+	for rows.Next() {
+		var id, projectID uuid.UUID
+		var assignedTo uuid.NullUUID
+		var from models.TaskStatus
+		if err := rows.Scan(&id, &projectID, &assignedTo, &from); err != nil {
+			rows.Close()
+			return err
+		}
+		blocked = append(blocked, id)
+		events = append(events, statusEvent(id, projectID, assignedTo, models.EventTaskBlocked, from, models.StatusBlocked))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Like unblocking, blocking follows from other changes
+	for _, e := range events {
+		if err := history.Record(ctx, tx, history.Actor{}, e); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.BroadcastTasks(ctx, blocked)
+	return nil
+}
+
+// OpenPrerequisites returns the prerequisites of taskID that are not done yet
+func (s *Service) OpenPrerequisites(ctx context.Context, taskID uuid.UUID) ([]models.TaskGraphNode, error) {
+	return s.queryNodes(ctx, `
+		SELECT p.id, p.title, p.status, p.assigned_to
+		FROM task_dependencies d
+		JOIN tasks p ON p.id = d.depends_on_id
+		WHERE d.task_id = $1 AND p.status <> 'done'
+		ORDER BY p.created_at
+	`, taskID)
+}
+
+// Neighbours returns the tasks taskID depends on and the tasks that depend on it
+func (s *Service) Neighbours(ctx context.Context, taskID uuid.UUID) (dependsOn, dependents []models.TaskGraphNode, err error) {
+	dependsOn, err = s.queryNodes(ctx, `
+		SELECT p.id, p.title, p.status, p.assigned_to
+		FROM task_dependencies d
+		JOIN tasks p ON p.id = d.depends_on_id
+		WHERE d.task_id = $1
+		ORDER BY p.created_at
+	`, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dependents, err = s.queryNodes(ctx, `
+		SELECT t.id, t.title, t.status, t.assigned_to
+		FROM task_dependencies d
+		JOIN tasks t ON t.id = d.task_id
+		WHERE d.depends_on_id = $1
+		ORDER BY t.created_at
+	`, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dependsOn, dependents, nil
+}
+
+// ProjectGraph returns every task in a project with the dependency edges between them
+func (s *Service) ProjectGraph(ctx context.Context, projectID uuid.UUID) (*models.TaskGraph, error) {
+	nodes, err := s.queryNodes(ctx, `
+		SELECT id, title, status, assigned_to
+		FROM tasks
+		WHERE project_id = $1
+		ORDER BY created_at
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT d.task_id, d.depends_on_id, d.created_at
+		FROM task_dependencies d
+		JOIN tasks t ON t.id = d.task_id
+		WHERE t.project_id = $1
+		ORDER BY d.created_at
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	graph := &models.TaskGraph{ProjectID: projectID, Nodes: nodes, Edges: []models.TaskDependency{}}
+	for rows.Next() {
+		var d models.TaskDependency
+		if err := rows.Scan(&d.TaskID, &d.DependsOnID, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		graph.Edges = append(graph.Edges, d)
+	}
+	return graph, rows.Err()
+}
+
+func (s *Service) queryNodes(ctx context.Context, query string, args ...interface{}) ([]models.TaskGraphNode, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks: %w", err)
+	}
+	defer rows.Close()
+
+	nodes := []models.TaskGraphNode{}
+	for rows.Next() {
+		var n models.TaskGraphNode
+		var assignedTo uuid.NullUUID
+		if err := rows.Scan(&n.ID, &n.Title, &n.Status, &assignedTo); err != nil {
+			return nil, err
+		}
+		if assignedTo.Valid {
+			n.AssignedTo = &assignedTo.UUID
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// BroadcastTasks sends a task_update with the current state of each task
+func (s *Service) BroadcastTasks(ctx context.Context, taskIDs []uuid.UUID) {
+	if s.hub == nil {
+		return
+	}
+
+	for _, id := range taskIDs {
+		var t models.Task
+		var assignedTo uuid.NullUUID
+		var output sql.NullString
+		err := s.db.QueryRowContext(ctx, `
+			SELECT id, project_id, title, description, status, priority, created_by, assigned_to, output, parent_id, due_at, sla_seconds, started_at, overdue_at, created_at, updated_at
+			FROM tasks
+			WHERE id = $1
+		`, id).Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &t.CreatedBy, &assignedTo, &output, &t.ParentID, &t.DueAt, &t.SLA, &t.StartedAt, &t.OverdueAt, &t.CreatedAt, &t.UpdatedAt)
+		if err != nil {
+			log.Printf("taskgraph: failed to load task %s for broadcast: %v", id, err)
+			continue
+		}
+		if assignedTo.Valid {
+			t.AssignedTo = &assignedTo.UUID
+		}
+		t.Output = output.String
+
+		s.hub.BroadcastToProject(t.ProjectID, "task_update", t)
+	}
+}
+
+func loadEdges(ctx context.Context, tx *sql.Tx, projectID uuid.UUID) (Edges, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT d.task_id, d.depends_on_id
+		FROM task_dependencies d
+		JOIN tasks t ON t.id = d.task_id
+		WHERE t.project_id = $1
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	edges := Edges{}
+	for rows.Next() {
+		var from, to uuid.UUID
+		if err := rows.Scan(&from, &to); err != nil {
+			return nil, err
+		}
+		edges[from] = append(edges[from], to)
+	}
+	return edges, rows.Err()
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
+
+// statusEvent describes a status change the graph made to a task
+func statusEvent(taskID, projectID uuid.UUID, assignedTo uuid.NullUUID, event models.TaskEventType, from, to models.TaskStatus) models.TaskEvent {
+	e := models.TaskEvent{TaskID: taskID, ProjectID: projectID, Event: event, OldStatus: from, NewStatus: to}
+	if assignedTo.Valid {
+		e.OldAssignedTo = &assignedTo.UUID
+		e.NewAssignedTo = &assignedTo.UUID
+	}
+	return e
+}
@@ -0,0 +1,249 @@
+// Package taskstate applies task status changes through the state machine
+// defined in models, so every write path rejects the same illegal transitions,
+// and records each change in the task's history.
+package taskstate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techbuzzz/agent-shaker/internal/database"
+	"github.com/techbuzzz/agent-shaker/internal/history"
+	"github.com/techbuzzz/agent-shaker/internal/models"
+)
+
+var (
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrInvalidStatus = errors.New("invalid status value")
+	ErrBlocked       = errors.New("task is blocked by unfinished dependencies")
+	ErrAgentNotFound = errors.New("agent not found in the task's project")
+)
+
+// Change describes a status update. Output replaces the task's output when
+// non-nil; Event defaults to a plain status change.
+type Change struct {
+	Status models.TaskStatus
+	Output *string
+	Event  models.TaskEventType
+	Actor  history.Actor
+}
+
+// snapshot is the part of a task a change is checked and recorded against
+type snapshot struct {
+	projectID  uuid.UUID
+	status     models.TaskStatus
+	assignedTo uuid.NullUUID
+}
+
+// lock loads a task's current state and holds its row until tx ends
+func lock(ctx context.Context, tx *sql.Tx, taskID uuid.UUID) (snapshot, error) {
+	var s snapshot
+	err := tx.QueryRowContext(ctx, "SELECT project_id, status, assigned_to FROM tasks WHERE id = $1 FOR UPDATE", taskID).
+		Scan(&s.projectID, &s.status, &s.assignedTo)
+	if err == sql.ErrNoRows {
+		return s, ErrTaskNotFound
+	}
+	return s, err
+}
+
+// needsPrerequisites reports whether a task may only move to status once all
+// of its prerequisites are done
+func needsPrerequisites(status models.TaskStatus) bool {
+	return status == models.StatusPending || status == models.StatusInProgress || status == models.StatusDone
+}
+
+// CheckPrerequisites returns ErrBlocked when any prerequisite of taskID is not
+// done. The prerequisite rows stay locked until tx ends, so none of them can
+// reopen before the caller's change commits.
+func CheckPrerequisites(ctx context.Context, tx *sql.Tx, taskID uuid.UUID) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT p.status
+		FROM task_dependencies d
+		JOIN tasks p ON p.id = d.depends_on_id
+		WHERE d.task_id = $1
+		ORDER BY p.id
+		FOR UPDATE OF p
+	`, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to check dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	open := false
+	for rows.Next() {
+		var status models.TaskStatus
+		if err := rows.Scan(&status); err != nil {
+			return err
+		}
+		if status != models.StatusDone {
+			open = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if open {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// Update moves a task to a new status. The task row is locked while the
+// transition is checked so concurrent writers cannot slip an illegal change
+// through; a rejected change returns *models.TransitionError. A task cannot
+// become pending, in progress or done while a prerequisite is open
+// (ErrBlocked). Leases only apply while a task is in progress and are cleared
+// otherwise.
+func Update(ctx context.Context, db *database.DB, taskID uuid.UUID, c Change) error {
+	if !c.Status.IsValid() {
+		return ErrInvalidStatus
+	}
+	if c.Event == "" {
+		c.Event = models.EventTaskStatus
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := lock(ctx, tx, taskID)
+	if err != nil {
+		return err
+	}
+	if !current.status.CanTransitionTo(c.Status) {
+		return &models.TransitionError{From: current.status, To: c.Status}
+	}
+	if needsPrerequisites(c.Status) {
+		if err := CheckPrerequisites(ctx, tx, taskID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE tasks
+		SET status = $1, output = COALESCE($2, output), updated_at = NOW(),
+		    lease_expires_at = CASE WHEN $1 = 'in_progress' THEN lease_expires_at ELSE NULL END,
+		    started_at = CASE WHEN $1 = 'in_progress' AND status <> 'in_progress' THEN NOW() ELSE started_at END,
+		    overdue_at = CASE WHEN $1 = 'in_progress' AND status <> 'in_progress' AND (due_at IS NULL OR due_at >= NOW()) THEN NULL ELSE overdue_at END
+		WHERE id = $3
+	`, string(c.Status), c.Output, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+
+	assignee := nullablePtr(current.assignedTo)
+	err = history.Record(ctx, tx, c.Actor, models.TaskEvent{
+		TaskID:        taskID,
+		ProjectID:     current.projectID,
+		Event:         c.Event,
+		OldStatus:     current.status,
+		NewStatus:     c.Status,
+		OldAssignedTo: assignee,
+		NewAssignedTo: assignee,
+		Output:        c.Output,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Reassign hands a task to another agent and records the move
+func Reassign(ctx context.Context, db *database.DB, taskID, agentID uuid.UUID, actor history.Actor) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := lock(ctx, tx, taskID)
+	if err != nil {
+		return err
+	}
+	if err := CheckAssignee(ctx, tx, agentID, current.projectID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE tasks SET assigned_to = $1, updated_at = NOW() WHERE id = $2", agentID, taskID); err != nil {
+		return fmt.Errorf("failed to reassign task: %w", err)
+	}
+
+	err = history.Record(ctx, tx, actor, models.TaskEvent{
+		TaskID:        taskID,
+		ProjectID:     current.projectID,
+		Event:         models.EventTaskReassigned,
+		OldStatus:     current.status,
+		NewStatus:     current.status,
+		OldAssignedTo: nullablePtr(current.assignedTo),
+		NewAssignedTo: &agentID,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CheckAssignee verifies that an agent belongs to the project, since tasks
+// can only be assigned to agents of their own project
+func CheckAssignee(ctx context.Context, tx *sql.Tx, agentID, projectID uuid.UUID) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM agents WHERE id = $1 AND project_id = $2)", agentID, projectID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify agent: %w", err)
+	}
+	if !exists {
+		return ErrAgentNotFound
+	}
+	return nil
+}
+
+// Schedule replaces a task's due date and SLA, clearing any overdue mark so
+// the task is checked again against the new deadline
+func Schedule(ctx context.Context, db *database.DB, taskID uuid.UUID, dueAt *time.Time, sla *models.Duration, actor history.Actor) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := lock(ctx, tx, taskID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE tasks SET due_at = $1, sla_seconds = $2, overdue_at = NULL, updated_at = NOW()
+		WHERE id = $3
+	`, dueAt, sla, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to schedule task: %w", err)
+	}
+
+	assignee := nullablePtr(current.assignedTo)
+	err = history.Record(ctx, tx, actor, models.TaskEvent{
+		TaskID:        taskID,
+		ProjectID:     current.projectID,
+		Event:         models.EventTaskRescheduled,
+		OldStatus:     current.status,
+		NewStatus:     current.status,
+		OldAssignedTo: assignee,
+		NewAssignedTo: assignee,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullablePtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
@@ -2,9 +2,13 @@ package validator
 
 import (
 	"errors"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/techbuzzz/agent-shaker/internal/models"
+	"github.com/techbuzzz/agent-shaker/internal/routing"
 )
 
 var (
@@ -16,8 +20,35 @@ var (
 	ErrInvalidStatus    = errors.New("invalid status value")
 	ErrInvalidProjectID = errors.New("project_id is required")
 	ErrInvalidAgentID   = errors.New("agent_id is required")
+	ErrInvalidRole      = errors.New("role must be maintainer, agent, or observer")
+	ErrInvalidMember    = errors.New("exactly one of user_id or agent_id is required")
+	ErrEmptyComment     = errors.New("comment body cannot be empty")
+	ErrCommentTooLong   = errors.New("comment body cannot exceed 20000 characters")
+	ErrNoSubtasks       = errors.New("at least one subtask is required")
+	ErrTooManySubtasks  = errors.New("cannot create more than 100 subtasks at once")
+	ErrInvalidSLA       = errors.New("sla must be at least one second")
+	ErrInvalidStrategy  = errors.New("unknown assign strategy")
+	ErrEmptyRole        = errors.New("role name cannot be empty")
+	ErrRoleTooLong      = errors.New("role name cannot exceed 100 characters")
+	ErrTooManyTags      = errors.New("an agent cannot have more than 50 capabilities")
+	ErrTagTooLong       = errors.New("capabilities cannot exceed 50 characters")
+	ErrInvalidPrompt    = errors.New("prompt name must be 1-100 lowercase letters, digits, '_' or '-'")
+	ErrReservedPrompt   = errors.New("prompt name is taken by a built-in prompt")
+	ErrEmptyTemplate    = errors.New("prompt template cannot be empty")
+	ErrTemplateTooLong  = errors.New("prompt template cannot exceed 20000 characters")
+	ErrInvalidArgument  = errors.New("prompt argument names must be unique letters, digits or '_'")
+	ErrInvalidToolName  = errors.New("tool name must be 1-128 letters, digits, '_', '-' or '.'")
+	ErrMissingEnabled   = errors.New("enabled is required")
+	ErrMissingApproved  = errors.New("approved is required")
+	ErrReasonTooLong    = errors.New("reason cannot exceed 2000 characters")
 )
 
+// MaxSubtasksPerRequest caps how many subtasks a single request may create
+const MaxSubtasksPerRequest = 100
+
+// MaxCapabilities caps how many capability tags an agent may have
+const MaxCapabilities = 50
+
 // ValidateCreateProjectRequest validates project creation request
 func ValidateCreateProjectRequest(req *models.CreateProjectRequest) error {
 	if strings.TrimSpace(req.Name) == "" {
@@ -26,6 +57,19 @@ func ValidateCreateProjectRequest(req *models.CreateProjectRequest) error {
 	if len(req.Name) > 255 {
 		return ErrNameTooLong
 	}
+	if req.AssignStrategy != "" {
+		if _, ok := routing.Lookup(req.AssignStrategy); !ok {
+			return ErrInvalidStrategy
+		}
+	}
+	return nil
+}
+
+// ValidateUpdateAssignStrategyRequest checks that the strategy is registered
+func ValidateUpdateAssignStrategyRequest(req *models.UpdateAssignStrategyRequest) error {
+	if _, ok := routing.Lookup(req.Strategy); !ok {
+		return ErrInvalidStrategy
+	}
 	return nil
 }
 
@@ -40,6 +84,88 @@ func ValidateCreateAgentRequest(req *models.CreateAgentRequest) error {
 	if req.ProjectID.String() == "00000000-0000-0000-0000-000000000000" {
 		return ErrInvalidProjectID
 	}
+	if len(req.Role) > 100 {
+		return ErrRoleTooLong
+	}
+	return ValidateCapabilities(req.Capabilities)
+}
+
+// ValidateCreateAgentRoleRequest validates a new project role
+func ValidateCreateAgentRoleRequest(req *models.CreateAgentRoleRequest) error {
+	name := models.NormalizeAgentRole(req.Name)
+	if name == "" {
+		return ErrEmptyRole
+	}
+	if len(name) > 100 {
+		return ErrRoleTooLong
+	}
+	return nil
+}
+
+var (
+	promptName   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,99}$`)
+	argumentName = regexp.MustCompile(`^[A-Za-z0-9_]{1,100}$`)
+	toolName     = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+)
+
+// ValidateCreatePromptTemplateRequest validates a project prompt template
+func ValidateCreatePromptTemplateRequest(req *models.CreatePromptTemplateRequest) error {
+	if !promptName.MatchString(req.Name) {
+		return ErrInvalidPrompt
+	}
+	for _, name := range models.BuiltinPrompts {
+		if req.Name == name {
+			return ErrReservedPrompt
+		}
+	}
+	if strings.TrimSpace(req.Template) == "" {
+		return ErrEmptyTemplate
+	}
+	if len(req.Template) > 20000 {
+		return ErrTemplateTooLong
+	}
+	seen := make(map[string]bool, len(req.Arguments))
+	for _, arg := range req.Arguments {
+		if !argumentName.MatchString(arg.Name) || seen[arg.Name] {
+			return ErrInvalidArgument
+		}
+		seen[arg.Name] = true
+	}
+	return nil
+}
+
+// ValidateUpdateToolSettingRequest validates an MCP tool switch
+func ValidateUpdateToolSettingRequest(req *models.UpdateToolSettingRequest) error {
+	if !toolName.MatchString(req.ToolName) {
+		return ErrInvalidToolName
+	}
+	if req.Enabled == nil {
+		return ErrMissingEnabled
+	}
+	return nil
+}
+
+// ValidateDecideToolApprovalRequest validates a decision on a held MCP tool call
+func ValidateDecideToolApprovalRequest(req *models.DecideToolApprovalRequest) error {
+	if req.Approved == nil {
+		return ErrMissingApproved
+	}
+	if len(req.Reason) > 2000 {
+		return ErrReasonTooLong
+	}
+	return nil
+}
+
+// ValidateCapabilities validates an agent's capability tags
+func ValidateCapabilities(tags []string) error {
+	if len(tags) > MaxCapabilities {
+		return ErrTooManyTags
+	}
+	for _, tag := range tags {
+		if len(strings.TrimSpace(tag)) > 50 {
+			return ErrTagTooLong
+		}
+	}
 	return nil
 }
 
@@ -60,19 +186,52 @@ func ValidateCreateTaskRequest(req *models.CreateTaskRequest) error {
 	if req.Priority != "" && req.Priority != "low" && req.Priority != "medium" && req.Priority != "high" {
 		return ErrInvalidPriority
 	}
+	return validateSLA(req.SLA)
+}
+
+// ValidateScheduleTaskRequest validates a task due date/SLA change
+func ValidateScheduleTaskRequest(req *models.ScheduleTaskRequest) error {
+	return validateSLA(req.SLA)
+}
+
+func validateSLA(sla *models.Duration) error {
+	if sla != nil && sla.Std() < time.Second {
+		return ErrInvalidSLA
+	}
+	return nil
+}
+
+// ValidateCreateSubtasksRequest validates a batch of subtasks
+func ValidateCreateSubtasksRequest(req *models.CreateSubtasksRequest) error {
+	if req.CreatedBy == uuid.Nil {
+		return ErrInvalidAgentID
+	}
+	if len(req.Subtasks) == 0 {
+		return ErrNoSubtasks
+	}
+	if len(req.Subtasks) > MaxSubtasksPerRequest {
+		return ErrTooManySubtasks
+	}
+	for _, st := range req.Subtasks {
+		if strings.TrimSpace(st.Title) == "" {
+			return ErrEmptyTitle
+		}
+		if len(st.Title) > 255 {
+			return ErrTitleTooLong
+		}
+		if st.Priority != "" && st.Priority != "low" && st.Priority != "medium" && st.Priority != "high" {
+			return ErrInvalidPriority
+		}
+		if err := validateSLA(st.SLA); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // ValidateUpdateTaskRequest validates task update request
 func ValidateUpdateTaskRequest(req *models.UpdateTaskRequest) error {
-	validStatuses := map[string]bool{
-		"pending":     true,
-		"in_progress": true,
-		"blocked":     true,
-		"done":        true,
-		"cancelled":   true,
-	}
-	if !validStatuses[string(req.Status)] {
+	if !req.Status.IsValid() {
 		return ErrInvalidStatus
 	}
 	return nil
@@ -118,3 +277,50 @@ func ValidateUpdateContextRequest(req *models.UpdateContextRequest) error {
 	}
 	return nil
 }
+
+// ValidateCreateUserRequest validates user creation request
+func ValidateCreateUserRequest(req *models.CreateUserRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return ErrEmptyName
+	}
+	if len(req.Name) > 255 {
+		return ErrNameTooLong
+	}
+	return nil
+}
+
+// ValidateSetProjectMemberRequest validates a project role assignment
+func ValidateSetProjectMemberRequest(req *models.SetProjectMemberRequest) error {
+	if (req.UserID == nil) == (req.AgentID == nil) {
+		return ErrInvalidMember
+	}
+	switch req.Role {
+	case "maintainer", "agent", "observer":
+	default:
+		return ErrInvalidRole
+	}
+	return nil
+}
+
+// ValidateCreateCommentRequest validates task comment creation request
+func ValidateCreateCommentRequest(req *models.CreateCommentRequest) error {
+	if req.AgentID == uuid.Nil {
+		return ErrInvalidAgentID
+	}
+	return validateCommentBody(req.Body)
+}
+
+// ValidateUpdateCommentRequest validates task comment edit request
+func ValidateUpdateCommentRequest(req *models.UpdateCommentRequest) error {
+	return validateCommentBody(req.Body)
+}
+
+func validateCommentBody(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return ErrEmptyComment
+	}
+	if len(body) > 20000 {
+		return ErrCommentTooLong
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package validator
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/techbuzzz/agent-shaker/internal/models"
@@ -162,6 +164,16 @@ func TestValidateUpdateTaskRequest(t *testing.T) {
 			req:     models.UpdateTaskRequest{Status: "done"},
 			wantErr: false,
 		},
+		{
+			name:    "valid status - failed",
+			req:     models.UpdateTaskRequest{Status: "failed"},
+			wantErr: false,
+		},
+		{
+			name:    "valid status - cancelled",
+			req:     models.UpdateTaskRequest{Status: "cancelled"},
+			wantErr: false,
+		},
 		{
 			name:    "invalid status",
 			req:     models.UpdateTaskRequest{Status: "completed"},
@@ -216,3 +228,267 @@ func TestValidateUpdateAgentStatusRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSetProjectMemberRequest(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name    string
+		req     models.SetProjectMemberRequest
+		wantErr bool
+	}{
+		{
+			name:    "valid user maintainer",
+			req:     models.SetProjectMemberRequest{UserID: &id, Role: "maintainer"},
+			wantErr: false,
+		},
+		{
+			name:    "valid agent observer",
+			req:     models.SetProjectMemberRequest{AgentID: &id, Role: "observer"},
+			wantErr: false,
+		},
+		{
+			name:    "both user and agent",
+			req:     models.SetProjectMemberRequest{UserID: &id, AgentID: &id, Role: "agent"},
+			wantErr: true,
+		},
+		{
+			name:    "no member",
+			req:     models.SetProjectMemberRequest{Role: "agent"},
+			wantErr: true,
+		},
+		{
+			name:    "admin is not a project role",
+			req:     models.SetProjectMemberRequest{UserID: &id, Role: "admin"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSetProjectMemberRequest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSetProjectMemberRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCreateCommentRequest(t *testing.T) {
+	agentID := uuid.New()
+
+	tests := []struct {
+		name    string
+		req     models.CreateCommentRequest
+		wantErr bool
+	}{
+		{
+			name:    "valid comment",
+			req:     models.CreateCommentRequest{AgentID: agentID, Body: "Looks good, **merging**."},
+			wantErr: false,
+		},
+		{
+			name:    "missing agent",
+			req:     models.CreateCommentRequest{Body: "Hello"},
+			wantErr: true,
+		},
+		{
+			name:    "blank body",
+			req:     models.CreateCommentRequest{AgentID: agentID, Body: "  \n"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCreateCommentRequest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreateCommentRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCreateSubtasksRequest(t *testing.T) {
+	agentID := uuid.New()
+	shortSLA := models.Duration(time.Millisecond)
+	tooMany := make([]models.SubtaskRequest, MaxSubtasksPerRequest+1)
+	for i := range tooMany {
+		tooMany[i].Title = "Step"
+	}
+
+	tests := []struct {
+		name    string
+		req     models.CreateSubtasksRequest
+		wantErr bool
+	}{
+		{
+			name: "valid batch",
+			req: models.CreateSubtasksRequest{CreatedBy: agentID, Subtasks: []models.SubtaskRequest{
+				{Title: "Write schema"},
+				{Title: "Write handler", Priority: "high"},
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "missing creator",
+			req:     models.CreateSubtasksRequest{Subtasks: []models.SubtaskRequest{{Title: "Step"}}},
+			wantErr: true,
+		},
+		{
+			name:    "empty batch",
+			req:     models.CreateSubtasksRequest{CreatedBy: agentID},
+			wantErr: true,
+		},
+		{
+			name:    "too many subtasks",
+			req:     models.CreateSubtasksRequest{CreatedBy: agentID, Subtasks: tooMany},
+			wantErr: true,
+		},
+		{
+			name:    "blank title",
+			req:     models.CreateSubtasksRequest{CreatedBy: agentID, Subtasks: []models.SubtaskRequest{{Title: " "}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid priority",
+			req:     models.CreateSubtasksRequest{CreatedBy: agentID, Subtasks: []models.SubtaskRequest{{Title: "Step", Priority: "urgent"}}},
+			wantErr: true,
+		},
+		{
+			name:    "sub-second sla",
+			req:     models.CreateSubtasksRequest{CreatedBy: agentID, Subtasks: []models.SubtaskRequest{{Title: "Step", SLA: &shortSLA}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCreateSubtasksRequest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreateSubtasksRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCapabilities(t *testing.T) {
+	tooMany := make([]string, MaxCapabilities+1)
+	for i := range tooMany {
+		tooMany[i] = "tag"
+	}
+
+	tests := []struct {
+		name    string
+		tags    []string
+		wantErr bool
+	}{
+		{name: "no tags", tags: nil, wantErr: false},
+		{name: "valid tags", tags: []string{"go", "postgres"}, wantErr: false},
+		{name: "too many tags", tags: tooMany, wantErr: true},
+		{name: "tag too long", tags: []string{strings.Repeat("a", 51)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCapabilities(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCapabilities() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCreateAgentRoleRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.CreateAgentRoleRequest
+		wantErr bool
+	}{
+		{name: "valid role", req: models.CreateAgentRoleRequest{Name: "security"}, wantErr: false},
+		{name: "blank name", req: models.CreateAgentRoleRequest{Name: "  "}, wantErr: true},
+		{name: "name too long", req: models.CreateAgentRoleRequest{Name: models.AgentRole(strings.Repeat("r", 101))}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCreateAgentRoleRequest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreateAgentRoleRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCreatePromptTemplateRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.CreatePromptTemplateRequest
+		wantErr bool
+	}{
+		{name: "valid template", req: models.CreatePromptTemplateRequest{Name: "review-pr", Template: "Review {{pr}}", Arguments: models.PromptArguments{{Name: "pr", Required: true}}}, wantErr: false},
+		{name: "uppercase name", req: models.CreatePromptTemplateRequest{Name: "Review", Template: "x"}, wantErr: true},
+		{name: "name too long", req: models.CreatePromptTemplateRequest{Name: strings.Repeat("p", 101), Template: "x"}, wantErr: true},
+		{name: "built-in name", req: models.CreatePromptTemplateRequest{Name: models.PromptStartMyDay, Template: "x"}, wantErr: true},
+		{name: "blank template", req: models.CreatePromptTemplateRequest{Name: "empty", Template: " "}, wantErr: true},
+		{name: "duplicate argument", req: models.CreatePromptTemplateRequest{Name: "dup", Template: "x", Arguments: models.PromptArguments{{Name: "a"}, {Name: "a"}}}, wantErr: true},
+		{name: "invalid argument", req: models.CreatePromptTemplateRequest{Name: "bad", Template: "x", Arguments: models.PromptArguments{{Name: "a b"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCreatePromptTemplateRequest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreatePromptTemplateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateUpdateToolSettingRequest(t *testing.T) {
+	enabled := false
+	tests := []struct {
+		name    string
+		req     models.UpdateToolSettingRequest
+		wantErr bool
+	}{
+		{name: "valid setting", req: models.UpdateToolSettingRequest{ToolName: "delegate_to_a2a_agent", Enabled: &enabled}, wantErr: false},
+		{name: "dotted name", req: models.UpdateToolSettingRequest{ToolName: "github.create-issue", Enabled: &enabled}, wantErr: false},
+		{name: "missing enabled", req: models.UpdateToolSettingRequest{ToolName: "heartbeat"}, wantErr: true},
+		{name: "empty name", req: models.UpdateToolSettingRequest{Enabled: &enabled}, wantErr: true},
+		{name: "name with space", req: models.UpdateToolSettingRequest{ToolName: "list tasks", Enabled: &enabled}, wantErr: true},
+		{name: "name too long", req: models.UpdateToolSettingRequest{ToolName: strings.Repeat("t", 129), Enabled: &enabled}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUpdateToolSettingRequest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdateToolSettingRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDecideToolApprovalRequest(t *testing.T) {
+	approved := true
+	tests := []struct {
+		name    string
+		req     models.DecideToolApprovalRequest
+		wantErr bool
+	}{
+		{name: "approval", req: models.DecideToolApprovalRequest{Approved: &approved}, wantErr: false},
+		{name: "with reason", req: models.DecideToolApprovalRequest{Approved: &approved, Reason: "checked with the team"}, wantErr: false},
+		{name: "missing approved", req: models.DecideToolApprovalRequest{Reason: "no"}, wantErr: true},
+		{name: "reason too long", req: models.DecideToolApprovalRequest{Approved: &approved, Reason: strings.Repeat("r", 2001)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDecideToolApprovalRequest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDecideToolApprovalRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -25,8 +25,15 @@ var upgrader = websocket.Upgrader{
 type Message struct {
 	Type    string      `json:"type"`
 	Payload interface{} `json:"payload"`
+	// projectID is the project named by the sender, which listeners receive
+	// even when the payload does not carry it
+	projectID uuid.UUID
 }
 
+// Listener observes every message the hub broadcasts. Listeners are called
+// from the hub's goroutine and must not block.
+type Listener func(projectID uuid.UUID, messageType string, payload interface{})
+
 type Client struct {
 	ID        string
 	ProjectID uuid.UUID
@@ -41,6 +48,7 @@ type Hub struct {
 	broadcast  chan *Message
 	register   chan *Client
 	unregister chan *Client
+	listeners  []Listener
 	mu         sync.RWMutex
 }
 
@@ -122,6 +130,14 @@ func (h *Hub) broadcastMessage(message *Message) {
 		}
 	}
 
+	for _, listen := range h.listeners {
+		listenerProject := message.projectID
+		if listenerProject == uuid.Nil {
+			listenerProject = projectID
+		}
+		listen(listenerProject, message.Type, message.Payload)
+	}
+
 	// Broadcast to all clients of the project
 	if projectClients, ok := h.projects[projectID]; ok {
 		for _, client := range projectClients {
@@ -138,12 +154,20 @@ func (h *Hub) broadcastMessage(message *Message) {
 
 func (h *Hub) BroadcastToProject(projectID uuid.UUID, messageType string, payload interface{}) {
 	message := &Message{
-		Type:    messageType,
-		Payload: payload,
+		Type:      messageType,
+		Payload:   payload,
+		projectID: projectID,
 	}
 	h.broadcast <- message
 }
 
+// AddListener registers l to observe every broadcast message
+func (h *Hub) AddListener(l Listener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.listeners = append(h.listeners, l)
+}
+
 // BroadcastTaskUpdate sends a task update to all connected clients
 func (h *Hub) BroadcastTaskUpdate(update *models.TaskUpdate) {
 	message := &Message{
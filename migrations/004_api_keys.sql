@@ -0,0 +1,17 @@
+-- Create api_keys table for per-agent authentication
+-- Only the SHA-256 hash of a key is stored; the plaintext is returned once on creation
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    name VARCHAR(255),
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+-- Create indexes for key lookups
+CREATE INDEX IF NOT EXISTS idx_api_keys_agent ON api_keys(agent_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_hash ON api_keys(key_hash);
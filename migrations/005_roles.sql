@@ -0,0 +1,33 @@
+-- Create users table for human operators
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) UNIQUE,
+    is_admin BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- API keys can now belong to either an agent or a user
+ALTER TABLE api_keys ALTER COLUMN agent_id DROP NOT NULL;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE api_keys ADD CONSTRAINT api_keys_owner_check CHECK ((agent_id IS NULL) <> (user_id IS NULL));
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+-- Create project_roles table for per-project role assignments
+-- Agents implicitly hold the 'agent' role in their own project unless overridden here
+CREATE TABLE IF NOT EXISTS project_roles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    agent_id UUID REFERENCES agents(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT project_roles_member_check CHECK ((user_id IS NULL) <> (agent_id IS NULL)),
+    CONSTRAINT project_roles_role_check CHECK (role IN ('maintainer', 'agent', 'observer')),
+    CONSTRAINT unique_project_user UNIQUE (project_id, user_id),
+    CONSTRAINT unique_project_agent UNIQUE (project_id, agent_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_roles_project ON project_roles(project_id);
+CREATE INDEX IF NOT EXISTS idx_project_roles_user ON project_roles(user_id);
+CREATE INDEX IF NOT EXISTS idx_project_roles_agent ON project_roles(agent_id);
@@ -0,0 +1,11 @@
+-- Create task_dependencies table
+-- A row means task_id cannot start until depends_on_id is done
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    depends_on_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, depends_on_id),
+    CONSTRAINT task_dependencies_self_check CHECK (task_id <> depends_on_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_depends_on ON task_dependencies(depends_on_id);
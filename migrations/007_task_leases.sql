@@ -0,0 +1,5 @@
+-- Track claim leases on tasks
+-- A claimed task must have its lease renewed (via heartbeats) or it is returned to pending
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_lease_expires ON tasks(lease_expires_at) WHERE lease_expires_at IS NOT NULL;
@@ -0,0 +1,16 @@
+-- Normalize task statuses to the canonical set
+-- Earlier code paths wrote 'completed'/'canceled'; anything unrecognised goes back to pending
+UPDATE tasks SET status = 'done' WHERE status = 'completed';
+UPDATE tasks SET status = 'cancelled' WHERE status = 'canceled';
+UPDATE tasks SET status = 'pending'
+WHERE status IS NULL
+   OR status NOT IN ('pending', 'in_progress', 'blocked', 'done', 'failed', 'cancelled');
+
+-- Leases only apply while a task is in progress
+UPDATE tasks SET lease_expires_at = NULL WHERE status <> 'in_progress' AND lease_expires_at IS NOT NULL;
+
+ALTER TABLE tasks ALTER COLUMN status SET NOT NULL;
+
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_status_check;
+ALTER TABLE tasks ADD CONSTRAINT tasks_status_check
+    CHECK (status IN ('pending', 'in_progress', 'blocked', 'done', 'failed', 'cancelled'));
@@ -0,0 +1,32 @@
+-- Create task_events table
+-- Append-only audit trail of task changes. There is deliberately no foreign key
+-- to tasks so the history of a task survives its deletion.
+CREATE TABLE IF NOT EXISTS task_events (
+    id UUID PRIMARY KEY,
+    task_id UUID NOT NULL,
+    project_id UUID NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    actor_agent_id UUID,
+    actor_user_id UUID,
+    old_status VARCHAR(50),
+    new_status VARCHAR(50),
+    old_assigned_to UUID,
+    new_assigned_to UUID,
+    output TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_events_task ON task_events(task_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_task_events_project ON task_events(project_id, created_at);
+
+-- Reject updates and deletes so the trail cannot be rewritten
+CREATE OR REPLACE FUNCTION task_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'task_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS task_events_append_only ON task_events;
+CREATE TRIGGER task_events_append_only
+    BEFORE UPDATE OR DELETE ON task_events
+    FOR EACH ROW EXECUTE FUNCTION task_events_append_only();
@@ -0,0 +1,6 @@
+-- Add task hierarchy
+-- parent_id links a subtask to the task it breaks down; deletes are handled by the
+-- application so children can be cascaded, detached or protected explicitly
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES tasks(id);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_parent ON tasks(parent_id) WHERE parent_id IS NOT NULL;
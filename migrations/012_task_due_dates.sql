@@ -0,0 +1,13 @@
+-- Add due dates and SLAs to tasks
+-- sla_seconds limits how long a task may stay in progress, measured from started_at;
+-- overdue_at is set by the scheduler once a task misses its due date or SLA
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sla_seconds INTEGER CHECK (sla_seconds > 0);
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS overdue_at TIMESTAMP;
+
+-- Tasks already in progress start their SLA clock from their last update
+UPDATE tasks SET started_at = updated_at WHERE status = 'in_progress' AND started_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_due_at ON tasks(due_at) WHERE due_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_tasks_overdue ON tasks(project_id) WHERE overdue_at IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Add automatic task routing
+-- Each project picks the strategy used to auto-assign tasks; agents advertise
+-- capability tags that tasks can require, and last_assigned_at drives round-robin
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS assign_strategy VARCHAR(50) NOT NULL DEFAULT 'least_loaded';
+
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS capabilities TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS last_assigned_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_assigned_open ON tasks(assigned_to)
+    WHERE status IN ('pending', 'in_progress', 'blocked');
@@ -0,0 +1,30 @@
+-- Make agent roles a per-project vocabulary
+-- Every project starts with the default roles; roles already used by agents are kept
+CREATE TABLE IF NOT EXISTS agent_roles (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, name)
+);
+
+INSERT INTO agent_roles (project_id, name, description)
+SELECT p.id, r.name, r.description
+FROM projects p
+CROSS JOIN (VALUES
+    ('backend', 'Server-side development'),
+    ('frontend', 'User interface development'),
+    ('qa', 'Testing and quality assurance'),
+    ('devops', 'Infrastructure, CI/CD and operations'),
+    ('docs', 'Documentation'),
+    ('reviewer', 'Code review')
+) AS r(name, description)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO agent_roles (project_id, name)
+SELECT DISTINCT project_id, role FROM agents
+WHERE role IS NOT NULL AND role <> ''
+ON CONFLICT DO NOTHING;
+
+-- Capability filters use array containment
+CREATE INDEX IF NOT EXISTS idx_agents_capabilities ON agents USING GIN (capabilities);
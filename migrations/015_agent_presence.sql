@@ -0,0 +1,18 @@
+-- Track agent presence transitions
+-- The presence tracker derives active/idle/offline from heartbeat age; every
+-- change it (or a heartbeat or manual update) makes is kept for auditing
+CREATE TABLE IF NOT EXISTS agent_presence_events (
+    id UUID PRIMARY KEY,
+    agent_id UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    old_status VARCHAR(50) NOT NULL,
+    new_status VARCHAR(50) NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    last_seen TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_presence_events_agent ON agent_presence_events(agent_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_agents_last_seen ON agents(last_seen)
+    WHERE status IS DISTINCT FROM 'offline';
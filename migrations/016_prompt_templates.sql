@@ -0,0 +1,13 @@
+-- Per-project MCP prompt templates
+-- Served by prompts/list and prompts/get next to the built-in prompts
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    arguments JSONB NOT NULL DEFAULT '[]',
+    template TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (project_id, name)
+);
@@ -0,0 +1,14 @@
+-- Per-project and per-agent MCP tool switches
+-- Tools without a row are enabled; an agent's row overrides its project's
+CREATE TABLE IF NOT EXISTS mcp_tool_settings (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    agent_id UUID REFERENCES agents(id) ON DELETE CASCADE,
+    tool_name VARCHAR(128) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mcp_tool_settings_scope
+    ON mcp_tool_settings (project_id, tool_name, COALESCE(agent_id, '00000000-0000-0000-0000-000000000000'::uuid));
+CREATE INDEX IF NOT EXISTS idx_mcp_tool_settings_agent ON mcp_tool_settings(agent_id) WHERE agent_id IS NOT NULL;
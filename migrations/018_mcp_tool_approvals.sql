@@ -0,0 +1,19 @@
+-- MCP tool calls held until a human approves them
+-- Rows are written by the MCP server when a client cannot be asked directly
+-- (no elicitation support) and decided through the REST API
+CREATE TABLE IF NOT EXISTS mcp_tool_approvals (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    agent_id UUID REFERENCES agents(id) ON DELETE SET NULL,
+    tool_name VARCHAR(128) NOT NULL,
+    arguments JSONB NOT NULL DEFAULT '{}',
+    summary TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reason TEXT,
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    requested_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP,
+    CONSTRAINT mcp_tool_approvals_status_check CHECK (status IN ('pending', 'approved', 'rejected', 'expired'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_tool_approvals_project_status ON mcp_tool_approvals(project_id, status);
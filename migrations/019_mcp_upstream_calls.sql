@@ -0,0 +1,16 @@
+-- Audit log of tool calls proxied to downstream MCP servers
+CREATE TABLE IF NOT EXISTS mcp_upstream_calls (
+    id UUID PRIMARY KEY,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    agent_id UUID REFERENCES agents(id) ON DELETE SET NULL,
+    server VARCHAR(64) NOT NULL,
+    tool_name VARCHAR(255) NOT NULL,
+    arguments JSONB NOT NULL DEFAULT '{}',
+    is_error BOOLEAN NOT NULL DEFAULT FALSE,
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+    called_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_upstream_calls_project ON mcp_upstream_calls(project_id, called_at DESC);
+CREATE INDEX IF NOT EXISTS idx_mcp_upstream_calls_agent ON mcp_upstream_calls(agent_id) WHERE agent_id IS NOT NULL;
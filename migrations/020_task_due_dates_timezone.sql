@@ -0,0 +1,9 @@
+-- Store task deadlines with their time zone
+-- due_at comes from clients in any offset and is compared with NOW(), so a plain
+-- TIMESTAMP dropped the offset and shifted deadlines by the client's UTC offset.
+-- Clients send RFC 3339 times, which were stored as their wall clock; treat
+-- existing values as UTC. started_at and overdue_at were written with NOW() and
+-- are converted from the session time zone.
+ALTER TABLE tasks ALTER COLUMN due_at TYPE TIMESTAMPTZ USING due_at AT TIME ZONE 'UTC';
+ALTER TABLE tasks ALTER COLUMN started_at TYPE TIMESTAMPTZ;
+ALTER TABLE tasks ALTER COLUMN overdue_at TYPE TIMESTAMPTZ;
@@ -34,6 +34,11 @@ export const updateApiBaseUrl = (serverUrl) => {
 // Request interceptor
 api.interceptors.request.use(
   (config) => {
+    // Attach API key when the server requires authentication
+    const apiKey = localStorage.getItem('mcp-api-key')
+    if (apiKey) {
+      config.headers.Authorization = `Bearer ${apiKey}`
+    }
     return config
   },
   (error) => {
@@ -72,6 +77,45 @@ export default {
   updateProjectStatus(id, status) {
     return api.put(`/projects/${id}/status`, { status })
   },
+  updateAssignStrategy(id, strategy) {
+    return api.put(`/projects/${id}/assign-strategy`, { strategy })
+  },
+  getAgentRoles(projectId) {
+    return api.get(`/projects/${projectId}/agent-roles`)
+  },
+  createAgentRole(projectId, data) {
+    return api.post(`/projects/${projectId}/agent-roles`, data)
+  },
+  deleteAgentRole(projectId, role) {
+    return api.delete(`/projects/${projectId}/agent-roles/${encodeURIComponent(role)}`)
+  },
+  getPromptTemplates(projectId) {
+    return api.get(`/projects/${projectId}/prompts`)
+  },
+  savePromptTemplate(projectId, data) {
+    return api.post(`/projects/${projectId}/prompts`, data)
+  },
+  deletePromptTemplate(projectId, name) {
+    return api.delete(`/projects/${projectId}/prompts/${encodeURIComponent(name)}`)
+  },
+  getToolSettings(projectId) {
+    return api.get(`/projects/${projectId}/mcp-tools`)
+  },
+  setToolEnabled(projectId, name, enabled, agentId = null) {
+    return api.put(`/projects/${projectId}/mcp-tools/${encodeURIComponent(name)}`, { enabled, agent_id: agentId || undefined })
+  },
+  deleteToolSetting(projectId, name, agentId = null) {
+    return api.delete(`/projects/${projectId}/mcp-tools/${encodeURIComponent(name)}`, { params: agentId ? { agent_id: agentId } : {} })
+  },
+  getToolApprovals(projectId, status = null) {
+    return api.get(`/projects/${projectId}/mcp-approvals`, { params: status ? { status } : {} })
+  },
+  decideToolApproval(projectId, approvalId, approved, reason = '') {
+    return api.post(`/projects/${projectId}/mcp-approvals/${approvalId}/decision`, { approved, reason: reason || undefined })
+  },
+  getUpstreamCalls(projectId, server = null) {
+    return api.get(`/projects/${projectId}/mcp-upstream-calls`, { params: server ? { server } : {} })
+  },
 
   // Agents
   getAgents(projectId = null) {
@@ -93,6 +137,12 @@ export default {
   updateAgentStatus(id, status) {
     return api.put(`/agents/${id}/status`, { status })
   },
+  updateAgentCapabilities(id, capabilities) {
+    return api.put(`/agents/${id}/capabilities`, { capabilities })
+  },
+  getAgentPresence(agentId, limit = 50) {
+    return api.get(`/agents/${agentId}/presence`, { params: { limit } })
+  },
   deleteAgent(id) {
     return api.delete(`/agents/${id}`)
   },
@@ -126,6 +176,20 @@ export default {
     return api.delete(`/tasks/${id}`)
   },
 
+  // Task comments
+  getTaskComments(taskId) {
+    return api.get(`/tasks/${taskId}/comments`)
+  },
+  createTaskComment(taskId, data) {
+    return api.post(`/tasks/${taskId}/comments`, data)
+  },
+  updateTaskComment(taskId, commentId, body) {
+    return api.put(`/tasks/${taskId}/comments/${commentId}`, { body })
+  },
+  deleteTaskComment(taskId, commentId) {
+    return api.delete(`/tasks/${taskId}/comments/${commentId}`)
+  },
+
   // Context/Documentation
   createDocumentation(data) {
     return api.post('/documentation', data)